@@ -0,0 +1,176 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/log"
+	"github.com/jacsmith21/lukabox/ext/render"
+	"github.com/jacsmith21/lukabox/stc"
+)
+
+// AdherenceAPI the services used to record the doses taken
+type AdherenceAPI struct {
+	UserService      domain.UserService
+	PillService      domain.PillService
+	PillEventService domain.PillEventService
+	StockService     domain.StockService
+	Refills          *RefillAPI
+	Webhooks         *WebhookAPI
+	Stream           *StreamAPI
+}
+
+// PublishMissedDoses publishes a missed dose event for every scheduled dose whose window closed from from
+// until to without being taken, so checking consecutive periods publishes each missed dose once
+func (a *AdherenceAPI) PublishMissedDoses(from time.Time, to time.Time) error {
+	users, err := a.UserService.Users()
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		pills, err := a.PillService.Pills(user.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, pill := range pills {
+			if pill.Archived {
+				continue
+			}
+
+			doses := pill.Doses(from.Add(-domain.DoseWindow).In(user.Location()), to.Add(-domain.DoseWindow).In(user.Location()))
+			if len(doses) == 0 {
+				continue
+			}
+
+			taken, err := a.PillEventService.PillEvents(pill.ID)
+			if err != nil {
+				return err
+			}
+
+			for _, dose := range doses {
+				if domain.DoseTaken(dose, taken) {
+					continue
+				}
+				missed := &domain.MissedDose{PillID: pill.ID, Pill: pill.Name, Scheduled: dose}
+				if err := a.Webhooks.Publish(user.ID, domain.DoseMissedEvent, missed, dose.Add(domain.DoseWindow)); err != nil {
+					return err
+				}
+				a.Stream.Publish(user.ID, domain.DoseMissedEvent, missed, dose.Add(domain.DoseWindow))
+			}
+		}
+	}
+	return nil
+}
+
+// attribute records the doses an open event is attributed to, in the user's timezone
+func (a *AdherenceAPI) attribute(openEvent *domain.OpenEvent) error {
+	user, err := a.UserService.UserByID(openEvent.UserID)
+	if err != nil {
+		return err
+	}
+	opened := openEvent.Time.In(user.Location())
+
+	pills, err := a.PillService.Pills(openEvent.UserID)
+	if err != nil {
+		return err
+	}
+
+	for _, pill := range pills {
+		if pill.Archived {
+			continue
+		}
+
+		taken, err := a.PillEventService.PillEvents(pill.ID)
+		if err != nil {
+			return err
+		}
+
+		dose, ok := pill.Attribute(opened, taken)
+		if !ok {
+			continue
+		}
+
+		pillEvent := &domain.PillEvent{PillID: pill.ID, OpenEventID: openEvent.ID, Scheduled: dose, Time: opened}
+		if err := a.take(pill, pillEvent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// take records a dose and takes it out of stock
+func (a *AdherenceAPI) take(pill *domain.Pill, pillEvent *domain.PillEvent) error {
+	if err := a.PillEventService.InsertPillEvent(pillEvent); err != nil {
+		return err
+	}
+	log.WithField("pillId", pill.ID).WithField("scheduled", pillEvent.Scheduled).Debug("dose taken")
+	a.Stream.Publish(pill.UserID, domain.DoseTakenEvent, pillEvent, pillEvent.Time)
+
+	_, quantity, _ := pill.Dose(pillEvent.Time)
+	if quantity <= 0 {
+		return nil
+	}
+	adjustment := &domain.StockAdjustment{PillID: pill.ID, Change: -quantity, Reason: domain.DoseReason, Time: pillEvent.Time}
+	if err := a.StockService.InsertAdjustment(adjustment); err != nil {
+		return err
+	}
+	return a.Refills.CheckStock(pill.ID, pillEvent.Time)
+}
+
+// TakeDose records a dose taken outside of the box, as needed pills must respect their minimum interval
+// and scheduled pills must have a dose near the time it was taken
+func (a *AdherenceAPI) TakeDose(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "TakeDose").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+	pill := r.Context().Value("pill").(*domain.Pill)
+
+	if pill.UserID != user.ID {
+		render.WithMessage("pill not found").NotFound(w, r)
+		return
+	}
+
+	data := &stc.DoseRequest{}
+	if err := render.Bind(r, data); err != nil {
+		render.WithError(err).BadRequest(w, r)
+		return
+	}
+	if data.Time.IsZero() {
+		data.Time = time.Now()
+	}
+	data.Time = data.Time.In(user.Location())
+
+	taken, err := a.PillEventService.PillEvents(pill.ID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	pillEvent := &domain.PillEvent{PillID: pill.ID, Time: data.Time}
+	if pill.Schedule != nil && pill.Schedule.AsNeeded {
+		for _, e := range taken {
+			if next := pill.NextAllowed(e.Time); !e.Time.After(data.Time) && data.Time.Before(next) {
+				render.WithMessage(fmt.Sprintf("the next dose can't be taken until %s", next.Format(time.RFC3339))).Conflict(w, r)
+				return
+			}
+		}
+		pillEvent.Scheduled = data.Time
+	} else {
+		dose, ok := pill.Attribute(data.Time, taken)
+		if !ok {
+			render.WithMessage("there is no scheduled dose near that time").Conflict(w, r)
+			return
+		}
+		pillEvent.Scheduled = dose
+	}
+
+	if err := a.take(pill, pillEvent); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/mock"
+)
+
+func TestTakeDose(t *testing.T) {
+	pSvc := mock.PillService{}
+	peSvc := mock.PillEventService{}
+	sSvc := mock.StockService{}
+	uSvc := mock.UserService{}
+	aAPI := AdherenceAPI{UserService: &uSvc, PillService: &pSvc, PillEventService: &peSvc, StockService: &sSvc, Refills: &RefillAPI{PillService: &pSvc, UserService: &uSvc}}
+
+	pAPI := PillAPI{}
+	pAPI.PillService = &pSvc
+
+	uAPI := UserAPI{}
+	uAPI.UserService = &uSvc
+
+	json := map[string]string{"Content-Type": "application/json"}
+	tests := []*test{
+		{"/users/1/pills/1/doses", "POST", `{"time":"2018-01-01T10:00:00Z"}`, json, http.StatusCreated, ""},
+		{"/users/1/pills/1/doses", "POST", `{"time":"2018-01-01T12:00:00Z"}`, json, http.StatusConflict, `{"message":"the next dose can't be taken until 2018-01-01T14:00:00Z"}`},
+		{"/users/1/pills/1/doses", "POST", `{"time":"2018-01-01T14:00:00Z"}`, json, http.StatusCreated, ""},
+		{"/users/1/pills/2/doses", "POST", `{"time":"2018-01-01T08:30:00Z"}`, json, http.StatusCreated, ""},
+		{"/users/1/pills/2/doses", "POST", `{"time":"2018-01-01T08:45:00Z"}`, json, http.StatusConflict, `{"message":"there is no scheduled dose near that time"}`},
+		{"/users/1/pills/2/doses", "POST", `{"time":"2018-01-01T15:00:00Z"}`, json, http.StatusConflict, `{"message":"there is no scheduled dose near that time"}`},
+		{"/users/2/pills/2/doses", "POST", `{"time":"2018-01-01T08:30:00Z"}`, json, http.StatusNotFound, `{"message":"pill not found"}`},
+		{"/users/1/pills/2/doses", "POST", `{"time":"2118-01-01T08:30:00Z"}`, json, http.StatusBadRequest, `{"message":"time can't be in the future","fields":{"time":"time can't be in the future"}}`},
+	}
+
+	start := time.Date(2018, time.January, 1, 8, 0, 0, 0, time.UTC)
+	pills := map[int]*domain.Pill{
+		1: {ID: 1, UserID: 1, Name: "Tylenol", QuantityPerDose: 2, Schedule: &domain.Schedule{Start: start, AsNeeded: true, MinInterval: 240}},
+		2: {ID: 2, UserID: 1, Name: "DoxyPoxy", QuantityPerDose: 1, Schedule: &domain.Schedule{Start: start, RRule: "FREQ=DAILY"}},
+	}
+	pSvc.PillFn = func(id int) (*domain.Pill, error) {
+		return pills[id], nil
+	}
+
+	taken := map[int][]*domain.PillEvent{}
+	peSvc.PillEventsFn = func(pillID int) ([]*domain.PillEvent, error) {
+		return taken[pillID], nil
+	}
+	peSvc.InsertPillEventFn = func(pillEvent *domain.PillEvent) error {
+		taken[pillEvent.PillID] = append(taken[pillEvent.PillID], pillEvent)
+		return nil
+	}
+
+	stock := map[int]float64{}
+	sSvc.InsertAdjustmentFn = func(adjustment *domain.StockAdjustment) error {
+		stock[adjustment.PillID] += adjustment.Change
+		return nil
+	}
+
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id, Email: "jacob.smith@unb.ca", Password: "password", FirstName: "Jacob", LastName: "Smith", Archived: false}, nil
+	}
+
+	r := chi.NewRouter()
+	r.Route("/users/{userId}/pills/{pillId}", func(r chi.Router) {
+		r.Use(uAPI.UserCtx)
+		r.Use(pAPI.PillCtx)
+		r.Post("/doses", aAPI.TakeDose)
+	})
+
+	runTests(t, r, tests)
+
+	if len(taken[1]) != 2 || len(taken[2]) != 1 || !taken[2][0].Scheduled.Equal(start) {
+		t.Errorf("expected two as needed doses and the scheduled dose, got %v", taken)
+	}
+	if stock[1] != -4 || stock[2] != -1 {
+		t.Errorf("expected the doses to be taken out of stock, got %v", stock)
+	}
+}
@@ -1,13 +1,17 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/go-chi/chi"
 	"github.com/go-chi/jwtauth"
 	"github.com/go-errors/errors"
 	"github.com/jacsmith21/lukabox/domain"
 	"github.com/jacsmith21/lukabox/ext/log"
+	"github.com/jacsmith21/lukabox/ext/oidc"
 	"github.com/jacsmith21/lukabox/ext/render"
 	"github.com/jacsmith21/lukabox/stc"
 )
@@ -16,8 +20,27 @@ import (
 type AuthenticationAPI struct {
 	AuthenticationService domain.AuthenticationService
 	UserService           domain.UserService
+	RateLimiter           domain.RateLimiter
+	LockoutService        domain.LockoutService
+	Mailer                domain.Mailer
+	OneTimeTokenService   domain.OneTimeTokenService
+	AccountPolicy         domain.RateLimitPolicy
+
+	TwoFactorService domain.TwoFactorService
+	RequireTwoFactor map[string]bool
+	SessionService   domain.SessionService
+	SessionPolicy    domain.SessionPolicy
+
+	Providers                 map[string]domain.IdentityProvider
+	IdentityService           domain.IdentityService
+	AuthorizationStateService domain.AuthorizationStateService
 }
 
+const (
+	passwordResetTTL      = time.Hour
+	authorizationStateTTL = 10 * time.Minute
+)
+
 // RequestValidator validates the request ie. checks whether the user is allowed to make this request
 func (a *AuthenticationAPI) RequestValidator(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -32,8 +55,12 @@ func (a *AuthenticationAPI) RequestValidator(next http.Handler) http.Handler {
 		log.WithField("user", user).Debug("user in validate")
 		log.WithField("claims", claims).Debug("claims in validate")
 
-		id := int(claims["id"].(float64))
-		if user.ID != id {
+		switch err := authorize(r.Context(), claims, user); err {
+		case nil:
+		case errEnrolmentRequired:
+			render.WithError(err).Forbidden(w, r)
+			return
+		default:
 			render.Unauthorized(w, r)
 			return
 		}
@@ -42,7 +69,102 @@ func (a *AuthenticationAPI) RequestValidator(next http.Handler) http.Handler {
 	})
 }
 
-//SignUpValidator signup handler
+// errEnrolmentRequired the error of tokens which can only be used to enrol in two factor authentication
+var errEnrolmentRequired = errors.New("two factor authentication enrolment required")
+
+// authorize whether the claims of a token authorize a request for a user's resources, the token must
+// belong to the user and not have been invalidated. Enrolment tokens are only allowed while enrolling
+func authorize(ctx context.Context, claims jwtauth.Claims, user *domain.User) error {
+	id, _ := claims["id"].(float64)
+	if user.ID != int(id) {
+		return errors.New("the token belongs to another user")
+	}
+
+	version, _ := claims["ver"].(float64)
+	if user.TokenVersion != int(version) {
+		log.WithField("version", version).Debug("token has been invalidated")
+		return errors.New("the token has been invalidated")
+	}
+
+	if enrol, _ := claims["enrol"].(bool); enrol && ctx.Value("enrolment") == nil {
+		return errEnrolmentRequired
+	}
+	return nil
+}
+
+// VerifiedValidator only allows users who have verified their email
+func (a *AuthenticationAPI) VerifiedValidator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value("user").(*domain.User)
+		if !user.Verified {
+			render.WithMessage("email must be verified").Forbidden(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// SessionValidator rejects tokens whose session has been revoked or has expired under the SessionPolicy, the
+// session is added to the context
+func (a *AuthenticationAPI) SessionValidator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, claims, err := jwtauth.FromContext(r.Context())
+		if err != nil {
+			render.WithError(err).BadRequest(w, r)
+			return
+		}
+
+		id, _ := claims["jti"].(string)
+		if id == "" {
+			render.Unauthorized(w, r)
+			return
+		}
+
+		session, err := a.SessionService.Session(id)
+		if err != nil {
+			render.WithError(err).InternalServerError(w, r)
+			return
+		}
+		if session == nil {
+			log.WithField("jti", id).Debug("session has been revoked")
+			render.Unauthorized(w, r)
+			return
+		}
+
+		now := time.Now()
+		if a.SessionPolicy.Expired(session, now) {
+			log.WithField("jti", id).Debug("session has expired")
+			if err := a.SessionService.DeleteSession(session.ID); err != nil {
+				log.WithError(err).Error("error deleting expired session")
+			}
+			render.Unauthorized(w, r)
+			return
+		}
+
+		if err := a.SessionService.TouchSession(session.ID, now); err != nil {
+			log.WithError(err).Error("error touching session")
+		}
+
+		ctx := context.WithValue(r.Context(), "session", session)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Logout revokes the session of the token making the request
+func (a *AuthenticationAPI) Logout(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "Logout").Info("starting")
+	session := r.Context().Value("session").(*domain.Session)
+
+	if err := a.SessionService.DeleteSession(session.ID); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+//SignUpValidator signup handler, an unavailable email gets the same response as a successful signup so emails can't be enumerated
 func (a *AuthenticationAPI) SignUpValidator(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := r.Context().Value("user").(*domain.User)
@@ -54,7 +176,17 @@ func (a *AuthenticationAPI) SignUpValidator(next http.Handler) http.Handler {
 			return
 		}
 		if !available {
-			render.WithMessage("email unavailable").Conflict(w, r)
+			log.WithField("email", email).Debug("email unavailable")
+			notice := &domain.Email{
+				To:      email,
+				Subject: "Lukabox signup attempt",
+				Body:    "Someone tried to create a Lukabox account with this email. If this was you, you can log in or unlock your account instead.",
+			}
+			if err := a.Mailer.Send(notice); err != nil {
+				log.WithError(err).Error("error sending signup attempt email")
+			}
+			w.WriteHeader(http.StatusCreated)
+			return
 		}
 
 		next.ServeHTTP(w, r)
@@ -71,9 +203,38 @@ func (a *AuthenticationAPI) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.WithField("Credentials", c.Credentials).Debug("credentials")
+	credentials := c.Credentials
+	if credentials == nil {
+		err := errors.New("credentials must be supplied")
+		render.WithError(err).BadRequest(w, r)
+		return
+	}
+
+	log.WithField("Credentials", credentials).Debug("credentials")
+
+	allowed, wait, err := a.RateLimiter.Allow("email:"+credentials.Email, a.AccountPolicy)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+	if !allowed {
+		log.WithField("email", credentials.Email).Debug("account rate limited")
+		tooManyRequests(w, r, wait)
+		return
+	}
+
+	lockout, err := a.LockoutService.Lockout(credentials.Email)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+	if lockout.Locked() {
+		log.WithField("email", credentials.Email).Debug("account locked")
+		tooManyRequests(w, r, time.Until(lockout.Until))
+		return
+	}
 
-	authenticated, err := a.AuthenticationService.Authenticate(c.Credentials.Email, c.Credentials.Password)
+	authenticated, err := a.AuthenticationService.Authenticate(credentials.Email, credentials.Password)
 	if err != nil {
 		render.WithError(err).BadRequest(w, r)
 		return
@@ -81,18 +242,18 @@ func (a *AuthenticationAPI) Login(w http.ResponseWriter, r *http.Request) {
 
 	log.WithField("authenticated", authenticated).Debug("authentication complete")
 	if !authenticated {
+		if err := a.fail(credentials.Email); err != nil {
+			render.WithError(err).InternalServerError(w, r)
+			return
+		}
 		w.WriteHeader(http.StatusForbidden)
 		fmt.Fprint(w, "Invalid credentials")
 		return
 	}
 
-	log.Debug("Creating Token")
-	tokenAuth := jwtauth.New("HS256", []byte("secret"), nil)
-
-	credentials := c.Credentials
-	if credentials == nil {
-		err := errors.New("credentials must be supplied")
-		render.WithError(err).BadRequest(w, r)
+	if err := a.LockoutService.Clear(credentials.Email); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
 	}
 
 	user, err := a.UserService.UserByEmail(credentials.Email)
@@ -101,13 +262,315 @@ func (a *AuthenticationAPI) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	claims := jwtauth.Claims{"id": user.ID}
+	a.login(w, r, user)
+}
+
+// token renders a new token for a new session, an enrolment token can only be used to enrol in two factor authentication
+func (a *AuthenticationAPI) token(w http.ResponseWriter, r *http.Request, user *domain.User, enrol bool) {
+	now := time.Now()
+	session := &domain.Session{
+		UserID:    user.ID,
+		Device:    r.Header.Get("X-Device"),
+		UserAgent: r.UserAgent(),
+		IP:        remoteIP(r),
+		Created:   now,
+		LastSeen:  now,
+	}
+	if err := a.SessionService.InsertSession(session); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	log.Debug("Creating Token")
+	tokenAuth := jwtauth.New("HS256", []byte("secret"), nil)
+
+	claims := jwtauth.Claims{"id": user.ID, "ver": user.TokenVersion, "jti": session.ID}
+	if enrol {
+		claims["enrol"] = true
+	}
 	log.WithField("id", user.ID).Debug("adding id to claims")
 	_, tokenString, _ := tokenAuth.Encode(claims)
-	token := &domain.Token{Token: tokenString}
+	token := &domain.Token{Token: tokenString, EnrolmentRequired: enrol}
 
 	if err := render.Instance(w, r, stc.NewTokenResponse(token)); err != nil {
 		render.WithError(err).BadRequest(w, r)
 		return
 	}
 }
+
+// fail records a failed login and emails an unlock link to the user if the account is now locked
+func (a *AuthenticationAPI) fail(email string) error {
+	lockout, err := a.LockoutService.Fail(email)
+	if err != nil {
+		return err
+	}
+	if !lockout.Locked() {
+		return nil
+	}
+
+	log.WithField("email", email).Info("account locked")
+
+	user, err := a.UserService.UserByEmail(email)
+	if err != nil || user == nil {
+		log.WithField("email", email).Debug("no user to send unlock email to")
+		return nil
+	}
+
+	unlock := &domain.Email{
+		To:      user.Email,
+		Subject: "Lukabox account locked",
+		Body:    "Your account was locked after too many failed login attempts. Use this token to unlock it: " + lockout.Token,
+	}
+	if err := a.Mailer.Send(unlock); err != nil {
+		log.WithError(err).Error("error sending unlock email")
+	}
+	return nil
+}
+
+// Unlock unlocks an account using the token from the unlock email
+func (a *AuthenticationAPI) Unlock(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "Unlock").Info("starting")
+
+	data := &stc.UnlockRequest{}
+	if err := render.Bind(r, data); err != nil {
+		render.WithError(err).BadRequest(w, r)
+		return
+	}
+
+	unlocked, err := a.LockoutService.Unlock(data.Token)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+	if !unlocked {
+		render.WithMessage("invalid unlock token").BadRequest(w, r)
+		return
+	}
+}
+
+// Verify verifies a user's email using the token from the verification email
+func (a *AuthenticationAPI) Verify(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "Verify").Info("starting")
+
+	data := &stc.VerifyRequest{}
+	if err := render.Bind(r, data); err != nil {
+		render.WithError(err).BadRequest(w, r)
+		return
+	}
+
+	token, err := a.OneTimeTokenService.Redeem(data.Token, domain.VerificationPurpose)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+	if token == nil {
+		render.WithMessage("invalid verification token").BadRequest(w, r)
+		return
+	}
+
+	user, err := a.UserService.UserByID(token.UserID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	user.Verified = true
+	if err := a.UserService.UpdateUser(user.ID, user); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
+
+// ForgotPassword emails a password reset token, the response is the same whether or not the email exists
+func (a *AuthenticationAPI) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "ForgotPassword").Info("starting")
+
+	data := &stc.ForgotPasswordRequest{}
+	if err := render.Bind(r, data); err != nil {
+		render.WithError(err).BadRequest(w, r)
+		return
+	}
+
+	user, err := a.UserService.UserByEmail(data.Email)
+	if err != nil || user == nil {
+		log.WithField("email", data.Email).Debug("no user to send password reset email to")
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	token, err := a.OneTimeTokenService.Issue(user.ID, domain.PasswordResetPurpose, passwordResetTTL)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	reset := &domain.Email{
+		To:      user.Email,
+		Subject: "Lukabox password reset",
+		Body:    "Use this token to reset your password: " + token.Token,
+	}
+	if err := a.Mailer.Send(reset); err != nil {
+		log.WithError(err).Error("error sending password reset email")
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword resets a user's password using the token from the password reset email and invalidates their existing tokens
+func (a *AuthenticationAPI) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "ResetPassword").Info("starting")
+
+	data := &stc.ResetPasswordRequest{}
+	if err := render.Bind(r, data); err != nil {
+		render.WithError(err).BadRequest(w, r)
+		return
+	}
+
+	token, err := a.OneTimeTokenService.Redeem(data.Token, domain.PasswordResetPurpose)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+	if token == nil {
+		render.WithMessage("invalid password reset token").BadRequest(w, r)
+		return
+	}
+
+	user, err := a.UserService.UserByID(token.UserID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	user.Password = data.Password
+	user.TokenVersion++
+	if err := a.UserService.UpdateUser(user.ID, user); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	if err := a.SessionService.DeleteSessions(user.ID); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	if err := a.LockoutService.Clear(user.Email); err != nil {
+		log.WithError(err).Error("error clearing lockout after password reset")
+	}
+}
+
+// ProviderLogin redirects the user to an OpenID Connect provider
+func (a *AuthenticationAPI) ProviderLogin(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "ProviderLogin").Info("starting")
+
+	name := chi.URLParam(r, "provider")
+	provider, ok := a.Providers[name]
+	if !ok {
+		render.WithMessage("provider not found").NotFound(w, r)
+		return
+	}
+
+	state := &domain.AuthorizationState{Provider: name, Expires: time.Now().Add(authorizationStateTTL)}
+	for _, v := range []*string{&state.State, &state.Nonce, &state.Verifier} {
+		random, err := oidc.Random()
+		if err != nil {
+			render.WithError(err).InternalServerError(w, r)
+			return
+		}
+		*v = random
+	}
+
+	if err := a.AuthorizationStateService.InsertState(state); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	u, err := provider.AuthorizationURL(state.State, state.Nonce, oidc.Challenge(state.Verifier))
+	if err != nil {
+		log.WithError(err).Error("error creating authorization url")
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	http.Redirect(w, r, u, http.StatusFound)
+}
+
+// ProviderCallback completes an OpenID Connect login, the external identity is linked to the verified user with the
+// same email when the provider has verified it too
+func (a *AuthenticationAPI) ProviderCallback(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "ProviderCallback").Info("starting")
+
+	name := chi.URLParam(r, "provider")
+	provider, ok := a.Providers[name]
+	if !ok {
+		render.WithMessage("provider not found").NotFound(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	if e := query.Get("error"); e != "" {
+		render.WithMessage(e).BadRequest(w, r)
+		return
+	}
+
+	state, err := a.AuthorizationStateService.TakeState(query.Get("state"))
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+	if state == nil || state.Provider != name {
+		render.WithMessage("invalid state").BadRequest(w, r)
+		return
+	}
+
+	external, err := provider.Exchange(query.Get("code"), state.Verifier, state.Nonce)
+	if err != nil {
+		log.WithError(err).Debug("error exchanging authorization code")
+		render.Unauthorized(w, r)
+		return
+	}
+
+	identity, err := a.IdentityService.Identity(name, external.Subject)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	var user *domain.User
+	if identity != nil {
+		user, err = a.UserService.UserByID(identity.UserID)
+		if err != nil {
+			render.WithError(err).InternalServerError(w, r)
+			return
+		}
+	} else {
+		if !external.EmailVerified {
+			render.WithMessage("email must be verified by the provider").Forbidden(w, r)
+			return
+		}
+
+		user, err = a.UserService.UserByEmail(external.Email)
+		if err != nil || user == nil {
+			log.WithField("email", external.Email).Debug("no user to link identity to")
+			render.WithMessage("no account with this email").Forbidden(w, r)
+			return
+		}
+
+		// an unverified account may have been registered by someone else with the email, linking it would let
+		// them log in with their password alongside the owner of the email
+		if !user.Verified {
+			log.WithField("email", external.Email).Debug("not linking identity to unverified user")
+			render.WithMessage("verify your email before logging in with a provider").Forbidden(w, r)
+			return
+		}
+
+		identity = &domain.Identity{Provider: name, Subject: external.Subject, UserID: user.ID}
+		if err := a.IdentityService.InsertIdentity(identity); err != nil {
+			render.WithError(err).InternalServerError(w, r)
+			return
+		}
+		log.WithField("identity", identity).Info("linked identity")
+	}
+
+	a.login(w, r, user)
+}
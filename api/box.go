@@ -3,8 +3,8 @@ package api
 import (
 	"context"
 	"net/http"
+	"time"
 
-	"github.com/go-playground/validator"
 	"github.com/jacsmith21/lukabox/domain"
 	"github.com/jacsmith21/lukabox/ext/log"
 	"github.com/jacsmith21/lukabox/ext/render"
@@ -14,6 +14,9 @@ import (
 // BoxAPI the services used
 type BoxAPI struct {
 	BoxService domain.BoxService
+	Adherence  *AdherenceAPI
+	Webhooks   *WebhookAPI
+	Stream     *StreamAPI
 }
 
 // OpenEventRequestCtx OpenEventRequestCtx
@@ -67,13 +70,7 @@ func (a *BoxAPI) Open(w http.ResponseWriter, r *http.Request) {
 	}
 	openEvent := tmp.(*domain.OpenEvent)
 
-	validate := validator.New()
-	if err := validate.Struct(openEvent); err != nil {
-		render.WithError(err).BadRequest(w, r)
-		return
-	}
-
-	if err := a.BoxService.InsertOpenEvent(openEvent); err != nil {
+	if err := a.open(openEvent); err != nil {
 		render.WithError(err).InternalServerError(w, r)
 		return
 	}
@@ -92,16 +89,61 @@ func (a *BoxAPI) Close(w http.ResponseWriter, r *http.Request) {
 	}
 	closeEvent := tmp.(*domain.CloseEvent)
 
-	validate := validator.New()
-	if err := validate.Struct(closeEvent); err != nil {
-		render.WithError(err).BadRequest(w, r)
+	if err := a.close(closeEvent); err != nil {
+		render.WithError(err).InternalServerError(w, r)
 		return
 	}
 
-	if err := a.BoxService.InsertCloseEvent(closeEvent); err != nil {
+	w.WriteHeader(http.StatusCreated)
+}
+
+// Sessions lists the sessions of the user's compartments, pairing every open with its close
+func (a *BoxAPI) Sessions(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "Sessions").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+
+	opens, err := a.BoxService.OpenEvents(user.ID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+	closes, err := a.BoxService.CloseEvents(user.ID)
+	if err != nil {
 		render.WithError(err).InternalServerError(w, r)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	sessions := domain.Sessions(opens, closes, time.Now(), domain.LeftOpenThreshold)
+	if err := render.List(w, r, stc.NewCompartmentSessionListResponse(sessions)); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
+
+// open records an open event, attributing it to a dose and publishing it. Events from the rest api and
+// from devices are both recorded here
+func (a *BoxAPI) open(openEvent *domain.OpenEvent) error {
+	if err := a.BoxService.InsertOpenEvent(openEvent); err != nil {
+		return err
+	}
+
+	if err := a.Adherence.attribute(openEvent); err != nil {
+		log.WithError(err).Error("unable to attribute open event to a dose")
+	}
+
+	if err := a.Webhooks.Publish(openEvent.UserID, domain.BoxOpenedEvent, openEvent, time.Now()); err != nil {
+		log.WithError(err).Error("unable to publish box opened event")
+	}
+	a.Stream.Publish(openEvent.UserID, domain.BoxOpenedEvent, openEvent, time.Now())
+	return nil
+}
+
+// close records a close event and publishes it
+func (a *BoxAPI) close(closeEvent *domain.CloseEvent) error {
+	if err := a.BoxService.InsertCloseEvent(closeEvent); err != nil {
+		return err
+	}
+
+	a.Stream.Publish(closeEvent.UserID, domain.BoxClosedEvent, closeEvent, time.Now())
+	return nil
 }
@@ -3,6 +3,7 @@ package api
 import (
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/jacsmith21/lukabox/domain"
@@ -13,14 +14,16 @@ func TestOpen(t *testing.T) {
 	bAPI := BoxAPI{}
 	bSvc := mock.BoxService{}
 	bAPI.BoxService = &bSvc
+	uSvc := mock.UserService{}
+	pSvc := mock.PillService{}
+	bAPI.Adherence = &AdherenceAPI{UserService: &uSvc, PillService: &pSvc}
 
 	uAPI := UserAPI{}
-	uSvc := mock.UserService{}
 	uAPI.UserService = &uSvc
 
 	tests := []*test{
 		{"/users/1/box/open", "PUT", `{"compId": 1, "time": "2012-11-01T22:08:41+00:00"}`, map[string]string{"Content-Type": "application/json"}, http.StatusCreated, ""},
-		{"/users/1/box/open", "PUT", `{"time": "2012-11-01T22:08:41+00:00"}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"Key: 'OpenEvent.CompID' Error:Field validation for 'CompID' failed on the 'required' tag"}`},
+		{"/users/1/box/open", "PUT", `{"time": "2012-11-01T22:08:41+00:00"}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"compId is required","fields":{"compId":"compId is required"}}`},
 		{"/users/1/box/open", "PUT", `{"compId": 1, "time": "2012-11-01T22:08:400:00"}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"parsing time \"\"2012-11-01T22:08:400:00\"\" as \"\"2006-01-02T15:04:05Z07:00\"\": cannot parse \"0:00\"\" as \"Z07:00\""}`},
 	}
 
@@ -28,6 +31,73 @@ func TestOpen(t *testing.T) {
 		return nil
 	}
 
+	pSvc.PillsFn = func(id int) ([]*domain.Pill, error) {
+		return []*domain.Pill{}, nil
+	}
+
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id, Email: "jacob.smith@unb.ca", Password: "password", FirstName: "Jacob", LastName: "Smith", Archived: false}, nil
+	}
+
+	r := chi.NewRouter()
+	r.Route("/users/{userId}/box", func(r chi.Router) {
+		r.Use(uAPI.UserCtx)
+		r.Use(bAPI.OpenEventRequestCtx)
+		r.Put("/open", bAPI.Open)
+	})
+
+	runTests(t, r, tests)
+}
+
+func TestOpenAttributesDose(t *testing.T) {
+	bAPI := BoxAPI{}
+	bSvc := mock.BoxService{}
+	pSvc := mock.PillService{}
+	peSvc := mock.PillEventService{}
+	sSvc := mock.StockService{}
+	bAPI.BoxService = &bSvc
+	uSvc := mock.UserService{}
+	bAPI.Adherence = &AdherenceAPI{UserService: &uSvc, PillService: &pSvc, PillEventService: &peSvc, StockService: &sSvc, Refills: &RefillAPI{PillService: &pSvc, UserService: &uSvc}}
+
+	uAPI := UserAPI{}
+	uAPI.UserService = &uSvc
+
+	tests := []*test{
+		{"/users/1/box/open", "PUT", `{"compId": 1, "time": "2012-11-01T22:08:41+00:00"}`, map[string]string{"Content-Type": "application/json"}, http.StatusCreated, ""},
+		{"/users/1/box/open", "PUT", `{"compId": 1, "time": "2012-11-01T22:30:00+00:00"}`, map[string]string{"Content-Type": "application/json"}, http.StatusCreated, ""},
+	}
+
+	bSvc.InsertOpenEventFn = func(openEvent *domain.OpenEvent) error {
+		openEvent.ID = 1
+		return nil
+	}
+
+	pSvc.PillsFn = func(id int) ([]*domain.Pill, error) {
+		return []*domain.Pill{
+			{ID: 1, UserID: 1, Name: "DoxyPoxy", DaysOfWeek: []int{4}, TimesOfDay: []domain.TimeOfDay{{Hour: 22}}, Quantity: 10, QuantityPerDose: 2},
+			{ID: 2, UserID: 1, Name: "Weekend", DaysOfWeek: []int{6, 7}, TimesOfDay: []domain.TimeOfDay{{Hour: 22}}, Quantity: 10, QuantityPerDose: 1},
+		}, nil
+	}
+
+	pSvc.PillFn = func(id int) (*domain.Pill, error) {
+		return &domain.Pill{ID: id, UserID: 1}, nil
+	}
+
+	taken := []*domain.PillEvent{}
+	peSvc.PillEventsFn = func(pillID int) ([]*domain.PillEvent, error) {
+		return taken, nil
+	}
+	peSvc.InsertPillEventFn = func(pillEvent *domain.PillEvent) error {
+		taken = append(taken, pillEvent)
+		return nil
+	}
+
+	adjustments := []*domain.StockAdjustment{}
+	sSvc.InsertAdjustmentFn = func(adjustment *domain.StockAdjustment) error {
+		adjustments = append(adjustments, adjustment)
+		return nil
+	}
+
 	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
 		return &domain.User{ID: id, Email: "jacob.smith@unb.ca", Password: "password", FirstName: "Jacob", LastName: "Smith", Archived: false}, nil
 	}
@@ -40,6 +110,14 @@ func TestOpen(t *testing.T) {
 	})
 
 	runTests(t, r, tests)
+
+	scheduled := time.Date(2012, time.November, 1, 22, 0, 0, 0, time.UTC)
+	if len(taken) != 1 || taken[0].PillID != 1 || !taken[0].Scheduled.Equal(scheduled) || taken[0].OpenEventID != 1 {
+		t.Errorf("expected a single dose of pill 1 to be taken, got %v", taken)
+	}
+	if len(adjustments) != 1 || adjustments[0].Change != -2 || adjustments[0].Reason != domain.DoseReason {
+		t.Errorf("expected a single dose to be taken out of stock, got %v", adjustments)
+	}
 }
 
 func TestClose(t *testing.T) {
@@ -53,7 +131,7 @@ func TestClose(t *testing.T) {
 
 	tests := []*test{
 		{"/users/1/box/close", "PUT", `{"compId": 1, "time": "2012-11-01T22:08:41+00:00"}`, map[string]string{"Content-Type": "application/json"}, http.StatusCreated, ""},
-		{"/users/1/box/close", "PUT", `{"time": "2012-11-01T22:08:41+00:00"}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"Key: 'CloseEvent.CompID' Error:Field validation for 'CompID' failed on the 'required' tag"}`},
+		{"/users/1/box/close", "PUT", `{"time": "2012-11-01T22:08:41+00:00"}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"compId is required","fields":{"compId":"compId is required"}}`},
 		{"/users/1/box/close", "PUT", `{"compId": 1, "time": "2012-11-01T22:08:400:00"}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"parsing time \"\"2012-11-01T22:08:400:00\"\" as \"\"2006-01-02T15:04:05Z07:00\"\": cannot parse \"0:00\"\" as \"Z07:00\""}`},
 	}
 
@@ -74,3 +152,53 @@ func TestClose(t *testing.T) {
 
 	runTests(t, r, tests)
 }
+
+func TestCompartmentSessions(t *testing.T) {
+	bAPI := BoxAPI{}
+	bSvc := mock.BoxService{}
+	bAPI.BoxService = &bSvc
+	uAPI := UserAPI{}
+	uSvc := mock.UserService{}
+	uAPI.UserService = &uSvc
+
+	d := time.Date(2009, time.November, 10, 8, 0, 0, 0, time.UTC)
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id}, nil
+	}
+	opens := []*domain.OpenEvent{
+		{ID: 1, CompID: 1, UserID: 1, Time: d},
+		{ID: 2, CompID: 2, UserID: 1, Time: d.Add(time.Minute)},
+		{ID: 3, CompID: 1, UserID: 1, Time: d.Add(time.Hour)},
+		{ID: 4, CompID: 1, UserID: 1, Time: d.Add(2 * time.Hour)},
+	}
+	closes := []*domain.CloseEvent{
+		{ID: 1, CompID: 3, UserID: 1, Time: d},
+		{ID: 2, CompID: 1, UserID: 1, Time: d.Add(30 * time.Second)},
+		{ID: 3, CompID: 1, UserID: 1, Time: d.Add(45 * time.Second)},
+		{ID: 4, CompID: 2, UserID: 1, Time: d.Add(31 * time.Minute)},
+		{ID: 5, CompID: 1, UserID: 1, Time: d.Add(2*time.Hour + 10*time.Second)},
+	}
+	bSvc.OpenEventsFn = func(userID int) ([]*domain.OpenEvent, error) {
+		return opens, nil
+	}
+	bSvc.CloseEventsFn = func(userID int) ([]*domain.CloseEvent, error) {
+		return closes, nil
+	}
+
+	r := chi.NewRouter()
+	r.Route("/users/{userId}/box", func(r chi.Router) {
+		r.Use(uAPI.UserCtx)
+		r.Get("/sessions", bAPI.Sessions)
+	})
+
+	tests := []*test{
+		{"/users/1/box/sessions", "GET", "", nil, http.StatusOK, `[{"compId":1,"state":"closed","openEventId":1,"closeEventId":2,"opened":"2009-11-10T08:00:00Z","closed":"2009-11-10T08:00:30Z","seconds":30,"leftOpen":false,"duplicateCloses":1},{"compId":2,"state":"closed","openEventId":2,"closeEventId":4,"opened":"2009-11-10T08:01:00Z","closed":"2009-11-10T08:31:00Z","seconds":1800,"leftOpen":true,"duplicateCloses":0},{"compId":1,"state":"unclosed","openEventId":3,"opened":"2009-11-10T09:00:00Z","closed":null,"seconds":null,"leftOpen":false,"duplicateCloses":0},{"compId":1,"state":"closed","openEventId":4,"closeEventId":5,"opened":"2009-11-10T10:00:00Z","closed":"2009-11-10T10:00:10Z","seconds":10,"leftOpen":false,"duplicateCloses":0}]`},
+	}
+	runTests(t, r, tests)
+
+	// a compartment which hasn't been closed is open for as long as it has been so far
+	sessions := domain.Sessions([]*domain.OpenEvent{{ID: 1, CompID: 4, Time: d}}, nil, d.Add(15*time.Minute), domain.LeftOpenThreshold)
+	if len(sessions) != 1 || sessions[0].State != domain.OpenSession || *sessions[0].Seconds != 900 || !sessions[0].LeftOpen {
+		t.Errorf("expected a session left open for 15 minutes, got %+v", sessions)
+	}
+}
@@ -0,0 +1,257 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/ical"
+	"github.com/jacsmith21/lukabox/ext/log"
+	"github.com/jacsmith21/lukabox/ext/render"
+	"github.com/jacsmith21/lukabox/stc"
+)
+
+// doseDuration how long a dose is shown for in calendars
+const doseDuration = 15 * time.Minute
+
+var icalWeekdays = []string{"MO", "TU", "WE", "TH", "FR", "SA", "SU"}
+
+// CalendarAPI the services used
+type CalendarAPI struct {
+	PillService      domain.PillService
+	FeedTokenService domain.FeedTokenService
+}
+
+// Feed returns the user's schedule as an iCalendar feed, authenticated by the feed token in the token parameter.
+// The feed is generated from the user's pills on every request so it always reflects their current schedule
+func (a *CalendarAPI) Feed(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "Feed").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+
+	if format, _ := r.Context().Value(middleware.URLFormatCtxKey).(string); format != "ics" {
+		render.WithMessage("schedule not found").NotFound(w, r)
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		render.Unauthorized(w, r)
+		return
+	}
+
+	feedToken, err := a.FeedTokenService.FeedToken(token)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+	if feedToken == nil || feedToken.UserID != user.ID || subtle.ConstantTimeCompare([]byte(feedToken.Token), []byte(token)) != 1 {
+		render.Unauthorized(w, r)
+		return
+	}
+
+	pills, err := a.PillService.Pills(user.ID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	loc := user.Location()
+	now := time.Now().In(loc)
+	calendar := &ical.Calendar{Name: "Medication schedule", Location: loc}
+	for _, pill := range pills {
+		if pill.Archived {
+			continue
+		}
+		calendar.Events = append(calendar.Events, calendarEvents(pill, now)...)
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", "inline; filename=schedule.ics")
+	w.Write(calendar.Bytes(now))
+}
+
+// IssueFeedToken creates a new feed token for the user, the previous feed url stops working
+func (a *CalendarAPI) IssueFeedToken(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "IssueFeedToken").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+
+	token, err := a.FeedTokenService.IssueFeedToken(user.ID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	if err := render.Instance(w, r, stc.NewFeedTokenResponse(token)); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
+
+// RevokeFeedToken revokes the user's feed token, the feed url stops working
+func (a *CalendarAPI) RevokeFeedToken(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "RevokeFeedToken").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+
+	if err := a.FeedTokenService.RevokeFeedToken(user.ID); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// calendarEvents the events of a pill, now must be in the user's location.
+// Rules become recurring events, tapers become an event per dose as the dose changes each step
+// and pills taken as needed have no events
+func calendarEvents(pill *domain.Pill, now time.Time) []*ical.Event {
+	loc := now.Location()
+	summary := strings.TrimSpace(pill.Name + " " + pill.Dosage())
+	description := calendarDescription(pill)
+
+	switch {
+	case pill.Schedule == nil:
+		return weeklyEvents(pill, now, summary, description)
+	case pill.Schedule.AsNeeded:
+		return []*ical.Event{}
+	}
+
+	rule, err := domain.ParseRule(pill.Schedule.RRule)
+	if err != nil {
+		return []*ical.Event{}
+	}
+
+	begin := pill.Schedule.Begin(loc)
+	if len(pill.Schedule.Taper) > 0 {
+		days := 1
+		for _, step := range pill.Schedule.Taper {
+			days += step.Days
+		}
+
+		events := []*ical.Event{}
+		for _, o := range pill.Occurrences(begin, begin.AddDate(0, 0, days)) {
+			events = append(events, &ical.Event{
+				UID:         fmt.Sprintf("pill-%d-%d@lukabox", pill.ID, o.Time.Unix()),
+				Summary:     strings.TrimSpace(pill.Name + " " + strengthOf(pill, o.Strength)),
+				Description: description,
+				Start:       o.Time,
+				Duration:    doseDuration,
+			})
+		}
+		return events
+	}
+
+	return []*ical.Event{{
+		UID:         fmt.Sprintf("pill-%d@lukabox", pill.ID),
+		Summary:     summary,
+		Description: description,
+		Start:       begin,
+		Duration:    doseDuration,
+		RRule:       recurrence(pill, rule, loc),
+	}}
+}
+
+// weeklyEvents a weekly event for each time of day of a pill without a schedule,
+// the events start on the first day of the course or today
+func weeklyEvents(pill *domain.Pill, now time.Time, summary string, description string) []*ical.Event {
+	events := []*ical.Event{}
+	if len(pill.DaysOfWeek) == 0 {
+		return events
+	}
+
+	days := []string{}
+	for _, d := range pill.DaysOfWeek {
+		days = append(days, icalWeekdays[d-1])
+	}
+	rule := &domain.Rule{Freq: "WEEKLY", Interval: 1}
+	rrule := "FREQ=WEEKLY;BYDAY=" + strings.Join(days, ",")
+
+	start := now
+	if pill.StartDate != nil {
+		start = *pill.StartDate
+	}
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, now.Location())
+	for i := 0; i < 7 && !containsDay(pill.DaysOfWeek, day.Weekday()); i++ {
+		day = day.AddDate(0, 0, 1)
+	}
+
+	for _, t := range pill.TimesOfDay {
+		events = append(events, &ical.Event{
+			UID:         fmt.Sprintf("pill-%d-%02d%02d@lukabox", pill.ID, t.Hour, t.Minute),
+			Summary:     summary,
+			Description: description,
+			Start:       t.On(day),
+			Duration:    doseDuration,
+			RRule:       rrule + until(pill, rule, now.Location()),
+		})
+	}
+	return events
+}
+
+// recurrence the RRULE of a pill's schedule, any UNTIL is written in UTC as calendars require with a TZID
+func recurrence(pill *domain.Pill, rule *domain.Rule, loc *time.Location) string {
+	parts := []string{}
+	for _, part := range strings.Split(strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(pill.Schedule.RRule)), "RRULE:"), ";") {
+		if !strings.HasPrefix(part, "UNTIL=") {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ";") + until(pill, rule, loc)
+}
+
+// until the UNTIL part ending a recurrence at the end of a rule or the end of the course, whichever is first
+func until(pill *domain.Pill, rule *domain.Rule, loc *time.Location) string {
+	end := rule.Until
+	if pill.EndDate != nil {
+		courseEnd := time.Date(pill.EndDate.Year(), pill.EndDate.Month(), pill.EndDate.Day(), 23, 59, 59, 0, loc)
+		if end.IsZero() || courseEnd.Before(end) {
+			end = courseEnd
+		}
+	}
+	if end.IsZero() || rule.Count > 0 {
+		return ""
+	}
+	return ";UNTIL=" + ical.Until(end)
+}
+
+// calendarDescription how to take a pill
+func calendarDescription(pill *domain.Pill) string {
+	lines := []string{}
+	if pill.QuantityPerDose > 0 {
+		lines = append(lines, strings.TrimSpace("Take "+strconv.FormatFloat(pill.QuantityPerDose, 'f', -1, 64)+" "+pill.Form))
+	}
+	switch pill.Food {
+	case "with-food":
+		lines = append(lines, "Take with food")
+	case "empty-stomach":
+		lines = append(lines, "Take on an empty stomach")
+	}
+	if pill.Instructions != "" {
+		lines = append(lines, pill.Instructions)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func strengthOf(pill *domain.Pill, strength float64) string {
+	p := *pill
+	p.Strength = strength
+	return p.Dosage()
+}
+
+func containsDay(days []int, weekday time.Weekday) bool {
+	iso := int(weekday)
+	if iso == 0 {
+		iso = 7
+	}
+	for _, d := range days {
+		if d == iso {
+			return true
+		}
+	}
+	return false
+}
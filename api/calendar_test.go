@@ -0,0 +1,135 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/mock"
+)
+
+func TestFeedToken(t *testing.T) {
+	uAPI := UserAPI{}
+	uSvc := mock.UserService{}
+	uAPI.UserService = &uSvc
+	cAPI := CalendarAPI{}
+	fSvc := mock.FeedTokenService{}
+	cAPI.FeedTokenService = &fSvc
+
+	tests := []*test{
+		{"/users/1/schedule/token", "POST", "", nil, http.StatusCreated, `{"token":"feed","created":"2009-11-10T12:00:00Z","url":"/users/1/schedule.ics?token=feed"}`},
+		{"/users/1/schedule/token", "DELETE", "", nil, http.StatusNoContent, ""},
+	}
+
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id}, nil
+	}
+	fSvc.IssueFeedTokenFn = func(userID int) (*domain.FeedToken, error) {
+		return &domain.FeedToken{Token: "feed", UserID: userID, Created: time.Date(2009, time.November, 10, 12, 0, 0, 0, time.UTC)}, nil
+	}
+	revoked := 0
+	fSvc.RevokeFeedTokenFn = func(userID int) error {
+		revoked = userID
+		return nil
+	}
+
+	r := chi.NewRouter()
+	r.Route("/users/{userId}", func(r chi.Router) {
+		r.Use(uAPI.UserCtx)
+		r.Post("/schedule/token", cAPI.IssueFeedToken)
+		r.Delete("/schedule/token", cAPI.RevokeFeedToken)
+	})
+
+	runTests(t, r, tests)
+
+	if revoked != 1 {
+		t.Errorf("expected the token of user 1 to be revoked, got %d", revoked)
+	}
+}
+
+func TestFeed(t *testing.T) {
+	uAPI := UserAPI{}
+	uSvc := mock.UserService{}
+	uAPI.UserService = &uSvc
+	cAPI := CalendarAPI{}
+	pSvc := mock.PillService{}
+	fSvc := mock.FeedTokenService{}
+	cAPI.PillService = &pSvc
+	cAPI.FeedTokenService = &fSvc
+
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id, Timezone: "America/Halifax"}, nil
+	}
+	fSvc.FeedTokenFn = func(token string) (*domain.FeedToken, error) {
+		if token == "feed" {
+			return &domain.FeedToken{Token: "feed", UserID: 1}, nil
+		}
+		return nil, nil
+	}
+
+	end := time.Date(2018, time.March, 31, 0, 0, 0, 0, time.UTC)
+	pSvc.PillsFn = func(id int) ([]*domain.Pill, error) {
+		return []*domain.Pill{
+			{ID: 1, UserID: 1, Name: "DoxyPoxy", Strength: 100, Unit: "mg", QuantityPerDose: 2, Form: "tablet", Food: "with-food", EndDate: &end, Schedule: &domain.Schedule{Start: time.Date(2018, time.March, 1, 8, 0, 0, 0, time.UTC), RRule: "FREQ=DAILY;BYHOUR=8,20"}},
+			{ID: 2, UserID: 1, Name: "Prednisone", Unit: "mg", Schedule: &domain.Schedule{Start: time.Date(2018, time.March, 1, 9, 0, 0, 0, time.UTC), RRule: "FREQ=DAILY", Taper: []domain.TaperStep{{Days: 1, Strength: 40}, {Days: 1, Strength: 20}}}},
+			{ID: 3, UserID: 1, Name: "Advil", Schedule: &domain.Schedule{Start: time.Date(2018, time.March, 1, 9, 0, 0, 0, time.UTC), AsNeeded: true}},
+			{ID: 4, UserID: 1, Name: "Archived", Archived: true, DaysOfWeek: []int{1}, TimesOfDay: []domain.TimeOfDay{{Hour: 8}}},
+		}, nil
+	}
+
+	r := chi.NewRouter()
+	r.Use(middleware.URLFormat)
+	r.Route("/users/{userId}", func(r chi.Router) {
+		r.Use(uAPI.UserCtx)
+		r.Get("/schedule", cAPI.Feed)
+	})
+
+	tests := []*test{
+		{"/users/1/schedule.ics", "GET", "", nil, http.StatusUnauthorized, `{"message":"unauthorized"}`},
+		{"/users/1/schedule.ics?token=wrong", "GET", "", nil, http.StatusUnauthorized, `{"message":"unauthorized"}`},
+		{"/users/2/schedule.ics?token=feed", "GET", "", nil, http.StatusUnauthorized, `{"message":"unauthorized"}`},
+		{"/users/1/schedule?token=feed", "GET", "", nil, http.StatusNotFound, `{"message":"schedule not found"}`},
+	}
+	runTests(t, r, tests)
+
+	req, err := http.NewRequest("GET", "/users/1/schedule.ics?token=feed", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/calendar; charset=utf-8" {
+		t.Fatalf("expected a calendar, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	body := w.Body.String()
+	for _, line := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"TZID:America/Halifax\r\n",
+		"UID:pill-1@lukabox\r\n",
+		"DTSTART;TZID=America/Halifax:20180301T080000\r\n",
+		"RRULE:FREQ=DAILY;BYHOUR=8,20;UNTIL=20180401T025959Z\r\n",
+		"SUMMARY:DoxyPoxy 100 mg\r\n",
+		"DESCRIPTION:Take 2 tablet\\nTake with food\r\n",
+		"TRIGGER:PT0M\r\n",
+		"DTSTART;TZID=America/Halifax:20180301T090000\r\nDURATION:PT15M\r\nSUMMARY:Prednisone 40 mg\r\n",
+		"DTSTART;TZID=America/Halifax:20180302T090000\r\nDURATION:PT15M\r\nSUMMARY:Prednisone 20 mg\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("expected the feed to contain %q:\n%s", line, body)
+		}
+	}
+	if events := strings.Count(body, "BEGIN:VEVENT"); events != 3 {
+		t.Errorf("expected 3 events, got %d", events)
+	}
+	if strings.Contains(body, "Advil") || strings.Contains(body, "Archived") {
+		t.Errorf("expected as needed and archived pills to be left out:\n%s", body)
+	}
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/log"
+	"github.com/jacsmith21/lukabox/ext/render"
+	"github.com/jacsmith21/lukabox/stc"
+)
+
+// CaregiverAPI the services used
+type CaregiverAPI struct {
+	CaregiverService domain.CaregiverService
+	UserService      domain.UserService
+	Mailer           domain.Mailer
+}
+
+// Caregivers lists the caregivers of the user and the caregivers who haven't accepted their invitation yet
+func (a *CaregiverAPI) Caregivers(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "Caregivers").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+
+	links, err := a.CaregiverService.CaregiverLinks(user.ID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	users := map[int]*domain.User{}
+	for _, l := range links {
+		caregiver, err := a.UserService.UserByID(l.CaregiverID)
+		if err != nil {
+			render.WithError(err).InternalServerError(w, r)
+			return
+		}
+		if caregiver == nil || caregiver.Archived {
+			continue
+		}
+		users[caregiver.ID] = caregiver
+	}
+
+	if err := render.List(w, r, stc.NewCaregiverListResponse(links, users)); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
+
+// AddCaregiver invites another user to be a caregiver of the user, they get the user's low stock alerts once they
+// accept. Only verified users can be invited
+func (a *CaregiverAPI) AddCaregiver(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "AddCaregiver").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+
+	id, err := strconv.Atoi(chi.URLParam(r, "caregiverId"))
+	if err != nil {
+		render.WithMessage("unable to parse parameter caregiver id").BadRequest(w, r)
+		return
+	}
+	if id == user.ID {
+		render.WithMessage("users can't be their own caregiver").BadRequest(w, r)
+		return
+	}
+
+	caregiver, err := a.UserService.UserByID(id)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+	if caregiver == nil || caregiver.Archived || !caregiver.Verified {
+		render.WithMessage("caregiver not found").NotFound(w, r)
+		return
+	}
+
+	if err := a.CaregiverService.InviteCaregiver(user.ID, caregiver.ID); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	invitation := &domain.Email{
+		To:      caregiver.Email,
+		Subject: fmt.Sprintf("%s %s invited you to be their caregiver", user.FirstName, user.LastName),
+		Body:    fmt.Sprintf("%s %s would like you to get their low stock alerts. Accept with PUT /users/%d/patients/%d or decline with DELETE.", user.FirstName, user.LastName, caregiver.ID, user.ID),
+	}
+	if err := a.Mailer.Send(invitation); err != nil {
+		log.WithError(err).Error("error sending caregiver invitation")
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// RemoveCaregiver unlinks a caregiver of the user or withdraws their invitation
+func (a *CaregiverAPI) RemoveCaregiver(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "RemoveCaregiver").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+
+	id, err := strconv.Atoi(chi.URLParam(r, "caregiverId"))
+	if err != nil {
+		render.WithMessage("unable to parse parameter caregiver id").BadRequest(w, r)
+		return
+	}
+
+	if err := a.CaregiverService.RemoveCaregiver(user.ID, id); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AcceptPatient accepts a patient's invitation to be their caregiver, the user in the url is the caregiver
+func (a *CaregiverAPI) AcceptPatient(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "AcceptPatient").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+
+	id, err := strconv.Atoi(chi.URLParam(r, "patientId"))
+	if err != nil {
+		render.WithMessage("unable to parse parameter patient id").BadRequest(w, r)
+		return
+	}
+
+	accepted, err := a.CaregiverService.AcceptCaregiver(id, user.ID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+	if !accepted {
+		render.WithMessage("invitation not found").NotFound(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemovePatient declines a patient's invitation or stops being their caregiver, the user in the url is the caregiver
+func (a *CaregiverAPI) RemovePatient(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "RemovePatient").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+
+	id, err := strconv.Atoi(chi.URLParam(r, "patientId"))
+	if err != nil {
+		render.WithMessage("unable to parse parameter patient id").BadRequest(w, r)
+		return
+	}
+
+	if err := a.CaregiverService.RemoveCaregiver(id, user.ID); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/mock"
+)
+
+func TestCaregivers(t *testing.T) {
+	uAPI := UserAPI{}
+	uSvc := mock.UserService{}
+	uAPI.UserService = &uSvc
+	cAPI := CaregiverAPI{}
+	cSvc := mock.CaregiverService{}
+	mailer := mock.Mailer{}
+	cAPI.CaregiverService = &cSvc
+	cAPI.UserService = &uSvc
+	cAPI.Mailer = &mailer
+
+	// user 3 is archived and user 4 hasn't verified their email
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		if id > 4 {
+			return nil, nil
+		}
+		return &domain.User{ID: id, Email: "jacob.smith@unb.ca", Password: "password", FirstName: "Jacob", LastName: "Smith", Archived: id == 3, Verified: id != 4}, nil
+	}
+
+	links := map[int][]*domain.CaregiverLink{}
+	cSvc.CaregiverLinksFn = func(patientID int) ([]*domain.CaregiverLink, error) {
+		return links[patientID], nil
+	}
+	cSvc.InviteCaregiverFn = func(patientID int, caregiverID int) error {
+		links[patientID] = append(links[patientID], &domain.CaregiverLink{PatientID: patientID, CaregiverID: caregiverID})
+		return nil
+	}
+	cSvc.AcceptCaregiverFn = func(patientID int, caregiverID int) (bool, error) {
+		for _, l := range links[patientID] {
+			if l.CaregiverID == caregiverID {
+				l.Accepted = true
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	cSvc.RemoveCaregiverFn = func(patientID int, caregiverID int) error {
+		delete(links, patientID)
+		return nil
+	}
+	invited := []*domain.Email{}
+	mailer.SendFn = func(email *domain.Email) error {
+		invited = append(invited, email)
+		return nil
+	}
+
+	r := chi.NewRouter()
+	r.Route("/users/{userId}", func(r chi.Router) {
+		r.Use(uAPI.UserCtx)
+		r.Get("/caregivers", cAPI.Caregivers)
+		r.Put("/caregivers/{caregiverId}", cAPI.AddCaregiver)
+		r.Delete("/caregivers/{caregiverId}", cAPI.RemoveCaregiver)
+		r.Put("/patients/{patientId}", cAPI.AcceptPatient)
+		r.Delete("/patients/{patientId}", cAPI.RemovePatient)
+	})
+
+	tests := []*test{
+		{"/users/1/caregivers", "GET", "", nil, http.StatusOK, "[]"},
+		{"/users/1/caregivers/2", "PUT", "", nil, http.StatusAccepted, ""},
+		{"/users/1/caregivers", "GET", "", nil, http.StatusOK, `[{"id":2,"email":"jacob.smith@unb.ca","firstName":"Jacob","lastName":"Smith","accepted":false}]`},
+		{"/users/2/patients/3", "PUT", "", nil, http.StatusNotFound, `{"message":"invitation not found"}`},
+		{"/users/2/patients/1", "PUT", "", nil, http.StatusNoContent, ""},
+		{"/users/1/caregivers", "GET", "", nil, http.StatusOK, `[{"id":2,"email":"jacob.smith@unb.ca","firstName":"Jacob","lastName":"Smith","accepted":true}]`},
+		{"/users/1/caregivers/1", "PUT", "", nil, http.StatusBadRequest, `{"message":"users can't be their own caregiver"}`},
+		{"/users/1/caregivers/3", "PUT", "", nil, http.StatusNotFound, `{"message":"caregiver not found"}`},
+		{"/users/1/caregivers/4", "PUT", "", nil, http.StatusNotFound, `{"message":"caregiver not found"}`},
+		{"/users/1/caregivers/5", "PUT", "", nil, http.StatusNotFound, `{"message":"caregiver not found"}`},
+		{"/users/1/caregivers/bad", "PUT", "", nil, http.StatusBadRequest, `{"message":"unable to parse parameter caregiver id"}`},
+		{"/users/1/caregivers/2", "DELETE", "", nil, http.StatusNoContent, ""},
+		{"/users/1/caregivers", "GET", "", nil, http.StatusOK, "[]"},
+		{"/users/2/patients/bad", "DELETE", "", nil, http.StatusBadRequest, `{"message":"unable to parse parameter patient id"}`},
+	}
+	runTests(t, r, tests)
+
+	if len(invited) != 1 || invited[0].Subject != "Jacob Smith invited you to be their caregiver" {
+		t.Errorf("expected the caregiver to be emailed an invitation, got %+v", invited)
+	}
+}
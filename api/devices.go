@@ -0,0 +1,406 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/jwtauth"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/devicepb"
+	"github.com/jacsmith21/lukabox/ext/log"
+	"github.com/jacsmith21/lukabox/ext/render"
+	"github.com/jacsmith21/lukabox/ext/validate"
+	"github.com/jacsmith21/lukabox/stc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// The metadata devices authenticate with, Register is authenticated with the owner's token instead
+const (
+	deviceIDMetadata     = "device-id"
+	deviceSecretMetadata = "device-secret"
+)
+
+// defaultConfigurationHours how far ahead the configuration includes doses when the device doesn't say
+const defaultConfigurationHours = 24
+
+// maxConfigurationHours how far ahead a device can ask for doses
+const maxConfigurationHours = 7 * 24
+
+// defaultCommandPoll how often queued commands are checked for while a device receives commands
+const defaultCommandPoll = 5 * time.Second
+
+// DeviceAPI the services used by the grpc device rpcs and the rest routes managing devices. Events from
+// devices are recorded by the BoxAPI like the events sent to the rest api
+type DeviceAPI struct {
+	devicepb.UnimplementedDeviceServer
+
+	DeviceService  domain.DeviceService
+	UserService    domain.UserService
+	SessionService domain.SessionService
+	SessionPolicy  domain.SessionPolicy
+	PillService    domain.PillService
+	Box            *BoxAPI
+	TokenAuth      *jwtauth.JwtAuth
+	Poll           time.Duration
+}
+
+// Server creates the grpc server of the device rpcs, opts are added to the server's options, ie. its credentials
+func (a *DeviceAPI) Server(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.UnaryInterceptor(a.UnaryInterceptor), grpc.StreamInterceptor(a.StreamInterceptor))
+	s := grpc.NewServer(opts...)
+	devicepb.RegisterDeviceServer(s, a)
+	return s
+}
+
+// UnaryInterceptor authenticates the unary rpcs
+func (a *DeviceAPI) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamInterceptor authenticates the streaming rpcs
+func (a *DeviceAPI) StreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &deviceStream{ServerStream: ss, ctx: ctx})
+}
+
+// deviceStream a stream with the authenticated context
+type deviceStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context the authenticated context
+func (s *deviceStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticate adds the user and device of the credentials in the metadata to the context. Register
+// is authenticated by the owner's token, which is authorized like the rest routes of the box
+func (a *DeviceAPI) authenticate(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if method == devicepb.Device_Register_FullMethodName {
+		user, err := a.owner(ctx, firstMetadata(md, "authorization"))
+		if err != nil {
+			return nil, err
+		}
+		return context.WithValue(ctx, "user", user), nil
+	}
+
+	id, err := strconv.Atoi(firstMetadata(md, deviceIDMetadata))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	device, err := a.DeviceService.Device(id)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	secret := firstMetadata(md, deviceSecretMetadata)
+	if device == nil || !device.Authenticates(secret) {
+		log.WithField("deviceId", id).Debug("invalid device credentials")
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+
+	user, err := a.UserService.UserByID(device.UserID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if user == nil {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+
+	if err := a.DeviceService.TouchDevice(device.ID, time.Now()); err != nil {
+		log.WithError(err).Error("error touching device")
+	}
+
+	ctx = context.WithValue(ctx, "user", user)
+	return context.WithValue(ctx, "device", device), nil
+}
+
+// owner the user of the bearer token in authorization, the token's session must not be revoked or expired
+func (a *DeviceAPI) owner(ctx context.Context, authorization string) (*domain.User, error) {
+	if len(authorization) <= 7 || strings.ToUpper(authorization[:6]) != "BEARER" {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	token, err := a.TokenAuth.Decode(authorization[7:])
+	if err != nil || token == nil || !token.Valid {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	_, claims, _ := jwtauth.FromContext(jwtauth.NewContext(ctx, token, nil))
+
+	jti, _ := claims["jti"].(string)
+	session, err := a.SessionService.Session(jti)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if session == nil || a.SessionPolicy.Expired(session, time.Now()) {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+
+	id, _ := claims["id"].(float64)
+	user, err := a.UserService.UserByID(int(id))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if user == nil {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+
+	switch err := authorize(ctx, claims, user); err {
+	case nil:
+	case errEnrolmentRequired:
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	default:
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	if !user.Verified {
+		return nil, status.Error(codes.PermissionDenied, "email must be verified")
+	}
+	return user, nil
+}
+
+// firstMetadata the first value of a metadata key
+func firstMetadata(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Register registers a device to the user of the token, the secret is only returned once
+func (a *DeviceAPI) Register(ctx context.Context, req *devicepb.RegisterRequest) (*devicepb.RegisterResponse, error) {
+	log.WithField("method", "Register").Info("starting")
+	user := ctx.Value("user").(*domain.User)
+
+	device := &domain.Device{UserID: user.ID, Name: req.Name}
+	if err := a.DeviceService.InsertDevice(device); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &devicepb.RegisterResponse{DeviceId: int64(device.ID), Secret: device.Secret}, nil
+}
+
+// StreamEvents records the open and close events streamed by a device, events without a time happened
+// when they were received. An invalid event ends the stream, the events before it are kept
+func (a *DeviceAPI) StreamEvents(stream grpc.ClientStreamingServer[devicepb.BoxEvent, devicepb.EventsResponse]) error {
+	log.WithField("method", "StreamEvents").Info("starting")
+	user := stream.Context().Value("user").(*domain.User)
+
+	accepted := int32(0)
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&devicepb.EventsResponse{Accepted: accepted})
+		}
+		if err != nil {
+			return err
+		}
+
+		t := time.Now()
+		if event.Time != 0 {
+			t = time.Unix(event.Time, 0)
+		}
+
+		switch event.Type {
+		case devicepb.EventType_OPENED:
+			openEvent := &domain.OpenEvent{CompID: int(event.Compartment), UserID: user.ID, Time: t}
+			if err := validate.Struct(nil, openEvent); err != nil {
+				return status.Error(codes.InvalidArgument, err.Error())
+			}
+			if err := a.Box.open(openEvent); err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+		case devicepb.EventType_CLOSED:
+			closeEvent := &domain.CloseEvent{CompID: int(event.Compartment), UserID: user.ID, Time: t}
+			if err := validate.Struct(nil, closeEvent); err != nil {
+				return status.Error(codes.InvalidArgument, err.Error())
+			}
+			if err := a.Box.close(closeEvent); err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+		default:
+			return status.Error(codes.InvalidArgument, "the event type must be opened or closed")
+		}
+		accepted++
+	}
+}
+
+// GetConfiguration the timezone of the user and the doses of their non archived pills over the next hours
+func (a *DeviceAPI) GetConfiguration(ctx context.Context, req *devicepb.ConfigurationRequest) (*devicepb.Configuration, error) {
+	log.WithField("method", "GetConfiguration").Info("starting")
+	user := ctx.Value("user").(*domain.User)
+
+	hours := int(req.Hours)
+	if hours == 0 {
+		hours = defaultConfigurationHours
+	}
+	if hours < 0 || hours > maxConfigurationHours {
+		return nil, status.Errorf(codes.InvalidArgument, "hours must be between 1 and %d", maxConfigurationHours)
+	}
+
+	pills, err := a.PillService.Pills(user.ID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	loc := user.Location()
+	now := time.Now().In(loc)
+	config := &devicepb.Configuration{Timezone: loc.String(), DoseWindowMinutes: int32(domain.DoseWindow / time.Minute)}
+	for _, pill := range pills {
+		if pill.Archived {
+			continue
+		}
+		for _, o := range pill.Occurrences(now, now.Add(time.Duration(hours)*time.Hour)) {
+			config.Doses = append(config.Doses, &devicepb.Dose{PillId: int64(pill.ID), Name: pill.Name, Time: o.Time.Unix(), Quantity: o.Quantity})
+		}
+	}
+	sort.SliceStable(config.Doses, func(i, j int) bool { return config.Doses[i].Time < config.Doses[j].Time })
+
+	return config, nil
+}
+
+// ReceiveCommands sends the device its queued commands as they are queued, until the device hangs up
+func (a *DeviceAPI) ReceiveCommands(req *devicepb.CommandsRequest, stream grpc.ServerStreamingServer[devicepb.Command]) error {
+	log.WithField("method", "ReceiveCommands").Info("starting")
+	device := stream.Context().Value("device").(*domain.Device)
+
+	poll := a.Poll
+	if poll <= 0 {
+		poll = defaultCommandPoll
+	}
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	for {
+		commands, err := a.DeviceService.PendingCommands(device.ID)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		for _, c := range commands {
+			command := &devicepb.Command{Id: int64(c.ID), Type: c.Type, Compartment: int32(c.CompID), Created: c.Created.Unix()}
+			if err := stream.Send(command); err != nil {
+				return err
+			}
+			if err := a.DeviceService.DeliverCommand(c.ID, time.Now()); err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeviceCtx adds the device in the url to the context, devices of other users aren't found
+func (a *DeviceAPI) DeviceCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.WithField("method", "DeviceCtx").Info("starting")
+		user := r.Context().Value("user").(*domain.User)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "deviceId"))
+		if err != nil {
+			render.WithMessage("unable to parse parameter device id").BadRequest(w, r)
+			return
+		}
+
+		device, err := a.DeviceService.Device(id)
+		if err != nil {
+			render.WithError(err).InternalServerError(w, r)
+			return
+		}
+		if device == nil || device.UserID != user.ID {
+			render.WithMessage("device not found").NotFound(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "device", device)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Devices lists the devices registered to the user
+func (a *DeviceAPI) Devices(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "Devices").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+
+	devices, err := a.DeviceService.Devices(user.ID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	if err := render.List(w, r, stc.NewDeviceListResponse(devices)); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
+
+// Device returns a device
+func (a *DeviceAPI) Device(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "Device").Info("starting")
+	device := r.Context().Value("device").(*domain.Device)
+
+	if err := render.Instance(w, r, stc.NewDeviceResponse(device)); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
+
+// DeleteDevice deletes a device, it can't authenticate anymore and its pending commands won't be sent
+func (a *DeviceAPI) DeleteDevice(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "DeleteDevice").Info("starting")
+	device := r.Context().Value("device").(*domain.Device)
+
+	if err := a.DeviceService.DeleteDevice(device.ID); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// QueueCommand queues a command for a device, it is sent the next time the device receives commands
+func (a *DeviceAPI) QueueCommand(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "QueueCommand").Info("starting")
+	device := r.Context().Value("device").(*domain.Device)
+
+	data := &stc.DeviceCommandRequest{}
+	if err := render.Bind(r, data); err != nil {
+		render.WithError(err).BadRequest(w, r)
+		return
+	}
+
+	command := data.DeviceCommand
+	command.ID = 0
+	command.DeviceID = device.ID
+	command.Created = time.Now()
+	command.Delivered = nil
+	if err := a.DeviceService.InsertCommand(command); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	if err := render.Instance(w, r, stc.NewDeviceCommandResponse(command)); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
@@ -0,0 +1,275 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/jwtauth"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/devicepb"
+	"github.com/jacsmith21/lukabox/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dialDevices serves the device rpcs over an in process listener and returns a client of them
+func dialDevices(t *testing.T, dAPI *DeviceAPI) devicepb.DeviceClient {
+	lis := bufconn.Listen(1024 * 1024)
+	s := dAPI.Server()
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return devicepb.NewDeviceClient(conn)
+}
+
+func TestDeviceRPCs(t *testing.T) {
+	uSvc := mock.UserService{}
+	sSvc := mock.SessionService{}
+	dSvc := mock.DeviceService{}
+	pSvc := mock.PillService{}
+	bSvc := mock.BoxService{}
+	peSvc := mock.PillEventService{}
+	aAPI := AdherenceAPI{UserService: &uSvc, PillService: &pSvc, PillEventService: &peSvc}
+	bAPI := BoxAPI{BoxService: &bSvc, Adherence: &aAPI}
+	dAPI := DeviceAPI{
+		DeviceService:  &dSvc,
+		UserService:    &uSvc,
+		SessionService: &sSvc,
+		PillService:    &pSvc,
+		Box:            &bAPI,
+		TokenAuth:      jwtauth.New("HS256", []byte("secret"), nil),
+		Poll:           10 * time.Millisecond,
+	}
+
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id, Verified: true, Timezone: "America/Halifax"}, nil
+	}
+	sSvc.SessionFn = func(id string) (*domain.Session, error) {
+		if id != "session" {
+			return nil, nil
+		}
+		return &domain.Session{ID: id, UserID: 1}, nil
+	}
+	devices := map[int]*domain.Device{}
+	dSvc.InsertDeviceFn = func(device *domain.Device) error {
+		device.ID = len(devices) + 1
+		device.Secret = "shh"
+		stored := *device
+		stored.Secret = domain.HashDeviceSecret("shh")
+		devices[device.ID] = &stored
+		return nil
+	}
+	dSvc.DeviceFn = func(id int) (*domain.Device, error) {
+		return devices[id], nil
+	}
+	dSvc.TouchDeviceFn = func(id int, lastSeen time.Time) error {
+		return nil
+	}
+	halifax, _ := time.LoadLocation("America/Halifax")
+	start := time.Now().In(halifax).Truncate(time.Second).Add(time.Hour)
+	pSvc.PillsFn = func(userID int) ([]*domain.Pill, error) {
+		return []*domain.Pill{
+			{ID: 1, UserID: userID, Name: "Advil", Schedule: &domain.Schedule{RRule: "FREQ=HOURLY;INTERVAL=8", Start: start}},
+			{ID: 2, UserID: userID, Name: "Tylenol", Schedule: &domain.Schedule{RRule: "FREQ=HOURLY;INTERVAL=8", Start: start}, Archived: true},
+		}, nil
+	}
+	peSvc.PillEventsFn = func(pillID int) ([]*domain.PillEvent, error) {
+		return []*domain.PillEvent{}, nil
+	}
+	taken := []*domain.PillEvent{}
+	peSvc.InsertPillEventFn = func(pillEvent *domain.PillEvent) error {
+		taken = append(taken, pillEvent)
+		return nil
+	}
+	opened := []*domain.OpenEvent{}
+	bSvc.InsertOpenEventFn = func(openEvent *domain.OpenEvent) error {
+		opened = append(opened, openEvent)
+		return nil
+	}
+	closed := []*domain.CloseEvent{}
+	bSvc.InsertCloseEventFn = func(closeEvent *domain.CloseEvent) error {
+		closed = append(closed, closeEvent)
+		return nil
+	}
+	pending := []*domain.DeviceCommand{{ID: 1, DeviceID: 1, Type: domain.UnlockCommand, CompID: 3, Created: start}}
+	dSvc.PendingCommandsFn = func(deviceID int) ([]*domain.DeviceCommand, error) {
+		commands := []*domain.DeviceCommand{}
+		for _, c := range pending {
+			if c.DeviceID == deviceID && c.Delivered == nil {
+				commands = append(commands, c)
+			}
+		}
+		return commands, nil
+	}
+	dSvc.DeliverCommandFn = func(id int, delivered time.Time) error {
+		pending[id-1].Delivered = &delivered
+		return nil
+	}
+
+	client := dialDevices(t, &dAPI)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := client.Register(ctx, &devicepb.RegisterRequest{Name: "kitchen"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected registering without a token to be unauthenticated, got %v", err)
+	}
+	if _, err := client.Register(metadata.AppendToOutgoingContext(ctx, "authorization", revokedToken), &devicepb.RegisterRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected registering with a revoked session to be unauthenticated, got %v", err)
+	}
+
+	registered, err := client.Register(metadata.AppendToOutgoingContext(ctx, "authorization", sessionToken), &devicepb.RegisterRequest{Name: "kitchen"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if registered.DeviceId != 1 || registered.Secret != "shh" || devices[1].UserID != 1 || devices[1].Name != "kitchen" {
+		t.Errorf("unexpected registration %+v of %+v", registered, devices[1])
+	}
+
+	wrong := metadata.AppendToOutgoingContext(ctx, "device-id", "1", "device-secret", "guess")
+	if _, err := client.GetConfiguration(wrong, &devicepb.ConfigurationRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected a wrong secret to be unauthenticated, got %v", err)
+	}
+
+	device := metadata.AppendToOutgoingContext(ctx, "device-id", "1", "device-secret", "shh")
+	config, err := client.GetConfiguration(device, &devicepb.ConfigurationRequest{Hours: 12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Timezone != "America/Halifax" || config.DoseWindowMinutes != 120 || len(config.Doses) != 2 {
+		t.Fatalf("unexpected configuration %+v", config)
+	}
+	if d := config.Doses[0]; d.PillId != 1 || d.Name != "Advil" || d.Time != start.Unix() {
+		t.Errorf("unexpected dose %+v", d)
+	}
+	if _, err := client.GetConfiguration(device, &devicepb.ConfigurationRequest{Hours: 1000}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected too many hours to be invalid, got %v", err)
+	}
+
+	events, err := client.StreamEvents(device)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the box is opened an hour early, which is still within the window of the first dose
+	early := start.Add(-time.Hour)
+	events.Send(&devicepb.BoxEvent{Type: devicepb.EventType_OPENED, Compartment: 3, Time: early.Unix()})
+	events.Send(&devicepb.BoxEvent{Type: devicepb.EventType_CLOSED, Compartment: 3})
+	res, err := events.CloseAndRecv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Accepted != 2 || len(opened) != 1 || len(closed) != 1 {
+		t.Fatalf("expected an open and a close event, got %d accepted, %d opened and %d closed", res.Accepted, len(opened), len(closed))
+	}
+	if opened[0].UserID != 1 || opened[0].CompID != 3 || !opened[0].Time.Equal(early) || closed[0].Time.IsZero() {
+		t.Errorf("unexpected events %+v and %+v", opened[0], closed[0])
+	}
+	if len(taken) != 1 || taken[0].PillID != 1 {
+		t.Errorf("expected the open event to be attributed to a dose of Advil, got %+v", taken)
+	}
+
+	events, err = client.StreamEvents(device)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events.Send(&devicepb.BoxEvent{Compartment: 3})
+	if _, err := events.CloseAndRecv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected an event without a type to be invalid, got %v", err)
+	}
+
+	events, err = client.StreamEvents(device)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events.Send(&devicepb.BoxEvent{Type: devicepb.EventType_CLOSED, Compartment: 3, Time: start.Add(time.Hour).Unix()})
+	if _, err := events.CloseAndRecv(); status.Code(err) != codes.InvalidArgument || status.Convert(err).Message() != "time can't be in the future" {
+		t.Errorf("expected an event in the future to be invalid, got %v", err)
+	}
+
+	commands, err := client.ReceiveCommands(device, &devicepb.CommandsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	command, err := commands.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if command.Id != 1 || command.Type != domain.UnlockCommand || command.Compartment != 3 || command.Created != start.Unix() {
+		t.Errorf("unexpected command %+v", command)
+	}
+}
+
+func TestDevices(t *testing.T) {
+	uAPI := UserAPI{}
+	uSvc := mock.UserService{}
+	uAPI.UserService = &uSvc
+	dAPI := DeviceAPI{}
+	dSvc := mock.DeviceService{}
+	dAPI.DeviceService = &dSvc
+
+	d := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id}, nil
+	}
+	device := &domain.Device{ID: 1, UserID: 1, Name: "kitchen", Secret: "shh", Registered: d, LastSeen: d}
+	dSvc.DeviceFn = func(id int) (*domain.Device, error) {
+		if id == 1 {
+			return device, nil
+		}
+		return nil, nil
+	}
+	dSvc.DevicesFn = func(userID int) ([]*domain.Device, error) {
+		return []*domain.Device{device}, nil
+	}
+	dSvc.InsertCommandFn = func(command *domain.DeviceCommand) error {
+		command.ID = 1
+		command.Created = d
+		return nil
+	}
+	deleted := 0
+	dSvc.DeleteDeviceFn = func(id int) error {
+		deleted = id
+		return nil
+	}
+
+	r := chi.NewRouter()
+	r.Route("/users/{userId}/devices", func(r chi.Router) {
+		r.Use(uAPI.UserCtx)
+		r.Get("/", dAPI.Devices)
+		r.Route("/{deviceId}", func(r chi.Router) {
+			r.Use(dAPI.DeviceCtx)
+			r.Get("/", dAPI.Device)
+			r.Delete("/", dAPI.DeleteDevice)
+			r.Post("/commands", dAPI.QueueCommand)
+		})
+	})
+
+	jsonHeader := map[string]string{"Content-Type": "application/json"}
+	tests := []*test{
+		{"/users/1/devices", "GET", "", nil, http.StatusOK, `[{"id":1,"userId":1,"name":"kitchen","registered":"2009-11-10T23:00:00Z","lastSeen":"2009-11-10T23:00:00Z"}]`},
+		{"/users/2/devices/1", "GET", "", nil, http.StatusNotFound, `{"message":"device not found"}`},
+		{"/users/1/devices/1/commands", "POST", `{"type":"ring"}`, jsonHeader, http.StatusCreated, `{"id":1,"deviceId":1,"type":"ring","compId":0,"created":"2009-11-10T23:00:00Z","delivered":null}`},
+		{"/users/1/devices/1/commands", "POST", `{"type":"unlock"}`, jsonHeader, http.StatusBadRequest, `{"message":"an unlock command needs a compartment"}`},
+		{"/users/2/devices/1", "DELETE", "", nil, http.StatusNotFound, `{"message":"device not found"}`},
+		{"/users/1/devices/1", "DELETE", "", nil, http.StatusNoContent, ""},
+	}
+	runTests(t, r, tests)
+
+	if deleted != 1 {
+		t.Errorf("expected device 1 to be deleted, got %d", deleted)
+	}
+}
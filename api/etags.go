@@ -0,0 +1,62 @@
+package api
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// etag the entity tag of a version of a user or pill
+func etag(version int) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// pillsETag the entity tag of a list of pills, it changes whenever a pill is added or updated
+func pillsETag(pills []*domain.Pill) string {
+	h := fnv.New64a()
+	for _, p := range pills {
+		fmt.Fprintf(h, "%d.%d,", p.ID, p.Version)
+	}
+	return fmt.Sprintf(`"%x"`, h.Sum64())
+}
+
+// notModified sets the ETag of the response and writes a 304 when it matches If-None-Match,
+// returns whether the response was written
+func notModified(w http.ResponseWriter, r *http.Request, tag string) bool {
+	w.Header().Set("ETag", tag)
+	if !matchesETag(r.Header.Get("If-None-Match"), tag, true) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// preconditionMet whether If-Match matches the tag, requests without If-Match are always allowed
+func preconditionMet(r *http.Request, tag string) bool {
+	header := r.Header.Get("If-Match")
+	return header == "" || matchesETag(header, tag, false)
+}
+
+// matchesETag whether a list of entity tags in an If-Match or If-None-Match header matches the tag,
+// weak tags only match when comparing weakly as If-None-Match does
+func matchesETag(header string, tag string, weak bool) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" {
+			return true
+		}
+		if strings.HasPrefix(t, "W/") {
+			if !weak {
+				continue
+			}
+			t = t[2:]
+		}
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
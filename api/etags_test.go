@@ -0,0 +1,118 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/mock"
+)
+
+func TestPillETags(t *testing.T) {
+	pAPI := PillAPI{}
+	pSvc := mock.PillService{}
+	pAPI.PillService = &pSvc
+	iSvc := mock.InteractionService{}
+	pAPI.InteractionService = &iSvc
+	uAPI := UserAPI{}
+	uSvc := mock.UserService{}
+	uAPI.UserService = &uSvc
+
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id}, nil
+	}
+	stored := &domain.Pill{ID: 1, UserID: 1, Name: "DoxyPoxy", DaysOfWeek: []int{1}, Version: 3}
+	pSvc.PillFn = func(id int) (*domain.Pill, error) {
+		return stored, nil
+	}
+	pSvc.PillsFn = func(id int) ([]*domain.Pill, error) {
+		return []*domain.Pill{stored}, nil
+	}
+	iSvc.InteractionsFn = func(pill string, other string) ([]*domain.Interaction, error) {
+		return []*domain.Interaction{}, nil
+	}
+	// another update lands between reading the pill and saving it when the name is Race
+	pSvc.UpdatePillFn = func(id int, pill *domain.Pill) error {
+		if pill.Version != stored.Version || pill.Name == "Race" {
+			return domain.ErrStale
+		}
+		pill.Version++
+		return nil
+	}
+
+	r := chi.NewRouter()
+	r.Route("/users/{userId}/pills", func(r chi.Router) {
+		r.Use(uAPI.UserCtx)
+		r.Get("/", pAPI.Pills)
+		r.Route("/{pillId}", func(r chi.Router) {
+			r.Use(pAPI.PillCtx)
+			r.Get("/", pAPI.Pill)
+			r.Post("/", pAPI.UpdatePill)
+		})
+	})
+
+	pill := `{"pillId":1,"id":1,"name":"DoxyPoxy","medicationId":"","generic":"","daysOfWeek":[1],"timesOfDay":null,"schedule":null,"archived":false,"strength":0,"unit":"","form":"","route":"","food":"","instructions":"","prescriber":"","startDate":null,"endDate":null,"quantity":0,"quantityPerDose":0,"alertDays":0}`
+	tests := []*test{
+		{"/users/1/pills/1", "GET", "", nil, http.StatusOK, pill},
+		{"/users/1/pills/1", "GET", "", map[string]string{"If-None-Match": `"3"`}, http.StatusNotModified, ""},
+		{"/users/1/pills/1", "GET", "", map[string]string{"If-None-Match": `"1", W/"3"`}, http.StatusNotModified, ""},
+		{"/users/1/pills/1", "GET", "", map[string]string{"If-None-Match": `"2"`}, http.StatusOK, pill},
+		{"/users/2/pills/1", "GET", "", nil, http.StatusNotFound, `{"message":"pill not found"}`},
+		{"/users/1/pills/1", "POST", `{"name":"DoxyPoxy"}`, map[string]string{"Content-Type": "application/json", "If-Match": `"2"`}, http.StatusPreconditionFailed, `{"message":"the pill has been updated since it was read"}`},
+		{"/users/1/pills/1", "POST", `{"name":"Race"}`, map[string]string{"Content-Type": "application/json", "If-Match": `"3"`}, http.StatusPreconditionFailed, `{"message":"the pill has been updated since it was read"}`},
+		{"/users/1/pills/1", "POST", `{"name":"DoxyPoxy","daysOfWeek":[1]}`, map[string]string{"Content-Type": "application/json", "If-Match": `W/"3"`}, http.StatusPreconditionFailed, `{"message":"the pill has been updated since it was read"}`},
+	}
+
+	runTests(t, r, tests)
+
+	for _, ifMatch := range []string{`"3"`, "*", ""} {
+		stored.Version = 3
+		req := httptest.NewRequest("POST", "/users/1/pills/1", strings.NewReader(`{"name":"DoxyPoxy","daysOfWeek":[1]}`))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Header().Get("ETag") != `"4"` {
+			t.Errorf("expected the update with If-Match %s to succeed with the next version, got %d and %s", ifMatch, w.Code, w.Header().Get("ETag"))
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/users/1/pills", nil))
+	tag := w.Header().Get("ETag")
+	req := httptest.NewRequest("GET", "/users/1/pills", nil)
+	req.Header.Set("If-None-Match", tag)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if tag == "" || w.Code != http.StatusNotModified {
+		t.Errorf("expected the unchanged list with ETag %s not to be modified, got %d", tag, w.Code)
+	}
+}
+
+func TestUserETags(t *testing.T) {
+	uAPI := UserAPI{}
+	uSvc := mock.UserService{}
+	uAPI.UserService = &uSvc
+
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id, Email: "jacob.smith@unb.ca", Password: "password", FirstName: "Jacob", LastName: "Smith", Version: 5}, nil
+	}
+
+	r := chi.NewRouter()
+	r.Route("/users/{userId}", func(r chi.Router) {
+		r.Use(uAPI.UserCtx)
+		r.Get("/", uAPI.UserByID)
+		r.Post("/", uAPI.UpdateUser)
+	})
+
+	tests := []*test{
+		{"/users/1", "GET", "", map[string]string{"If-None-Match": `"5"`}, http.StatusNotModified, ""},
+		{"/users/1", "POST", `{"firstName":"Jake"}`, map[string]string{"Content-Type": "application/json", "If-Match": `"4"`}, http.StatusPreconditionFailed, `{"message":"the user has been updated since it was read"}`},
+	}
+	runTests(t, r, tests)
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/fhir"
+	"github.com/jacsmith21/lukabox/ext/log"
+	"github.com/jacsmith21/lukabox/ext/render"
+)
+
+// exportDays how many days an export covers when from isn't supplied
+const exportDays = 30
+
+// FHIRAPI the services used
+type FHIRAPI struct {
+	PillService      domain.PillService
+	PillEventService domain.PillEventService
+}
+
+// Patient returns the user as a FHIR Patient
+func (a *FHIRAPI) Patient(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "Patient").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+	writeFHIR(w, fhir.NewPatient(user))
+}
+
+// MedicationStatement returns a pill as a FHIR MedicationStatement
+func (a *FHIRAPI) MedicationStatement(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "MedicationStatement").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+	pill := r.Context().Value("pill").(*domain.Pill)
+
+	if pill.UserID != user.ID {
+		render.WithMessage("pill not found").NotFound(w, r)
+		return
+	}
+
+	writeFHIR(w, fhir.NewMedicationStatement(pill, time.Now().In(user.Location())))
+}
+
+// MedicationRequest returns a pill as a FHIR MedicationRequest
+func (a *FHIRAPI) MedicationRequest(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "MedicationRequest").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+	pill := r.Context().Value("pill").(*domain.Pill)
+
+	if pill.UserID != user.ID {
+		render.WithMessage("pill not found").NotFound(w, r)
+		return
+	}
+
+	writeFHIR(w, fhir.NewMedicationRequest(pill, time.Now().In(user.Location())))
+}
+
+// MedicationAdministration returns a dose taken by the user as a FHIR MedicationAdministration
+func (a *FHIRAPI) MedicationAdministration(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "MedicationAdministration").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+
+	id, err := strconv.Atoi(chi.URLParam(r, "eventId"))
+	if err != nil {
+		render.WithMessage("unable to parse parameter id").BadRequest(w, r)
+		return
+	}
+
+	pills, err := a.PillService.Pills(user.ID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	for _, pill := range pills {
+		events, err := a.PillEventService.PillEvents(pill.ID)
+		if err != nil {
+			render.WithError(err).InternalServerError(w, r)
+			return
+		}
+		for _, e := range events {
+			if e.ID == id {
+				writeFHIR(w, fhir.NewMedicationAdministration(pill, e))
+				return
+			}
+		}
+	}
+
+	render.WithMessage("administration not found").NotFound(w, r)
+}
+
+// Export returns a FHIR Bundle of the user, their pills and the doses taken or missed between ?from= and ?to=,
+// to defaults to now and from to exportDays before to
+func (a *FHIRAPI) Export(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "Export").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+	now := time.Now().In(user.Location())
+
+	to := now
+	if t := r.URL.Query().Get("to"); t != "" {
+		var err error
+		if to, err = time.Parse(time.RFC3339, t); err != nil {
+			render.WithMessage("to must be an RFC 3339 time").BadRequest(w, r)
+			return
+		}
+	}
+	from := to.AddDate(0, 0, -exportDays)
+	if f := r.URL.Query().Get("from"); f != "" {
+		var err error
+		if from, err = time.Parse(time.RFC3339, f); err != nil {
+			render.WithMessage("from must be an RFC 3339 time").BadRequest(w, r)
+			return
+		}
+	}
+	if !from.Before(to) {
+		render.WithMessage("from must be before to").BadRequest(w, r)
+		return
+	}
+	from, to = from.In(user.Location()), to.In(user.Location())
+
+	pills, err := a.PillService.Pills(user.ID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	resources := []interface{}{fhir.NewPatient(user)}
+	administrations := []interface{}{}
+	for _, pill := range pills {
+		resources = append(resources, fhir.NewMedicationRequest(pill, now), fhir.NewMedicationStatement(pill, now))
+
+		events, err := a.PillEventService.PillEvents(pill.ID)
+		if err != nil {
+			render.WithError(err).InternalServerError(w, r)
+			return
+		}
+		for _, e := range events {
+			if !e.Time.Before(from) && e.Time.Before(to) {
+				administrations = append(administrations, fhir.NewMedicationAdministration(pill, e))
+			}
+		}
+
+		// doses are only missed once their window has passed
+		end := to
+		if missed := now.Add(-domain.DoseWindow); missed.Before(end) {
+			end = missed
+		}
+		for _, dose := range pill.Doses(from, end) {
+			if !domain.DoseTaken(dose, events) {
+				administrations = append(administrations, fhir.NewMissedAdministration(pill, dose))
+			}
+		}
+	}
+
+	writeFHIR(w, fhir.NewBundle(append(resources, administrations...), now))
+}
+
+// writeFHIR writes a resource as FHIR JSON
+func writeFHIR(w http.ResponseWriter, resource interface{}) {
+	w.Header().Set("Content-Type", fhir.ContentType)
+	if err := json.NewEncoder(w).Encode(resource); err != nil {
+		log.WithError(err).Error("error writing fhir resource")
+	}
+}
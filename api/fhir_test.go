@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/mock"
+)
+
+// fhirRequired the fields FHIR R4 requires of each resource and the codes allowed for their status
+var fhirRequired = map[string]struct {
+	fields   []string
+	statuses []string
+}{
+	"Patient":                  {[]string{"id"}, nil},
+	"MedicationStatement":      {[]string{"id", "status", "medicationCodeableConcept", "subject"}, []string{"active", "completed", "entered-in-error", "intended", "stopped", "on-hold", "unknown", "not-taken"}},
+	"MedicationRequest":        {[]string{"id", "status", "intent", "medicationCodeableConcept", "subject"}, []string{"active", "on-hold", "cancelled", "completed", "entered-in-error", "stopped", "draft", "unknown"}},
+	"MedicationAdministration": {[]string{"id", "status", "medicationCodeableConcept", "subject", "effectiveDateTime"}, []string{"in-progress", "not-done", "on-hold", "completed", "entered-in-error", "stopped", "unknown"}},
+	"Bundle":                   {[]string{"type"}, nil},
+}
+
+// validateFHIR checks a resource has the fields FHIR R4 requires, returning its type
+func validateFHIR(t *testing.T, resource map[string]interface{}) string {
+	resourceType, _ := resource["resourceType"].(string)
+	required, ok := fhirRequired[resourceType]
+	if !ok {
+		t.Errorf("unexpected resource type %q", resourceType)
+		return resourceType
+	}
+
+	for _, field := range required.fields {
+		if v, ok := resource[field]; !ok || v == "" || v == nil {
+			t.Errorf("%s is missing required field %s", resourceType, field)
+		}
+	}
+	if required.statuses != nil {
+		valid := false
+		for _, status := range required.statuses {
+			valid = valid || resource["status"] == status
+		}
+		if !valid {
+			t.Errorf("%s has invalid status %v", resourceType, resource["status"])
+		}
+	}
+	if subject, ok := resource["subject"].(map[string]interface{}); ok && subject["reference"] == "" {
+		t.Errorf("%s has a subject without a reference", resourceType)
+	}
+	return resourceType
+}
+
+func TestFHIRResources(t *testing.T) {
+	uAPI := UserAPI{}
+	uSvc := mock.UserService{}
+	uAPI.UserService = &uSvc
+	pAPI := PillAPI{}
+	pSvc := mock.PillService{}
+	pAPI.PillService = &pSvc
+	fAPI := FHIRAPI{}
+	eSvc := mock.PillEventService{}
+	fAPI.PillService = &pSvc
+	fAPI.PillEventService = &eSvc
+
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id, Email: "jacob.smith@unb.ca", FirstName: "Jacob", LastName: "Smith"}, nil
+	}
+	pill := &domain.Pill{ID: 1, UserID: 1, Name: "DoxyPoxy", Strength: 100, Unit: "mg", Route: "oral", QuantityPerDose: 2, Form: "tablet", Prescriber: "Dr. Jones", DaysOfWeek: []int{1, 3}, TimesOfDay: []domain.TimeOfDay{{Hour: 8}}}
+	pSvc.PillFn = func(id int) (*domain.Pill, error) {
+		return pill, nil
+	}
+	pSvc.PillsFn = func(id int) ([]*domain.Pill, error) {
+		if id != 1 {
+			return []*domain.Pill{}, nil
+		}
+		return []*domain.Pill{pill}, nil
+	}
+	eSvc.PillEventsFn = func(pillID int) ([]*domain.PillEvent, error) {
+		return []*domain.PillEvent{{ID: 5, PillID: 1, Scheduled: time.Date(2018, time.March, 5, 8, 0, 0, 0, time.UTC), Time: time.Date(2018, time.March, 5, 8, 10, 0, 0, time.UTC)}}, nil
+	}
+
+	tests := []*test{
+		{"/users/1/fhir/Patient", "GET", "", nil, http.StatusOK, `{"resourceType":"Patient","id":"1","active":true,"name":[{"family":"Smith","given":["Jacob"]}],"telecom":[{"system":"email","value":"jacob.smith@unb.ca"}]}`},
+		{"/users/1/fhir/MedicationRequest/1", "GET", "", nil, http.StatusOK, `{"resourceType":"MedicationRequest","id":"1","status":"active","intent":"order","medicationCodeableConcept":{"text":"DoxyPoxy 100 mg"},"subject":{"reference":"Patient/1"},"requester":{"display":"Dr. Jones"},"dosageInstruction":[{"timing":{"repeat":{"dayOfWeek":["mon","wed"],"timeOfDay":["08:00:00"]}},"route":{"coding":[{"system":"http://snomed.info/sct","code":"26643006","display":"Oral route"}],"text":"oral"},"doseAndRate":[{"doseQuantity":{"value":2,"unit":"tablet"}}]}]}`},
+		{"/users/2/fhir/MedicationRequest/1", "GET", "", nil, http.StatusNotFound, `{"message":"pill not found"}`},
+		{"/users/1/fhir/MedicationAdministration/5", "GET", "", nil, http.StatusOK, `{"resourceType":"MedicationAdministration","id":"5","status":"completed","medicationCodeableConcept":{"text":"DoxyPoxy 100 mg"},"subject":{"reference":"Patient/1"},"effectiveDateTime":"2018-03-05T08:10:00Z","request":{"reference":"MedicationRequest/1"},"dosage":{"route":{"coding":[{"system":"http://snomed.info/sct","code":"26643006","display":"Oral route"}],"text":"oral"},"dose":{"value":2,"unit":"tablet"}}}`},
+		{"/users/2/fhir/MedicationAdministration/5", "GET", "", nil, http.StatusNotFound, `{"message":"administration not found"}`},
+		{"/users/1/fhir?from=2018-03-05", "GET", "", nil, http.StatusBadRequest, `{"message":"from must be an RFC 3339 time"}`},
+		{"/users/1/fhir?from=2018-03-08T00:00:00Z&to=2018-03-01T00:00:00Z", "GET", "", nil, http.StatusBadRequest, `{"message":"from must be before to"}`},
+	}
+
+	r := chi.NewRouter()
+	r.Route("/users/{userId}/fhir", func(r chi.Router) {
+		r.Use(uAPI.UserCtx)
+		r.Get("/", fAPI.Export)
+		r.Get("/Patient", fAPI.Patient)
+		r.With(pAPI.PillCtx).Get("/MedicationStatement/{pillId}", fAPI.MedicationStatement)
+		r.With(pAPI.PillCtx).Get("/MedicationRequest/{pillId}", fAPI.MedicationRequest)
+		r.Get("/MedicationAdministration/{eventId}", fAPI.MedicationAdministration)
+	})
+
+	runTests(t, r, tests)
+
+	for _, url := range []string{"/users/1/fhir/Patient", "/users/1/fhir/MedicationStatement/1", "/users/1/fhir/MedicationRequest/1", "/users/1/fhir/MedicationAdministration/5"} {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if contentType := w.Header().Get("Content-Type"); contentType != "application/fhir+json" {
+			t.Errorf("expected application/fhir+json, got %s", contentType)
+		}
+		resource := map[string]interface{}{}
+		if err := json.Unmarshal(w.Body.Bytes(), &resource); err != nil {
+			t.Fatal(err)
+		}
+		validateFHIR(t, resource)
+	}
+
+	// a week with doses on monday and wednesday, monday's was taken and wednesday's was missed
+	req, err := http.NewRequest("GET", "/users/1/fhir?from=2018-03-05T00:00:00Z&to=2018-03-12T00:00:00Z", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	bundle := map[string]interface{}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &bundle); err != nil {
+		t.Fatal(err)
+	}
+	if validateFHIR(t, bundle) != "Bundle" || bundle["type"] != "collection" {
+		t.Fatalf("expected a collection bundle, got %v", bundle)
+	}
+
+	types := map[string]int{}
+	statuses := []interface{}{}
+	for _, e := range bundle["entry"].([]interface{}) {
+		entry := e.(map[string]interface{})
+		resource := entry["resource"].(map[string]interface{})
+		resourceType := validateFHIR(t, resource)
+		if entry["fullUrl"] != resourceType+"/"+resource["id"].(string) {
+			t.Errorf("unexpected fullUrl %v", entry["fullUrl"])
+		}
+		types[resourceType]++
+		if resourceType == "MedicationAdministration" {
+			statuses = append(statuses, resource["status"])
+		}
+	}
+	if types["Patient"] != 1 || types["MedicationStatement"] != 1 || types["MedicationRequest"] != 1 || len(statuses) != 2 || statuses[0] != "completed" || statuses[1] != "not-done" {
+		t.Errorf("unexpected bundle entries %v %v", types, statuses)
+	}
+}
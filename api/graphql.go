@@ -0,0 +1,395 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/graphql"
+	"github.com/jacsmith21/lukabox/ext/log"
+	"github.com/jacsmith21/lukabox/ext/render"
+)
+
+// GraphQLAPI the services the graph is resolved through
+type GraphQLAPI struct {
+	UserService      domain.UserService
+	PillService      domain.PillService
+	PillEventService domain.PillEventService
+	BoxService       domain.BoxService
+
+	once   sync.Once
+	schema *graphql.Schema
+}
+
+// loaders batch and cache the values loaded while resolving a query
+type loaders struct {
+	users       *graphql.Loader
+	pills       *graphql.Loader
+	userPills   *graphql.Loader
+	pillEvents  *graphql.Loader
+	openEvents  *graphql.Loader
+	closeEvents *graphql.Loader
+}
+
+// GraphQL executes a query sent as JSON in a POST or in the query, variables and operationName parameters of a
+// GET. Like RequestValidator the token must be valid for the user whose data is queried, so users can only
+// query their own graph. Queries which can't be executed are a bad request
+func (a *GraphQLAPI) GraphQL(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "GraphQL").Info("starting")
+
+	_, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		render.Unauthorized(w, r)
+		return
+	}
+
+	id, _ := claims["id"].(float64)
+	viewer, err := a.UserService.UserByID(int(id))
+	if err != nil || viewer == nil {
+		render.Unauthorized(w, r)
+		return
+	}
+	switch err := authorize(r.Context(), claims, viewer); err {
+	case nil:
+	case errEnrolmentRequired:
+		render.WithError(err).Forbidden(w, r)
+		return
+	default:
+		render.Unauthorized(w, r)
+		return
+	}
+
+	request := &graphql.Request{}
+	if r.Method == http.MethodGet {
+		request.Query = r.URL.Query().Get("query")
+		request.OperationName = r.URL.Query().Get("operationName")
+		if v := r.URL.Query().Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &request.Variables); err != nil {
+				render.WithMessage("variables must be a JSON object").BadRequest(w, r)
+				return
+			}
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		render.WithMessage("the request must be a JSON object with a query").BadRequest(w, r)
+		return
+	}
+	if request.Query == "" {
+		render.WithMessage("a query must be supplied").BadRequest(w, r)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), "user", viewer)
+	ctx = context.WithValue(ctx, "loaders", a.loaders())
+	response := a.graph().Execute(ctx, request)
+
+	w.Header().Set("Content-Type", "application/json")
+	if response.Data == nil {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.WithError(err).Error("error writing graphql response")
+	}
+}
+
+// loaders creates the loaders of a request, each fetches the keys it is given from the services
+func (a *GraphQLAPI) loaders() *loaders {
+	fetch := func(get func(key int) (interface{}, error)) *graphql.Loader {
+		return &graphql.Loader{Fetch: func(keys []int) (map[int]interface{}, error) {
+			values := map[int]interface{}{}
+			for _, k := range keys {
+				v, err := get(k)
+				if err != nil {
+					return nil, err
+				}
+				values[k] = v
+			}
+			return values, nil
+		}}
+	}
+
+	return &loaders{
+		users: fetch(func(id int) (interface{}, error) { return a.UserService.UserByID(id) }),
+		pills: fetch(func(id int) (interface{}, error) { return a.PillService.Pill(id) }),
+		userPills: fetch(func(userID int) (interface{}, error) {
+			return a.PillService.Pills(userID)
+		}),
+		pillEvents: fetch(func(pillID int) (interface{}, error) {
+			return a.PillEventService.PillEvents(pillID)
+		}),
+		openEvents: fetch(func(userID int) (interface{}, error) {
+			return a.BoxService.OpenEvents(userID)
+		}),
+		closeEvents: fetch(func(userID int) (interface{}, error) {
+			return a.BoxService.CloseEvents(userID)
+		}),
+	}
+}
+
+func requestLoaders(ctx context.Context) *loaders {
+	return ctx.Value("loaders").(*loaders)
+}
+
+// load loads the value of the key of every source
+func load(loader *graphql.Loader, sources []interface{}, key func(source interface{}) int) ([]interface{}, error) {
+	keys := make([]int, len(sources))
+	for i, s := range sources {
+		keys[i] = key(s)
+	}
+	return loader.LoadMany(keys)
+}
+
+// scalar a field whose value is read from its source
+func scalar(get func(source interface{}) interface{}) *graphql.Field {
+	return &graphql.Field{Resolve: func(p *graphql.Params) (interface{}, error) {
+		return get(p.Source), nil
+	}}
+}
+
+// graph the schema of the graph, built once
+func (a *GraphQLAPI) graph() *graphql.Schema {
+	a.once.Do(func() {
+		a.schema = a.newSchema()
+	})
+	return a.schema
+}
+
+func (a *GraphQLAPI) newSchema() *graphql.Schema {
+	user := &graphql.Object{Name: "User"}
+	pill := &graphql.Object{Name: "Pill"}
+	box := &graphql.Object{Name: "Box"}
+	openEvent := &graphql.Object{Name: "OpenEvent"}
+	closeEvent := &graphql.Object{Name: "CloseEvent"}
+	pillEvent := &graphql.Object{Name: "PillEvent"}
+	report := &graphql.Object{Name: "AdherenceReport"}
+	pillAdherence := &graphql.Object{Name: "PillAdherence"}
+	dayAdherence := &graphql.Object{Name: "DayAdherence"}
+
+	userOf := func(id func(source interface{}) int) func(p *graphql.BatchParams) ([]interface{}, error) {
+		return func(p *graphql.BatchParams) ([]interface{}, error) {
+			return load(requestLoaders(p.Context).users, p.Sources, id)
+		}
+	}
+	pillOf := func(id func(source interface{}) int) func(p *graphql.BatchParams) ([]interface{}, error) {
+		return func(p *graphql.BatchParams) ([]interface{}, error) {
+			return load(requestLoaders(p.Context).pills, p.Sources, id)
+		}
+	}
+
+	query := &graphql.Object{Name: "Query", Fields: map[string]*graphql.Field{
+		"me": {Type: user, Resolve: func(p *graphql.Params) (interface{}, error) {
+			return p.Context.Value("user"), nil
+		}},
+		"user": {Type: user, Args: map[string]string{"id": "Int!"}, Resolve: func(p *graphql.Params) (interface{}, error) {
+			viewer := p.Context.Value("user").(*domain.User)
+			if p.Args["id"].(int) != viewer.ID {
+				return nil, errors.New("unauthorized")
+			}
+			return viewer, nil
+		}},
+		"pill": {Type: pill, Args: map[string]string{"id": "Int!"}, Resolve: func(p *graphql.Params) (interface{}, error) {
+			viewer := p.Context.Value("user").(*domain.User)
+			pills, err := requestLoaders(p.Context).pills.LoadMany([]int{p.Args["id"].(int)})
+			if err != nil {
+				return nil, err
+			}
+			found, _ := pills[0].(*domain.Pill)
+			if found == nil || found.UserID != viewer.ID {
+				return nil, errors.New("pill not found")
+			}
+			return found, nil
+		}},
+	}}
+
+	user.Fields = map[string]*graphql.Field{
+		"id":        scalar(func(s interface{}) interface{} { return s.(*domain.User).ID }),
+		"email":     scalar(func(s interface{}) interface{} { return s.(*domain.User).Email }),
+		"firstName": scalar(func(s interface{}) interface{} { return s.(*domain.User).FirstName }),
+		"lastName":  scalar(func(s interface{}) interface{} { return s.(*domain.User).LastName }),
+		"role":      scalar(func(s interface{}) interface{} { return s.(*domain.User).Role }),
+		"timezone":  scalar(func(s interface{}) interface{} { return s.(*domain.User).Timezone }),
+		"verified":  scalar(func(s interface{}) interface{} { return s.(*domain.User).Verified }),
+		"pills": {Type: pill, List: true, Args: map[string]string{"archived": "Boolean"}, Batch: func(p *graphql.BatchParams) ([]interface{}, error) {
+			values, err := load(requestLoaders(p.Context).userPills, p.Sources, func(s interface{}) int { return s.(*domain.User).ID })
+			if err != nil {
+				return nil, err
+			}
+			archived, filter := p.Args["archived"].(bool)
+			for i, v := range values {
+				pills := []*domain.Pill{}
+				for _, pill := range v.([]*domain.Pill) {
+					if !filter || pill.Archived == archived {
+						pills = append(pills, pill)
+					}
+				}
+				values[i] = pills
+			}
+			return values, nil
+		}},
+		"box": {Type: box, Resolve: func(p *graphql.Params) (interface{}, error) {
+			return p.Source, nil
+		}},
+		"adherence": {Type: report, Args: map[string]string{"from": "String", "to": "String"}, Resolve: a.resolveAdherence},
+	}
+
+	box.Fields = map[string]*graphql.Field{
+		"openEvents": {Type: openEvent, List: true, Batch: func(p *graphql.BatchParams) ([]interface{}, error) {
+			return load(requestLoaders(p.Context).openEvents, p.Sources, func(s interface{}) int { return s.(*domain.User).ID })
+		}},
+		"closeEvents": {Type: closeEvent, List: true, Batch: func(p *graphql.BatchParams) ([]interface{}, error) {
+			return load(requestLoaders(p.Context).closeEvents, p.Sources, func(s interface{}) int { return s.(*domain.User).ID })
+		}},
+	}
+
+	openEvent.Fields = map[string]*graphql.Field{
+		"id":          scalar(func(s interface{}) interface{} { return s.(*domain.OpenEvent).ID }),
+		"compartment": scalar(func(s interface{}) interface{} { return s.(*domain.OpenEvent).CompID }),
+		"time":        scalar(func(s interface{}) interface{} { return s.(*domain.OpenEvent).Time }),
+		"user":        {Type: user, Batch: userOf(func(s interface{}) int { return s.(*domain.OpenEvent).UserID })},
+	}
+
+	closeEvent.Fields = map[string]*graphql.Field{
+		"id":          scalar(func(s interface{}) interface{} { return s.(*domain.CloseEvent).ID }),
+		"compartment": scalar(func(s interface{}) interface{} { return s.(*domain.CloseEvent).CompID }),
+		"time":        scalar(func(s interface{}) interface{} { return s.(*domain.CloseEvent).Time }),
+		"user":        {Type: user, Batch: userOf(func(s interface{}) int { return s.(*domain.CloseEvent).UserID })},
+	}
+
+	pill.Fields = map[string]*graphql.Field{
+		"id":           scalar(func(s interface{}) interface{} { return s.(*domain.Pill).ID }),
+		"name":         scalar(func(s interface{}) interface{} { return s.(*domain.Pill).Name }),
+		"generic":      scalar(func(s interface{}) interface{} { return s.(*domain.Pill).Generic }),
+		"medicationId": scalar(func(s interface{}) interface{} { return s.(*domain.Pill).MedicationID }),
+		"dosage":       scalar(func(s interface{}) interface{} { return s.(*domain.Pill).Dosage() }),
+		"strength":     scalar(func(s interface{}) interface{} { return s.(*domain.Pill).Strength }),
+		"unit":         scalar(func(s interface{}) interface{} { return s.(*domain.Pill).Unit }),
+		"form":         scalar(func(s interface{}) interface{} { return s.(*domain.Pill).Form }),
+		"quantity":     scalar(func(s interface{}) interface{} { return s.(*domain.Pill).Quantity }),
+		"archived":     scalar(func(s interface{}) interface{} { return s.(*domain.Pill).Archived }),
+		"asNeeded": scalar(func(s interface{}) interface{} {
+			p := s.(*domain.Pill)
+			return p.Schedule != nil && p.Schedule.AsNeeded
+		}),
+		"user": {Type: user, Batch: userOf(func(s interface{}) int { return s.(*domain.Pill).UserID })},
+		"events": {Type: pillEvent, List: true, Batch: func(p *graphql.BatchParams) ([]interface{}, error) {
+			return load(requestLoaders(p.Context).pillEvents, p.Sources, func(s interface{}) int { return s.(*domain.Pill).ID })
+		}},
+		"nextDoses": {List: true, Args: map[string]string{"count": "Int"}, Resolve: func(p *graphql.Params) (interface{}, error) {
+			viewer := p.Context.Value("user").(*domain.User)
+			count, ok := p.Args["count"].(int)
+			if !ok {
+				count = 10
+			}
+			if count < 1 || count > 100 {
+				return nil, errors.New("count must be between 1 and 100")
+			}
+			now := time.Now().In(viewer.Location())
+			doses := p.Source.(*domain.Pill).Doses(now, now.Add(previewLength))
+			if len(doses) > count {
+				doses = doses[:count]
+			}
+			return doses, nil
+		}},
+	}
+
+	pillEvent.Fields = map[string]*graphql.Field{
+		"id":        scalar(func(s interface{}) interface{} { return s.(*domain.PillEvent).ID }),
+		"scheduled": scalar(func(s interface{}) interface{} { return s.(*domain.PillEvent).Scheduled }),
+		"time":      scalar(func(s interface{}) interface{} { return s.(*domain.PillEvent).Time }),
+		"late": scalar(func(s interface{}) interface{} {
+			e := s.(*domain.PillEvent)
+			return e.Time.Sub(e.Scheduled) > domain.LateAfter
+		}),
+		"pill": {Type: pill, Batch: pillOf(func(s interface{}) int { return s.(*domain.PillEvent).PillID })},
+	}
+
+	report.Fields = map[string]*graphql.Field{
+		"from":  scalar(func(s interface{}) interface{} { return s.(*domain.AdherenceReport).From }),
+		"to":    scalar(func(s interface{}) interface{} { return s.(*domain.AdherenceReport).To }),
+		"notes": scalar(func(s interface{}) interface{} { return s.(*domain.AdherenceReport).Notes }),
+		"pills": {Type: pillAdherence, List: true, Resolve: func(p *graphql.Params) (interface{}, error) {
+			return p.Source.(*domain.AdherenceReport).Pills, nil
+		}},
+		"days": {Type: dayAdherence, List: true, Resolve: func(p *graphql.Params) (interface{}, error) {
+			return p.Source.(*domain.AdherenceReport).Days, nil
+		}},
+	}
+
+	pillAdherence.Fields = map[string]*graphql.Field{
+		"name":      scalar(func(s interface{}) interface{} { return s.(*domain.PillAdherence).Name }),
+		"dosage":    scalar(func(s interface{}) interface{} { return s.(*domain.PillAdherence).Dosage }),
+		"scheduled": scalar(func(s interface{}) interface{} { return s.(*domain.PillAdherence).Scheduled }),
+		"taken":     scalar(func(s interface{}) interface{} { return s.(*domain.PillAdherence).Taken }),
+		"late":      scalar(func(s interface{}) interface{} { return s.(*domain.PillAdherence).Late }),
+		"missed":    scalar(func(s interface{}) interface{} { return s.(*domain.PillAdherence).Missed }),
+		"asNeeded":  scalar(func(s interface{}) interface{} { return s.(*domain.PillAdherence).AsNeeded }),
+		"rate":      scalar(func(s interface{}) interface{} { return s.(*domain.PillAdherence).Rate() }),
+		"pill":      {Type: pill, Batch: pillOf(func(s interface{}) int { return s.(*domain.PillAdherence).PillID })},
+	}
+
+	dayAdherence.Fields = map[string]*graphql.Field{
+		"day":       scalar(func(s interface{}) interface{} { return s.(*domain.DayAdherence).Day.Format("2006-01-02") }),
+		"scheduled": scalar(func(s interface{}) interface{} { return s.(*domain.DayAdherence).Scheduled }),
+		"taken":     scalar(func(s interface{}) interface{} { return s.(*domain.DayAdherence).Taken }),
+		"late":      scalar(func(s interface{}) interface{} { return s.(*domain.DayAdherence).Late }),
+		"missed":    scalar(func(s interface{}) interface{} { return s.(*domain.DayAdherence).Missed }),
+		"rate":      scalar(func(s interface{}) interface{} { return s.(*domain.DayAdherence).Rate() }),
+	}
+
+	return &graphql.Schema{Query: query}
+}
+
+// resolveAdherence the adherence report of a user, from and to are dates or RFC 3339 times like the report endpoint
+func (a *GraphQLAPI) resolveAdherence(p *graphql.Params) (interface{}, error) {
+	user := p.Source.(*domain.User)
+	loc := user.Location()
+	now := time.Now().In(loc)
+
+	to := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, loc)
+	if t, ok := p.Args["to"].(string); ok {
+		var err error
+		if to, err = parseReportTime(t, loc, true); err != nil {
+			return nil, errors.New("to must be a date or an RFC 3339 time")
+		}
+	}
+	from := to.AddDate(0, 0, -exportDays)
+	if f, ok := p.Args["from"].(string); ok {
+		var err error
+		if from, err = parseReportTime(f, loc, false); err != nil {
+			return nil, errors.New("from must be a date or an RFC 3339 time")
+		}
+	}
+	if !from.Before(to) {
+		return nil, errors.New("from must be before to")
+	}
+	if to.Sub(from) > maxReportDays*24*time.Hour {
+		return nil, fmt.Errorf("reports can't cover more than %d days", maxReportDays)
+	}
+
+	l := requestLoaders(p.Context)
+	values, err := l.userPills.LoadMany([]int{user.ID})
+	if err != nil {
+		return nil, err
+	}
+	pills := values[0].([]*domain.Pill)
+
+	ids := make([]int, len(pills))
+	for i, pill := range pills {
+		ids[i] = pill.ID
+	}
+	events, err := l.pillEvents.LoadMany(ids)
+	if err != nil {
+		return nil, err
+	}
+	taken := map[int][]*domain.PillEvent{}
+	for i, pill := range pills {
+		taken[pill.ID] = events[i].([]*domain.PillEvent)
+	}
+
+	return domain.NewAdherenceReport(pills, taken, from, to, now), nil
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/jwtauth"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/mock"
+)
+
+func TestGraphQL(t *testing.T) {
+	gAPI := GraphQLAPI{}
+	uSvc := mock.UserService{}
+	pSvc := mock.PillService{}
+	eSvc := mock.PillEventService{}
+	bSvc := mock.BoxService{}
+	gAPI.UserService = &uSvc
+	gAPI.PillService = &pSvc
+	gAPI.PillEventService = &eSvc
+	gAPI.BoxService = &bSvc
+
+	scheduled := time.Date(2018, time.March, 1, 8, 0, 0, 0, time.UTC)
+	pills := map[int]*domain.Pill{
+		1: {ID: 1, UserID: 1, Name: "DoxyPoxy"},
+		2: {ID: 2, UserID: 1, Name: "Advil"},
+		3: {ID: 3, UserID: 2, Name: "Someone Else's"},
+	}
+
+	userCalls, pillCalls, eventCalls := 0, 0, 0
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		userCalls++
+		return &domain.User{ID: id, FirstName: "Jacob"}, nil
+	}
+	pSvc.PillFn = func(id int) (*domain.Pill, error) {
+		pillCalls++
+		return pills[id], nil
+	}
+	pSvc.PillsFn = func(userID int) ([]*domain.Pill, error) {
+		return []*domain.Pill{pills[1], pills[2]}, nil
+	}
+	eSvc.PillEventsFn = func(pillID int) ([]*domain.PillEvent, error) {
+		eventCalls++
+		return []*domain.PillEvent{{ID: pillID, PillID: pillID, Scheduled: scheduled, Time: scheduled.Add(time.Hour)}}, nil
+	}
+	bSvc.OpenEventsFn = func(userID int) ([]*domain.OpenEvent, error) {
+		return []*domain.OpenEvent{{ID: 1, CompID: 3, UserID: userID, Time: scheduled}}, nil
+	}
+
+	tokenAuth := jwtauth.New("HS256", []byte("secret"), nil)
+	r := chi.NewRouter()
+	r.With(jwtauth.Verifier(tokenAuth)).Post("/graphql", gAPI.GraphQL)
+
+	auth := map[string]string{"Authorization": sessionToken}
+	runTests(t, r, []*test{
+		{"/graphql", "POST", `{"query":"{ me { id firstName pills { id name user { id } events { scheduled late pill { name } } } box { openEvents { compartment } } } }"}`, auth, http.StatusOK, `{"data":{"me":{"id":1,"firstName":"Jacob","pills":[{"id":1,"name":"DoxyPoxy","user":{"id":1},"events":[{"scheduled":"2018-03-01T08:00:00Z","late":true,"pill":{"name":"DoxyPoxy"}}]},{"id":2,"name":"Advil","user":{"id":1},"events":[{"scheduled":"2018-03-01T08:00:00Z","late":true,"pill":{"name":"Advil"}}]}],"box":{"openEvents":[{"compartment":3}]}}}}`},
+	})
+
+	// the user and pills of every pill and event are loaded once
+	if userCalls != 2 || pillCalls != 2 || eventCalls != 2 {
+		t.Errorf("expected the users, pills and events to be batched, got %d user, %d pill and %d event calls", userCalls, pillCalls, eventCalls)
+	}
+
+	runTests(t, r, []*test{
+		{"/graphql", "POST", `{"query":"{ me { id } }"}`, nil, http.StatusUnauthorized, `{"message":"unauthorized"}`},
+		{"/graphql", "POST", `{"query":"{ user(id: 2) { id } }"}`, auth, http.StatusOK, `{"data":{"user":null},"errors":[{"message":"unauthorized","path":["user"]}]}`},
+		{"/graphql", "POST", `{"query":"query Pill($id: Int!) { pill(id: $id) { name } }","variables":{"id":3}}`, auth, http.StatusOK, `{"data":{"pill":null},"errors":[{"message":"pill not found","path":["pill"]}]}`},
+		{"/graphql", "POST", `{"query":"query Pill($id: Int!) { mine: pill(id: $id) { ...fields } } fragment fields on Pill { name archived @skip(if: true) }","variables":{"id":1}}`, auth, http.StatusOK, `{"data":{"mine":{"name":"DoxyPoxy"}}}`},
+		{"/graphql", "POST", `{"query":"{ me { password } }"}`, auth, http.StatusBadRequest, `{"errors":[{"message":"cannot query field password on type User"}]}`},
+		{"/graphql", "POST", `{"query":"mutation { me { id } }"}`, auth, http.StatusBadRequest, `{"errors":[{"message":"only queries are supported, not mutations"}]}`},
+		{"/graphql", "POST", `{"query":"{ me { pills { user { pills { user { pills { user { pills { user { pills { id } } } } } } } } } } }"}`, auth, http.StatusBadRequest, `{"errors":[{"message":"the query is nested more than 10 levels deep"}]}`},
+		{"/graphql", "POST", `{"query":"{ me { pills { events { pill { events { pill { events { id } } } } } } } }"}`, auth, http.StatusBadRequest, `{"errors":[{"message":"the query is too complex, its complexity is more than 1000"}]}`},
+		{"/graphql", "POST", `{"query":"{ me { id "}`, auth, http.StatusBadRequest, `{"errors":[{"message":"syntax error: unexpected end of the document"}]}`},
+	})
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/log"
+	"github.com/jacsmith21/lukabox/ext/render"
+)
+
+// defaultIdempotencyMaxBody the largest body of a request with an Idempotency-Key when MaxBody isn't set
+const defaultIdempotencyMaxBody = 1 << 20
+
+// errUnscoped the error of requests whose token wouldn't get past the auth chain, they aren't made idempotent
+var errUnscoped = errors.New("the token has been revoked, expired or invalidated")
+
+// IdempotencyAPI the services used, responses are replayed for TTL after the first request. The bodies of
+// requests with an Idempotency-Key are buffered to fingerprint them so they can't be larger than MaxBody
+type IdempotencyAPI struct {
+	IdempotencyService domain.IdempotencyService
+	UserService        domain.UserService
+	SessionService     domain.SessionService
+	SessionPolicy      domain.SessionPolicy
+	TokenAuth          *jwtauth.JwtAuth
+	TTL                time.Duration
+	MaxBody            int64
+}
+
+// Idempotent replays the first response to a mutating request with an Idempotency-Key to retries of it. Keys
+// reused for a different request are rejected, as are retries while the first request is still in progress.
+// Server errors and responses rejecting the token or the rate aren't stored so the request can be retried.
+// Requests with a revoked or invalidated token are passed on as they are so the auth chain rejects them
+func (a *IdempotencyAPI) Idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" || r.Method == "GET" || r.Method == "HEAD" || r.Method == "OPTIONS" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > 255 {
+			render.WithMessage("an Idempotency-Key can't be longer than 255 characters").BadRequest(w, r)
+			return
+		}
+
+		userID, err := a.scope(r)
+		if err == errUnscoped {
+			log.WithField("key", key).Debug("not replaying for an unauthorized token")
+			next.ServeHTTP(w, r)
+			return
+		} else if err != nil {
+			render.WithError(err).InternalServerError(w, r)
+			return
+		}
+
+		maxBody := a.MaxBody
+		if maxBody <= 0 {
+			maxBody = defaultIdempotencyMaxBody
+		}
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+		if _, ok := err.(*http.MaxBytesError); ok {
+			render.WithMessage(fmt.Sprintf("the body of a request with an Idempotency-Key can't be larger than %d bytes", maxBody)).RequestEntityTooLarge(w, r)
+			return
+		} else if err != nil {
+			render.WithError(err).BadRequest(w, r)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		h := sha256.New()
+		fmt.Fprintf(h, "%s %s\n", r.Method, r.URL.Path)
+		h.Write(body)
+		record := &domain.IdempotencyRecord{
+			UserID:      userID,
+			Key:         key,
+			Fingerprint: hex.EncodeToString(h.Sum(nil)),
+			Expires:     time.Now().Add(a.TTL),
+		}
+
+		existing, err := a.IdempotencyService.Begin(record)
+		if err != nil {
+			log.WithError(err).Error("unable to begin idempotent request")
+			render.WithError(err).InternalServerError(w, r)
+			return
+		}
+		if existing != nil {
+			if existing.Fingerprint != record.Fingerprint {
+				render.WithMessage("the Idempotency-Key has already been used for a different request").UnprocessableEntity(w, r)
+				return
+			}
+			if !existing.Completed() {
+				render.WithMessage("a request with the Idempotency-Key is in progress").Conflict(w, r)
+				return
+			}
+			replay(w, existing)
+			return
+		}
+
+		// the key is released when the response isn't stored, including when the handler panics
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := a.IdempotencyService.Release(record.UserID, record.Key); err != nil {
+				log.WithError(err).Error("unable to release idempotency key")
+			}
+		}()
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		if rec.status >= 500 || rec.status == http.StatusUnauthorized || rec.status == http.StatusTooManyRequests {
+			return
+		}
+
+		header := map[string][]string{}
+		for k, v := range w.Header() {
+			header[k] = append([]string{}, v...)
+		}
+		response := &domain.IdempotencyRecord{
+			UserID:      record.UserID,
+			Key:         record.Key,
+			Fingerprint: record.Fingerprint,
+			Expires:     record.Expires,
+			Status:      rec.status,
+			Header:      header,
+			Body:        rec.body.Bytes(),
+		}
+		if err := a.IdempotencyService.Complete(response); err != nil {
+			log.WithError(err).Error("unable to store idempotent response")
+			return
+		}
+		completed = true
+	})
+}
+
+// scope the user the keys of a request belong to, 0 without a token. A token is checked like the auth chain
+// checks it, errUnscoped is returned when its session has been revoked or has expired or the token has been
+// invalidated so it can't replay the responses of its user
+func (a *IdempotencyAPI) scope(r *http.Request) (int, error) {
+	token, err := jwtauth.VerifyRequest(a.TokenAuth, r, jwtauth.TokenFromQuery, jwtauth.TokenFromHeader, jwtauth.TokenFromCookie)
+	if token == nil && err == jwtauth.ErrUnauthorized {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errUnscoped
+	}
+	_, claims, _ := jwtauth.FromContext(jwtauth.NewContext(r.Context(), token, nil))
+
+	jti, _ := claims["jti"].(string)
+	session, err := a.SessionService.Session(jti)
+	if err != nil {
+		return 0, err
+	}
+	if session == nil || a.SessionPolicy.Expired(session, time.Now()) {
+		return 0, errUnscoped
+	}
+
+	id, _ := claims["id"].(float64)
+	user, err := a.UserService.UserByID(int(id))
+	if err != nil {
+		return 0, err
+	}
+	if user == nil || authorize(r.Context(), claims, user) != nil {
+		return 0, errUnscoped
+	}
+	return user.ID, nil
+}
+
+// replay writes a stored response, marked with an Idempotent-Replayed header
+func replay(w http.ResponseWriter, record *domain.IdempotencyRecord) {
+	for k, v := range record.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.Status)
+	w.Write(record.Body)
+}
+
+// responseRecorder records the status and body of a response as it is written
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	wrote  bool
+	body   bytes.Buffer
+}
+
+// WriteHeader records the status
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wrote {
+		rec.status = status
+		rec.wrote = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the body
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.wrote = true
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
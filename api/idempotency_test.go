@@ -0,0 +1,107 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/jwtauth"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/db"
+	"github.com/jacsmith21/lukabox/mock"
+)
+
+func TestIdempotent(t *testing.T) {
+	tokenAuth := jwtauth.New("HS256", []byte("secret"), nil)
+	iAPI := IdempotencyAPI{IdempotencyService: &db.IdempotencyService{}, TokenAuth: tokenAuth, TTL: time.Hour}
+	_, otherToken, _ := tokenAuth.Encode(jwtauth.Claims{"id": 2, "jti": "other", "ver": 0})
+	_, revokedToken, _ := tokenAuth.Encode(jwtauth.Claims{"id": 1, "jti": "revoked", "ver": 0})
+	_, invalidatedToken, _ := tokenAuth.Encode(jwtauth.Claims{"id": 1, "jti": "session", "ver": 1})
+
+	uSvc := mock.UserService{}
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id, Email: "jacob.smith@unb.ca", Password: "password", FirstName: "Jacob", LastName: "Smith"}, nil
+	}
+	iAPI.UserService = &uSvc
+
+	sSvc := mock.SessionService{}
+	sSvc.SessionFn = func(id string) (*domain.Session, error) {
+		if id == "revoked" {
+			return nil, nil
+		}
+		return &domain.Session{ID: id}, nil
+	}
+	iAPI.SessionService = &sSvc
+
+	created := 0
+	failures := 0
+	r := chi.NewRouter()
+	r.Use(iAPI.Idempotent)
+	r.Post("/pills", func(w http.ResponseWriter, r *http.Request) {
+		created++
+		w.Header().Set("Location", fmt.Sprintf("/pills/%d", created))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"id":%d}`, created)
+	})
+	r.Put("/flaky", func(w http.ResponseWriter, r *http.Request) {
+		failures++
+		if failures == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("done"))
+	})
+
+	tests := []*test{
+		{"/pills", "POST", `{"name":"Advil"}`, map[string]string{"Idempotency-Key": "a"}, http.StatusCreated, `{"id":1}`},
+		{"/pills", "POST", `{"name":"Advil"}`, map[string]string{"Idempotency-Key": "a"}, http.StatusCreated, `{"id":1}`},
+		{"/pills", "POST", `{"name":"Tylenol"}`, map[string]string{"Idempotency-Key": "a"}, http.StatusUnprocessableEntity, `{"message":"the Idempotency-Key has already been used for a different request"}`},
+		{"/pills", "POST", `{"name":"Advil"}`, map[string]string{"Idempotency-Key": "a", "Authorization": sessionToken}, http.StatusCreated, `{"id":2}`},
+		{"/pills", "POST", `{"name":"Advil"}`, map[string]string{"Idempotency-Key": "a", "Authorization": "BEARER " + otherToken}, http.StatusCreated, `{"id":3}`},
+		{"/pills", "POST", `{"name":"Advil"}`, map[string]string{"Idempotency-Key": "a", "Authorization": sessionToken}, http.StatusCreated, `{"id":2}`},
+		{"/pills", "POST", `{"name":"Advil"}`, nil, http.StatusCreated, `{"id":4}`},
+		{"/flaky", "PUT", "", map[string]string{"Idempotency-Key": "b"}, http.StatusServiceUnavailable, ""},
+		{"/flaky", "PUT", "", map[string]string{"Idempotency-Key": "b"}, http.StatusOK, "done"},
+		{"/flaky", "PUT", "", map[string]string{"Idempotency-Key": "b"}, http.StatusOK, "done"},
+	}
+	runTests(t, r, tests)
+	if failures != 2 {
+		t.Errorf("expected the failed request to be retried once, it was handled %d times", failures)
+	}
+
+	req := httptest.NewRequest("POST", "/pills", strings.NewReader(`{"name":"Advil"}`))
+	req.Header.Set("Idempotency-Key", "a")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Header().Get("Idempotent-Replayed") != "true" || w.Header().Get("Location") != "/pills/1" {
+		t.Errorf("expected the replayed response to keep its headers, got %v", w.Header())
+	}
+
+	tests = []*test{
+		{"/pills", "POST", "", map[string]string{"Idempotency-Key": "c"}, http.StatusCreated, `{"id":5}`},
+		{"/pills", "POST", "", map[string]string{"Idempotency-Key": "c"}, http.StatusCreated, `{"id":6}`},
+	}
+	iAPI.TTL = 0
+	runTests(t, r, tests)
+
+	tests = []*test{
+		{"/pills", "POST", `{"name":"Advil"}`, map[string]string{"Idempotency-Key": "d"}, http.StatusRequestEntityTooLarge, `{"message":"the body of a request with an Idempotency-Key can't be larger than 8 bytes"}`},
+		{"/pills", "POST", `{}`, map[string]string{"Idempotency-Key": "d"}, http.StatusCreated, `{"id":7}`},
+	}
+	iAPI.MaxBody = 8
+	runTests(t, r, tests)
+
+	tests = []*test{
+		{"/pills", "POST", "", map[string]string{"Idempotency-Key": "e", "Authorization": sessionToken}, http.StatusCreated, `{"id":8}`},
+		{"/pills", "POST", "", map[string]string{"Idempotency-Key": "e", "Authorization": "BEARER " + revokedToken}, http.StatusCreated, `{"id":9}`},
+		{"/pills", "POST", "", map[string]string{"Idempotency-Key": "e", "Authorization": "BEARER " + invalidatedToken}, http.StatusCreated, `{"id":10}`},
+		{"/pills", "POST", "", map[string]string{"Idempotency-Key": "e", "Authorization": sessionToken}, http.StatusCreated, `{"id":8}`},
+	}
+	iAPI.TTL = time.Hour
+	iAPI.MaxBody = 0
+	runTests(t, r, tests)
+}
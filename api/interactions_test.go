@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/interactions"
+	"github.com/jacsmith21/lukabox/mock"
+)
+
+const interactionDataset = `drug,other,severity,description
+warfarin,aspirin,major,Raises the risk of bleeding.
+warfarin,ibuprofen,major,Raises the risk of bleeding.
+lisinopril,ibuprofen,moderate,Can reduce the effect of lisinopril.
+levothyroxine,calcium carbonate,moderate,Take them at least 4 hours apart.
+`
+
+func TestInteractions(t *testing.T) {
+	uAPI := UserAPI{}
+	uSvc := mock.UserService{}
+	uAPI.UserService = &uSvc
+	pAPI := PillAPI{}
+	pSvc := mock.PillService{}
+	pAPI.PillService = &pSvc
+
+	dataset := interactions.Dataset{}
+	if err := dataset.Import(strings.NewReader(interactionDataset)); err != nil {
+		t.Fatal(err)
+	}
+	pAPI.InteractionService = &dataset
+
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id}, nil
+	}
+	regimen := []*domain.Pill{
+		{ID: 1, UserID: 1, Name: "Aspirin 81 mg"},
+		{ID: 2, UserID: 1, Name: "Ibuprofen", Archived: true},
+		{ID: 3, UserID: 1, Name: "Lisinopril"},
+	}
+	pSvc.PillsFn = func(id int) ([]*domain.Pill, error) {
+		return regimen, nil
+	}
+	created := 0
+	pSvc.CreatePillFn = func(pill *domain.Pill) error {
+		created++
+		pill.ID = 4
+		return nil
+	}
+
+	r := chi.NewRouter()
+	r.Route("/users/{userId}", func(r chi.Router) {
+		r.Use(uAPI.UserCtx)
+		r.Post("/pills", pAPI.CreatePill)
+		r.Get("/pills/interactions", pAPI.Interactions)
+	})
+
+	tests := []*test{
+		{"/users/1/pills", "POST", `{"name":"Warfarin"}`, map[string]string{"Content-Type": "application/json"}, http.StatusConflict, `{"message":"the pill interacts with the regimen, acknowledge the interactions to proceed","interactions":[{"pillId":0,"pill":"Warfarin","otherPillId":1,"other":"Aspirin 81 mg","severity":"major","description":"Raises the risk of bleeding."}]}`},
+		{"/users/1/pills", "POST", `{"name":"Warfarin","acknowledgeInteractions":true}`, map[string]string{"Content-Type": "application/json"}, http.StatusCreated, `{"pillId":4,"id":1,"name":"Warfarin","medicationId":"","generic":"","daysOfWeek":null,"timesOfDay":null,"schedule":null,"archived":false,"strength":0,"unit":"","form":"","route":"","food":"","instructions":"","prescriber":"","startDate":null,"endDate":null,"quantity":0,"quantityPerDose":0,"alertDays":0,"interactions":[{"pillId":0,"pill":"Warfarin","otherPillId":1,"other":"Aspirin 81 mg","severity":"major","description":"Raises the risk of bleeding."}]}`},
+		{"/users/1/pills", "POST", `{"name":"Calcium"}`, map[string]string{"Content-Type": "application/json"}, http.StatusCreated, `{"pillId":4,"id":1,"name":"Calcium","medicationId":"","generic":"","daysOfWeek":null,"timesOfDay":null,"schedule":null,"archived":false,"strength":0,"unit":"","form":"","route":"","food":"","instructions":"","prescriber":"","startDate":null,"endDate":null,"quantity":0,"quantityPerDose":0,"alertDays":0}`},
+	}
+	runTests(t, r, tests)
+
+	if created != 2 {
+		t.Errorf("expected the pill to be created once acknowledged and the pill without interactions, got %d", created)
+	}
+
+	// the regimen is listed most severe first, ibuprofen is back in the regimen
+	regimen[1].Archived = false
+	regimen = append(regimen, &domain.Pill{ID: 4, UserID: 1, Name: "Warfarin 5 mg"})
+	tests = []*test{
+		{"/users/1/pills/interactions", "GET", "", nil, http.StatusOK, `[{"pillId":1,"pill":"Aspirin 81 mg","otherPillId":4,"other":"Warfarin 5 mg","severity":"major","description":"Raises the risk of bleeding."},{"pillId":2,"pill":"Ibuprofen","otherPillId":4,"other":"Warfarin 5 mg","severity":"major","description":"Raises the risk of bleeding."},{"pillId":2,"pill":"Ibuprofen","otherPillId":3,"other":"Lisinopril","severity":"moderate","description":"Can reduce the effect of lisinopril."}]`},
+	}
+	runTests(t, r, tests)
+
+	for _, dataset := range []string{"drug,other\nwarfarin,aspirin", "drug,other,severity,description\nwarfarin,aspirin,bad,Bleeding", "drug,other,severity,description\nwarfarin,,major,Bleeding"} {
+		if err := (&interactions.Dataset{}).Import(strings.NewReader(dataset)); err == nil {
+			t.Errorf("expected %q to be rejected", dataset)
+		}
+	}
+}
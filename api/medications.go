@@ -0,0 +1,66 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/log"
+	"github.com/jacsmith21/lukabox/ext/render"
+	"github.com/jacsmith21/lukabox/stc"
+)
+
+// maxMedications the most medications a search returns
+const maxMedications = 50
+
+// MedicationAPI the services used
+type MedicationAPI struct {
+	MedicationService domain.MedicationService
+}
+
+// Medications searches the catalogue by name for ?q=, ?limit= defaults to 10
+func (a *MedicationAPI) Medications(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "Medications").Info("starting")
+
+	limit := 10
+	if l := r.URL.Query().Get("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil || limit < 1 || limit > maxMedications {
+			render.WithMessage(fmt.Sprintf("limit must be between 1 and %d", maxMedications)).BadRequest(w, r)
+			return
+		}
+	}
+
+	medications, err := a.MedicationService.SearchMedications(r.URL.Query().Get("q"), limit)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	if err := render.List(w, r, stc.NewMedicationListResponse(medications)); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
+
+// Medication returns a medication from the catalogue
+func (a *MedicationAPI) Medication(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "Medication").Info("starting")
+
+	medication, err := a.MedicationService.Medication(chi.URLParam(r, "medicationId"))
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+	if medication == nil {
+		render.WithMessage("medication not found").NotFound(w, r)
+		return
+	}
+
+	if err := render.Instance(w, r, stc.NewMedicationResponse(medication)); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/catalogue"
+	"github.com/jacsmith21/lukabox/ext/interactions"
+	"github.com/jacsmith21/lukabox/mock"
+)
+
+const medicationCatalogue = `id,name,generic,strengths,forms,rxnorm,din
+ibuprofen,Ibuprofen,ibuprofen,200 mg;400 mg,tablet;capsule,5640,
+advil,Advil,ibuprofen,200 mg,tablet,5640,
+warfarin,Warfarin,warfarin,1 mg;5 mg,tablet,11289,
+`
+
+func TestMedications(t *testing.T) {
+	mAPI := MedicationAPI{}
+	c := catalogue.Catalogue{}
+	if err := c.Import(strings.NewReader(medicationCatalogue)); err != nil {
+		t.Fatal(err)
+	}
+	mAPI.MedicationService = &c
+
+	advil := `{"id":"advil","name":"Advil","genericName":"ibuprofen","strengths":["200 mg"],"forms":["tablet"],"codes":{"rxnorm":"5640"}}`
+	ibuprofen := `{"id":"ibuprofen","name":"Ibuprofen","genericName":"ibuprofen","strengths":["200 mg","400 mg"],"forms":["tablet","capsule"],"codes":{"rxnorm":"5640"}}`
+	warfarin := `{"id":"warfarin","name":"Warfarin","genericName":"warfarin","strengths":["1 mg","5 mg"],"forms":["tablet"],"codes":{"rxnorm":"11289"}}`
+
+	tests := []*test{
+		{"/medications?q=adv", "GET", "", nil, http.StatusOK, "[" + advil + "]"},
+		{"/medications?q=IBU", "GET", "", nil, http.StatusOK, "[" + ibuprofen + "," + advil + "]"},
+		{"/medications?q=ibu&limit=1", "GET", "", nil, http.StatusOK, "[" + ibuprofen + "]"},
+		{"/medications?q=wafarin", "GET", "", nil, http.StatusOK, "[" + warfarin + "]"},
+		{"/medications?q=ibuprofn", "GET", "", nil, http.StatusOK, "[" + advil + "," + ibuprofen + "]"},
+		{"/medications?q=war", "GET", "", nil, http.StatusOK, "[" + warfarin + "]"},
+		{"/medications?q=xyz", "GET", "", nil, http.StatusOK, "[]"},
+		{"/medications?q=ibu&limit=100", "GET", "", nil, http.StatusBadRequest, `{"message":"limit must be between 1 and 50"}`},
+		{"/medications/advil", "GET", "", nil, http.StatusOK, advil},
+		{"/medications/tylenol", "GET", "", nil, http.StatusNotFound, `{"message":"medication not found"}`},
+	}
+
+	r := chi.NewRouter()
+	r.Get("/medications", mAPI.Medications)
+	r.Get("/medications/{medicationId}", mAPI.Medication)
+
+	runTests(t, r, tests)
+
+	for _, list := range []string{"id,name\nadvil,Advil", "id,name,generic,strengths,forms,rxnorm,din\n,Advil,ibuprofen,,,,", "id,name,generic,strengths,forms,rxnorm,din\nadvil,Advil,,,,,\nadvil,Advil,,,,,"} {
+		if err := (&catalogue.Catalogue{}).Import(strings.NewReader(list)); err == nil {
+			t.Errorf("expected %q to be rejected", list)
+		}
+	}
+}
+
+func TestCatalogueReference(t *testing.T) {
+	uAPI := UserAPI{}
+	uSvc := mock.UserService{}
+	uAPI.UserService = &uSvc
+	pAPI := PillAPI{}
+	pSvc := mock.PillService{}
+	pAPI.PillService = &pSvc
+
+	c := catalogue.Catalogue{}
+	if err := c.Import(strings.NewReader(medicationCatalogue)); err != nil {
+		t.Fatal(err)
+	}
+	pAPI.MedicationService = &c
+	dataset := interactions.Dataset{}
+	if err := dataset.Import(strings.NewReader(interactionDataset)); err != nil {
+		t.Fatal(err)
+	}
+	pAPI.InteractionService = &dataset
+
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id}, nil
+	}
+	pSvc.PillsFn = func(id int) ([]*domain.Pill, error) {
+		return []*domain.Pill{{ID: 1, UserID: 1, Name: "Blood thinner", MedicationID: "warfarin", Generic: "warfarin"}}, nil
+	}
+	pSvc.CreatePillFn = func(pill *domain.Pill) error {
+		pill.ID = 2
+		return nil
+	}
+
+	// the catalogue's generic name catches the interaction between the brand name and the renamed pill
+	tests := []*test{
+		{"/users/1/pills", "POST", `{"name":"Advil","medicationId":"advil"}`, map[string]string{"Content-Type": "application/json"}, http.StatusConflict, `{"message":"the pill interacts with the regimen, acknowledge the interactions to proceed","interactions":[{"pillId":0,"pill":"Advil","otherPillId":1,"other":"Blood thinner","severity":"major","description":"Raises the risk of bleeding."}]}`},
+		{"/users/1/pills", "POST", `{"name":"Advil","medicationId":"motrin"}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"medication motrin isn't in the catalogue"}`},
+		{"/users/1/pills", "POST", `{"name":"Vitamin D","generic":"cholecalciferol"}`, map[string]string{"Content-Type": "application/json"}, http.StatusCreated, `{"pillId":2,"id":1,"name":"Vitamin D","medicationId":"","generic":"cholecalciferol","daysOfWeek":null,"timesOfDay":null,"schedule":null,"archived":false,"strength":0,"unit":"","form":"","route":"","food":"","instructions":"","prescriber":"","startDate":null,"endDate":null,"quantity":0,"quantityPerDose":0,"alertDays":0}`},
+	}
+
+	r := chi.NewRouter()
+	r.Route("/users/{userId}", func(r chi.Router) {
+		r.Use(uAPI.UserCtx)
+		r.Post("/pills", pAPI.CreatePill)
+	})
+
+	runTests(t, r, tests)
+}
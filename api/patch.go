@@ -0,0 +1,114 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"github.com/jacsmith21/lukabox/ext/patch"
+	"github.com/jacsmith21/lukabox/ext/render"
+)
+
+// userFields the members of a user which can be patched, the password and role have their own routes
+var userFields = []string{"firstName", "lastName", "email", "timezone", "travelTimezone", "travelMode"}
+
+// pillFields the members of a pill which can be patched, the stock is changed through adjustments and
+// acknowledgeInteractions can be added to acknowledge the interactions of the patched pill
+var pillFields = []string{
+	"name", "medicationId", "daysOfWeek", "timesOfDay", "schedule", "archived", "strength", "unit", "form",
+	"route", "food", "instructions", "prescriber", "startDate", "endDate", "quantityPerDose", "alertDays",
+	"acknowledgeInteractions",
+}
+
+// patchDocument applies the merge patch or JSON patch in the body of the request to the representation of a
+// resource. Patches touching the immutable members or members which aren't mutable are rejected, renders the
+// error and returns false when the patch can't be applied
+func patchDocument(w http.ResponseWriter, r *http.Request, representation interface{}, mutable []string, immutable []string) ([]byte, bool) {
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != patch.MergePatchType && contentType != patch.JSONPatchType {
+		err := fmt.Errorf("patches must be %s or %s", patch.MergePatchType, patch.JSONPatchType)
+		render.WithError(err).UnsupportedMediaType(w, r)
+		return nil, false
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		render.WithError(err).BadRequest(w, r)
+		return nil, false
+	}
+	doc, err := json.Marshal(representation)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return nil, false
+	}
+
+	fields := []string{}
+	var ops []*patch.Operation
+	if contentType == patch.MergePatchType {
+		if fields, err = patch.MergeFields(body); err != nil {
+			render.WithError(err).BadRequest(w, r)
+			return nil, false
+		}
+	} else {
+		if ops, err = patch.Operations(body); err != nil {
+			render.WithError(err).BadRequest(w, r)
+			return nil, false
+		}
+		for _, o := range ops {
+			// tests only read the document so they can check any member
+			if o.Op != "test" {
+				fields = append(fields, o.Fields()...)
+			}
+		}
+	}
+
+	for _, f := range fields {
+		if f == "" {
+			render.WithMessage("the whole document can't be replaced").UnprocessableEntity(w, r)
+			return nil, false
+		}
+		if contains(immutable, f) {
+			render.WithMessage(fmt.Sprintf("%s is immutable", f)).UnprocessableEntity(w, r)
+			return nil, false
+		}
+		if !contains(mutable, f) {
+			render.WithMessage(fmt.Sprintf("%s can't be patched", f)).UnprocessableEntity(w, r)
+			return nil, false
+		}
+	}
+
+	if contentType == patch.MergePatchType {
+		doc, err = patch.Merge(doc, body)
+	} else {
+		doc, err = patch.Apply(doc, ops)
+	}
+	if err != nil {
+		render.WithError(err).Conflict(w, r)
+		return nil, false
+	}
+	return doc, true
+}
+
+// patchedRequest a copy of the request with the patched document as its JSON body so it can be bound and
+// validated like a full update
+func patchedRequest(r *http.Request, doc []byte) *http.Request {
+	patched := r.WithContext(r.Context())
+	patched.Header = r.Header.Clone()
+	patched.Header.Set("Content-Type", "application/json")
+	patched.Body = ioutil.NopCloser(bytes.NewReader(doc))
+	patched.ContentLength = int64(len(doc))
+	return patched
+}
+
+// contains whether the list contains the string
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/mock"
+)
+
+func TestPatchPill(t *testing.T) {
+	uAPI := UserAPI{}
+	uSvc := mock.UserService{}
+	uAPI.UserService = &uSvc
+	pAPI := PillAPI{}
+	pSvc := mock.PillService{}
+	pAPI.PillService = &pSvc
+	iSvc := mock.InteractionService{}
+	pAPI.InteractionService = &iSvc
+
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id}, nil
+	}
+	pSvc.PillFn = func(id int) (*domain.Pill, error) {
+		return &domain.Pill{ID: 1, UserID: 1, Name: "DoxyPoxy", DaysOfWeek: []int{1, 3}, Version: 2}, nil
+	}
+	pSvc.PillsFn = func(id int) ([]*domain.Pill, error) {
+		return []*domain.Pill{}, nil
+	}
+	iSvc.InteractionsFn = func(pill string, other string) ([]*domain.Interaction, error) {
+		return []*domain.Interaction{}, nil
+	}
+	pSvc.UpdatePillFn = func(id int, pill *domain.Pill) error {
+		pill.Version++
+		return nil
+	}
+
+	routes := func(r chi.Router) {
+		r.Route("/users/{userId}/pills/{pillId}", func(r chi.Router) {
+			r.Use(uAPI.UserCtx)
+			r.Use(pAPI.PillCtx)
+			r.Patch("/", pAPI.PatchPill)
+		})
+	}
+	r := chi.NewRouter()
+	r.Group(routes)
+	r.Route("/v2", func(r chi.Router) {
+		r.Use(Versioned(V2))
+		routes(r)
+	})
+
+	merge := map[string]string{"Content-Type": "application/merge-patch+json"}
+	jsonPatch := map[string]string{"Content-Type": "application/json-patch+json"}
+	tests := []*test{
+		{"/users/1/pills/1", "PATCH", `{"name":"Advil","instructions":"with water"}`, merge, http.StatusOK, `{"pillId":1,"id":1,"name":"Advil","medicationId":"","generic":"","daysOfWeek":[1,3],"timesOfDay":null,"schedule":null,"archived":false,"strength":0,"unit":"","form":"","route":"","food":"","instructions":"with water","prescriber":"","startDate":null,"endDate":null,"quantity":0,"quantityPerDose":0,"alertDays":0}`},
+		{"/users/1/pills/1", "PATCH", `[{"op":"test","path":"/name","value":"DoxyPoxy"},{"op":"remove","path":"/daysOfWeek/0"},{"op":"add","path":"/daysOfWeek/-","value":5}]`, jsonPatch, http.StatusOK, `{"pillId":1,"id":1,"name":"DoxyPoxy","medicationId":"","generic":"","daysOfWeek":[3,5],"timesOfDay":null,"schedule":null,"archived":false,"strength":0,"unit":"","form":"","route":"","food":"","instructions":"","prescriber":"","startDate":null,"endDate":null,"quantity":0,"quantityPerDose":0,"alertDays":0}`},
+		{"/v2/users/1/pills/1", "PATCH", `{"archived":true}`, merge, http.StatusOK, `{"id":1,"userId":1,"name":"DoxyPoxy","medicationId":"","generic":"","daysOfWeek":[1,3],"timesOfDay":null,"schedule":null,"archived":true,"strength":0,"unit":"","form":"","route":"","food":"","instructions":"","prescriber":"","startDate":null,"endDate":null,"quantity":0,"quantityPerDose":0,"alertDays":0}`},
+		{"/users/1/pills/1", "PATCH", `{"pillId":2}`, merge, http.StatusUnprocessableEntity, `{"message":"pillId is immutable"}`},
+		{"/v2/users/1/pills/1", "PATCH", `[{"op":"replace","path":"/userId","value":2}]`, jsonPatch, http.StatusUnprocessableEntity, `{"message":"userId is immutable"}`},
+		{"/users/1/pills/1", "PATCH", `[{"op":"move","from":"/id","path":"/instructions"}]`, jsonPatch, http.StatusUnprocessableEntity, `{"message":"id is immutable"}`},
+		{"/users/1/pills/1", "PATCH", `{"quantity":100}`, merge, http.StatusUnprocessableEntity, `{"message":"quantity can't be patched"}`},
+		{"/users/1/pills/1", "PATCH", `[{"op":"replace","path":"","value":{}}]`, jsonPatch, http.StatusUnprocessableEntity, `{"message":"the whole document can't be replaced"}`},
+		{"/users/1/pills/1", "PATCH", `{"name":null}`, merge, http.StatusBadRequest, `{"message":"name is required","fields":{"name":"name is required"}}`},
+		{"/users/1/pills/1", "PATCH", `{"daysOfWeek":[8]}`, merge, http.StatusBadRequest, `{"message":"daysOfWeek[0] must be a day of the week from 1 for Monday to 7 for Sunday","fields":{"daysOfWeek[0]":"daysOfWeek[0] must be a day of the week from 1 for Monday to 7 for Sunday"}}`},
+		{"/users/1/pills/1", "PATCH", `[{"op":"test","path":"/name","value":"Advil"}]`, jsonPatch, http.StatusConflict, `{"message":"/name: test failed"}`},
+		{"/users/1/pills/1", "PATCH", `[{"op":"remove","path":"/daysOfWeek/5"}]`, jsonPatch, http.StatusConflict, `{"message":"5 is out of range"}`},
+		{"/users/1/pills/1", "PATCH", `[{"op":"rename","path":"/name"}]`, jsonPatch, http.StatusBadRequest, `{"message":"unknown operation \"rename\""}`},
+		{"/users/1/pills/1", "PATCH", `["name"]`, merge, http.StatusBadRequest, `{"message":"a merge patch must be an object"}`},
+		{"/users/1/pills/1", "PATCH", `{"name":"Advil"}`, map[string]string{"Content-Type": "application/json"}, http.StatusUnsupportedMediaType, `{"message":"patches must be application/merge-patch+json or application/json-patch+json"}`},
+		{"/users/1/pills/1", "PATCH", `{"name":"Advil"}`, map[string]string{"Content-Type": "application/merge-patch+json", "If-Match": `"1"`}, http.StatusPreconditionFailed, `{"message":"the pill has been updated since it was read"}`},
+		{"/users/2/pills/1", "PATCH", `{"name":"Advil"}`, merge, http.StatusNotFound, `{"message":"pill not found"}`},
+	}
+	runTests(t, r, tests)
+}
+
+func TestPatchUser(t *testing.T) {
+	uAPI := UserAPI{}
+	uSvc := mock.UserService{}
+	uAPI.UserService = &uSvc
+
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id, Email: "jacob.smith@unb.ca", Password: "password", FirstName: "Jacob", LastName: "Smith", Verified: true, Role: domain.PatientRole, Version: 1}, nil
+	}
+	uSvc.UserByEmailFn = func(email string) (*domain.User, error) {
+		if email == "taken@unb.ca" {
+			return &domain.User{ID: 2, Email: email}, nil
+		}
+		return nil, errors.New("user not found")
+	}
+	uSvc.UpdateUserFn = func(id int, user *domain.User) error {
+		user.Version++
+		return nil
+	}
+
+	tSvc := mock.OneTimeTokenService{}
+	tSvc.IssueFn = func(userID int, purpose domain.TokenPurpose, ttl time.Duration) (*domain.OneTimeToken, error) {
+		return &domain.OneTimeToken{Token: "verify", UserID: userID, Purpose: purpose}, nil
+	}
+	uAPI.OneTimeTokenService = &tSvc
+
+	sent := []*domain.Email{}
+	mailer := mock.Mailer{}
+	mailer.SendFn = func(email *domain.Email) error {
+		sent = append(sent, email)
+		return nil
+	}
+	uAPI.Mailer = &mailer
+
+	r := chi.NewRouter()
+	r.Route("/users/{userId}", func(r chi.Router) {
+		r.Use(uAPI.UserCtx)
+		r.Patch("/", uAPI.PatchUser)
+	})
+
+	merge := map[string]string{"Content-Type": "application/merge-patch+json"}
+	jsonPatch := map[string]string{"Content-Type": "application/json-patch+json"}
+	tests := []*test{
+		{"/users/1", "PATCH", `{"firstName":"Jake","timezone":"America/Halifax"}`, merge, http.StatusOK, `{"id":1,"password":"password","email":"jacob.smith@unb.ca","firstName":"Jake","lastName":"Smith","archived":false,"verified":true,"role":"patient","timezone":"America/Halifax","travelTimezone":"","travelMode":""}`},
+		{"/users/1", "PATCH", `[{"op":"copy","from":"/firstName","path":"/lastName"}]`, jsonPatch, http.StatusOK, `{"id":1,"password":"password","email":"jacob.smith@unb.ca","firstName":"Jacob","lastName":"Jacob","archived":false,"verified":true,"role":"patient","timezone":"","travelTimezone":"","travelMode":""}`},
+		{"/users/1", "PATCH", `{"id":2}`, merge, http.StatusUnprocessableEntity, `{"message":"id is immutable"}`},
+		{"/users/1", "PATCH", `{"role":"admin"}`, merge, http.StatusUnprocessableEntity, `{"message":"role can't be patched"}`},
+		{"/users/1", "PATCH", `[{"op":"replace","path":"/password","value":"guess"}]`, jsonPatch, http.StatusUnprocessableEntity, `{"message":"password can't be patched"}`},
+		{"/users/1", "PATCH", `{"timezone":"Mars/Olympus"}`, merge, http.StatusBadRequest, `{"message":"unknown timezone Mars/Olympus"}`},
+		{"/users/1", "PATCH", `{"travelMode":"teleport"}`, merge, http.StatusBadRequest, `{"message":"travelMode must be one of home, local","fields":{"travelMode":"travelMode must be one of home, local"}}`},
+		{"/users/1", "PATCH", `[{"op":"remove","path":"/email"}]`, jsonPatch, http.StatusBadRequest, `{"message":"email is required","fields":{"email":"email is required"}}`},
+		{"/users/1", "PATCH", `{"email":"jake.smith@unb.ca"}`, merge, http.StatusOK, `{"id":1,"password":"password","email":"jake.smith@unb.ca","firstName":"Jacob","lastName":"Smith","archived":false,"verified":false,"role":"patient","timezone":"","travelTimezone":"","travelMode":""}`},
+		{"/users/1", "PATCH", `{"email":"taken@unb.ca"}`, merge, http.StatusConflict, `{"message":"the email is already in use"}`},
+	}
+	runTests(t, r, tests)
+
+	if len(sent) != 1 || sent[0].To != "jake.smith@unb.ca" {
+		t.Errorf("expected a single verification email to the new email, got %v", sent)
+	}
+}
@@ -3,8 +3,10 @@ package api
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/jacsmith21/lukabox/domain"
@@ -15,7 +17,12 @@ import (
 
 //PillAPI the services used
 type PillAPI struct {
-	PillService domain.PillService
+	PillService        domain.PillService
+	StockService       domain.StockService
+	InteractionService domain.InteractionService
+	MedicationService  domain.MedicationService
+	Refills            *RefillAPI
+	Webhooks           *WebhookAPI
 }
 
 // PillCtx is used to create a user context by id
@@ -63,13 +70,80 @@ func (a *PillAPI) Pills(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := render.List(w, r, stc.NewPillListResponse(pills)); err != nil {
+	if notModified(w, r, pillsETag(pills)) {
+		return
+	}
+
+	if err := renderPills(w, r, pills); err != nil {
+		render.WithError(err).BadRequest(w, r)
+		return
+	}
+}
+
+// Pill returns a pill, If-None-Match is answered with a 304 when the pill hasn't changed
+func (a *PillAPI) Pill(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "Pill").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+	pill := r.Context().Value("pill").(*domain.Pill)
+
+	if pill.UserID != user.ID {
+		render.WithMessage("pill not found").NotFound(w, r)
+		return
+	}
+
+	if notModified(w, r, etag(pill.Version)) {
+		return
+	}
+
+	if err := renderPill(w, r, pill, nil); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
+
+// CreatePill creates a pill for the user, pills which interact with the regimen must acknowledge the interactions
+func (a *PillAPI) CreatePill(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "CreatePill").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+
+	data, err := bindPill(r)
+	if err != nil {
 		render.WithError(err).BadRequest(w, r)
 		return
 	}
+
+	p := data.Pill
+	p.ID = 0
+	p.UserID = user.ID
+	p.Quantity = 0
+
+	if !a.linkMedication(w, r, p) {
+		return
+	}
+
+	warnings, ok := a.checkInteractions(w, r, p, data.AcknowledgeInteractions)
+	if !ok {
+		return
+	}
+
+	if err := a.PillService.CreatePill(p); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	if err := a.Webhooks.Publish(p.UserID, domain.PillChangedEvent, p, time.Now()); err != nil {
+		log.WithError(err).Error("unable to publish pill changed event")
+	}
+
+	w.Header().Set("ETag", etag(p.Version))
+	render.Status(r, http.StatusCreated)
+	if err := renderPill(w, r, p, warnings); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
 }
 
-// UpdatePill updates a pill
+// UpdatePill updates a pill, pills which interact with the regimen must acknowledge the interactions
 func (a *PillAPI) UpdatePill(w http.ResponseWriter, r *http.Request) {
 	log.WithField("method", "UpdatePill").Info("starting")
 	user := r.Context().Value("user").(*domain.User)
@@ -81,12 +155,23 @@ func (a *PillAPI) UpdatePill(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	data := &stc.PillRequest{}
-	if err := render.Bind(r, data); err != nil {
+	if !preconditionMet(r, etag(pill.Version)) {
+		render.WithMessage("the pill has been updated since it was read").PreconditionFailed(w, r)
+		return
+	}
+
+	data, err := bindPill(r)
+	if err != nil {
 		render.WithError(err).BadRequest(w, r)
 		return
 	}
 
+	a.savePill(w, r, user, pill, data)
+}
+
+// savePill saves the pill of a request over the stored pill and renders it, the stock of the stored pill and
+// whether it has been alerted are kept
+func (a *PillAPI) savePill(w http.ResponseWriter, r *http.Request, user *domain.User, pill *domain.Pill, data *stc.PillRequest) {
 	p := data.Pill
 	if p == nil {
 		err := errors.New("a pill must be supplied")
@@ -105,12 +190,259 @@ func (a *PillAPI) UpdatePill(w http.ResponseWriter, r *http.Request) {
 	if p.ID != pill.ID {
 		err := errors.New("updated pill id must match the parameter pill id")
 		render.WithError(err).BadRequest(w, r)
+		return
 	}
 	if p.UserID != user.ID {
 		err := errors.New("updated pill user id does not match parameter user id")
 		render.WithError(err).BadRequest(w, r)
+		return
+	}
+
+	if !a.linkMedication(w, r, p) {
+		return
+	}
+
+	warnings, ok := a.checkInteractions(w, r, p, data.AcknowledgeInteractions)
+	if !ok {
+		return
+	}
+
+	p.Quantity = pill.Quantity
+	p.Alerted = pill.Alerted
+	p.Version = pill.Version
+	if err := a.PillService.UpdatePill(p.ID, p); err == domain.ErrStale {
+		render.WithMessage("the pill has been updated since it was read").PreconditionFailed(w, r)
+		return
+	} else if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	if err := a.Webhooks.Publish(p.UserID, domain.PillChangedEvent, p, time.Now()); err != nil {
+		log.WithError(err).Error("unable to publish pill changed event")
+	}
+
+	w.Header().Set("ETag", etag(p.Version))
+	if err := renderPill(w, r, p, warnings); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
+
+// PatchPill applies a merge patch or JSON patch to a pill, the patched pill is validated like a full update
+func (a *PillAPI) PatchPill(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "PatchPill").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+	pill := r.Context().Value("pill").(*domain.Pill)
+
+	if pill.UserID != user.ID {
+		render.WithMessage("pill not found").NotFound(w, r)
+		return
+	}
+
+	if !preconditionMet(r, etag(pill.Version)) {
+		render.WithMessage("the pill has been updated since it was read").PreconditionFailed(w, r)
+		return
+	}
+
+	doc, ok := patchDocument(w, r, pillRepresentation(r, pill), pillFields, pillIDFields(r))
+	if !ok {
+		return
+	}
+
+	data, err := bindPill(patchedRequest(r, doc))
+	if err != nil {
+		render.WithError(err).BadRequest(w, r)
+		return
 	}
 
-	a.PillService.UpdatePill(p.ID, p)
+	a.savePill(w, r, user, pill, data)
+}
+
+// linkMedication sets the generic name of a pill which references the catalogue, renders a bad request and
+// returns false when the medication isn't in the catalogue. Pills without a medication are custom entries
+func (a *PillAPI) linkMedication(w http.ResponseWriter, r *http.Request, pill *domain.Pill) bool {
+	if pill.MedicationID == "" {
+		return true
+	}
 
+	medication, err := a.MedicationService.Medication(pill.MedicationID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return false
+	}
+	if medication == nil {
+		render.WithMessage(fmt.Sprintf("medication %s isn't in the catalogue", pill.MedicationID)).BadRequest(w, r)
+		return false
+	}
+
+	pill.Generic = medication.GenericName
+	return true
+}
+
+// checkInteractions the interactions between a pill and the rest of the user's regimen, renders a conflict
+// and returns false when there are interactions which haven't been acknowledged
+func (a *PillAPI) checkInteractions(w http.ResponseWriter, r *http.Request, pill *domain.Pill, acknowledged bool) ([]*domain.InteractionWarning, bool) {
+	regimen, err := a.PillService.Pills(pill.UserID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return nil, false
+	}
+
+	warnings, err := domain.Interactions(a.InteractionService, pill, regimen)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return nil, false
+	}
+
+	if len(warnings) > 0 && !acknowledged {
+		log.WithField("pillId", pill.ID).WithField("interactions", len(warnings)).Info("unacknowledged interactions")
+		render.Instance(w, r, stc.NewInteractionConflictResponse(warnings))
+		return nil, false
+	}
+	return warnings, true
+}
+
+// Interactions lists the interactions between the pills of the user's regimen, most severe first
+func (a *PillAPI) Interactions(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "Interactions").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+
+	regimen, err := a.PillService.Pills(user.ID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	warnings, err := domain.RegimenInteractions(a.InteractionService, regimen)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	if err := render.List(w, r, stc.NewInteractionWarningListResponse(warnings)); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
+
+// Stock returns the stock of a pill, its adjustments and when it is projected to run out
+func (a *PillAPI) Stock(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "Stock").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+	pill := r.Context().Value("pill").(*domain.Pill)
+
+	if pill.UserID != user.ID {
+		render.WithMessage("pill not found").NotFound(w, r)
+		return
+	}
+
+	adjustments, err := a.StockService.Adjustments(pill.ID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	if err := render.Instance(w, r, stc.NewStockResponse(pill, adjustments, time.Now().In(user.Location()))); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
+
+// AdjustStock manually adjusts the stock of a pill, ie. after a refill or a lost dose
+func (a *PillAPI) AdjustStock(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "AdjustStock").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+	pill := r.Context().Value("pill").(*domain.Pill)
+
+	if pill.UserID != user.ID {
+		render.WithMessage("pill not found").NotFound(w, r)
+		return
+	}
+
+	data := &stc.StockAdjustmentRequest{}
+	if err := render.Bind(r, data); err != nil {
+		render.WithError(err).BadRequest(w, r)
+		return
+	}
+
+	adjustment := data.StockAdjustment
+
+	adjustment.ID = 0
+	adjustment.PillID = pill.ID
+	adjustment.Time = time.Now()
+	if err := a.StockService.InsertAdjustment(adjustment); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	if err := a.Refills.CheckStock(pill.ID, adjustment.Time); err != nil {
+		log.WithError(err).Error("unable to check stock")
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// previewLength how far ahead previews look for occurrences
+const previewLength = 366 * 24 * time.Hour
+
+// Preview returns the next occurrences of a pill, ?count=N defaults to 10 and ?from= to now
+func (a *PillAPI) Preview(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "Preview").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+	pill := r.Context().Value("pill").(*domain.Pill)
+
+	if pill.UserID != user.ID {
+		render.WithMessage("pill not found").NotFound(w, r)
+		return
+	}
+
+	preview(w, r, pill)
+}
+
+// PreviewPill returns the next occurrences of a pill which hasn't been saved, ie. while it is being created
+func (a *PillAPI) PreviewPill(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "PreviewPill").Info("starting")
+
+	data, err := bindPill(r)
+	if err != nil {
+		render.WithError(err).BadRequest(w, r)
+		return
+	}
+
+	preview(w, r, data.Pill)
+}
+
+// preview renders the occurrences of a pill in the user's timezone
+func preview(w http.ResponseWriter, r *http.Request, pill *domain.Pill) {
+	user := r.Context().Value("user").(*domain.User)
+
+	count := 10
+	if c := r.URL.Query().Get("count"); c != "" {
+		var err error
+		if count, err = strconv.Atoi(c); err != nil || count < 1 || count > 100 {
+			render.WithMessage("count must be between 1 and 100").BadRequest(w, r)
+			return
+		}
+	}
+
+	from := time.Now()
+	if f := r.URL.Query().Get("from"); f != "" {
+		var err error
+		if from, err = time.Parse(time.RFC3339, f); err != nil {
+			render.WithMessage("from must be an RFC 3339 time").BadRequest(w, r)
+			return
+		}
+	}
+
+	from = from.In(user.Location())
+	occurrences := pill.Occurrences(from, from.Add(previewLength))
+	if len(occurrences) > count {
+		occurrences = occurrences[:count]
+	}
+
+	if err := render.List(w, r, stc.NewOccurrenceListResponse(occurrences)); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
 }
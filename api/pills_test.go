@@ -22,13 +22,12 @@ func TestPillCtx(t *testing.T) {
 		{"/pills/bad", "", "GET", nil, http.StatusBadRequest, `{"message":"unable to parse parameter id"}`},
 	}
 
-	d := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
 	pSvc.PillFn = func(id int) (*domain.Pill, error) {
 		if id == 1 {
-			pill := domain.Pill{ID: 1, UserID: 1, Name: "DoxyPoxy", DaysOfWeek: []int{1, 2, 3, 4, 5, 6, 7}, TimesOfDay: []time.Time{d}, Archived: false}
+			pill := domain.Pill{ID: 1, UserID: 1, Name: "DoxyPoxy", DaysOfWeek: []int{1, 2, 3, 4, 5, 6, 7}, TimesOfDay: []domain.TimeOfDay{{Hour: 23}}, Archived: false}
 			return &pill, nil
 		} else if id == 2 {
-			pill := domain.Pill{ID: 2, UserID: 1, Name: "DoxyPoxy", DaysOfWeek: []int{1, 2, 3, 4, 5, 6, 7}, TimesOfDay: []time.Time{d}, Archived: false}
+			pill := domain.Pill{ID: 2, UserID: 1, Name: "DoxyPoxy", DaysOfWeek: []int{1, 2, 3, 4, 5, 6, 7}, TimesOfDay: []domain.TimeOfDay{{Hour: 23}}, Archived: false}
 			return &pill, nil
 		}
 		return nil, nil
@@ -55,17 +54,16 @@ func TestPills(t *testing.T) {
 	uAPI.UserService = &uSvc
 
 	tests := []*test{
-		{"/users/1/pills", "", "GET", nil, http.StatusOK, `[{"pillId":1,"id":1,"name":"DoxyPoxy","daysOfWeek":[1],"timesOfDay":["2009-11-10T23:00:00Z"],"archived":false}]`},
+		{"/users/1/pills", "", "GET", nil, http.StatusOK, `[{"pillId":1,"id":1,"name":"DoxyPoxy","medicationId":"","generic":"","daysOfWeek":[1],"timesOfDay":["23:00"],"schedule":null,"archived":false,"strength":0,"unit":"","form":"","route":"","food":"","instructions":"","prescriber":"","startDate":null,"endDate":null,"quantity":0,"quantityPerDose":0,"alertDays":0}]`},
 		{"/users/2/pills", "", "GET", nil, http.StatusOK, "[]"},
 	}
 
-	d := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
 	pSvc.PillsFn = func(id int) ([]*domain.Pill, error) {
 		if id != 1 {
 			return nil, nil
 		}
 		pills := []*domain.Pill{
-			{ID: 1, UserID: 1, Name: "DoxyPoxy", DaysOfWeek: []int{1}, TimesOfDay: []time.Time{d}, Archived: false},
+			{ID: 1, UserID: 1, Name: "DoxyPoxy", DaysOfWeek: []int{1}, TimesOfDay: []domain.TimeOfDay{{Hour: 23}}, Archived: false},
 		}
 		return pills, nil
 	}
@@ -93,14 +91,20 @@ func TestUpdatePill(t *testing.T) {
 	uAPI.UserService = &uSvc
 
 	var tests = []*test{
-		{"/users/1/pills/1", "POST", `{"pillId":1,"id":1,"name":"DoxyPoxy","daysOfWeek":[1],"timesOfDay":["2009-11-10T23:00:00Z"],"archived":false}`, map[string]string{"Content-Type": "application/json"}, http.StatusOK, ""},
+		{"/users/1/pills/1", "POST", `{"pillId":1,"id":1,"name":"DoxyPoxy","daysOfWeek":[1],"timesOfDay":["2009-11-10T23:00:00Z"],"archived":false}`, map[string]string{"Content-Type": "application/json"}, http.StatusOK, `{"pillId":1,"id":1,"name":"DoxyPoxy","medicationId":"","generic":"","daysOfWeek":[1],"timesOfDay":["23:00"],"schedule":null,"archived":false,"strength":0,"unit":"","form":"","route":"","food":"","instructions":"","prescriber":"","startDate":null,"endDate":null,"quantity":30,"quantityPerDose":0,"alertDays":0}`},
+		{"/users/1/pills/1", "POST", `{"name":"DoxyPoxy","quantity":100}`, map[string]string{"Content-Type": "application/json"}, http.StatusOK, `{"pillId":1,"id":1,"name":"DoxyPoxy","medicationId":"","generic":"","daysOfWeek":null,"timesOfDay":null,"schedule":null,"archived":false,"strength":0,"unit":"","form":"","route":"","food":"","instructions":"","prescriber":"","startDate":null,"endDate":null,"quantity":30,"quantityPerDose":0,"alertDays":0}`},
 		{"/users/1/pills/2", "POST", `{"pillId":1,"id":1,"name":"DoxyPoxy", "archived":false}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"updated pill id must match the parameter pill id"}`},
 		{"/users/2/pills/1", "POST", `{"pillId":1,"id":1,"name":"DoxyPoxy", "archived":false}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"parameter pill user id should match the parameter user ID"}`},
+		{"/users/1/pills/1", "POST", `{"name":"DoxyPoxy","strength":500,"unit":"mg","form":"tablet","route":"oral","food":"with-food","instructions":"Swallow whole","prescriber":"Dr. Who","startDate":"2009-11-01T00:00:00Z","endDate":"2009-11-10T00:00:00Z"}`, map[string]string{"Content-Type": "application/json"}, http.StatusOK, `{"pillId":1,"id":1,"name":"DoxyPoxy","medicationId":"","generic":"","daysOfWeek":null,"timesOfDay":null,"schedule":null,"archived":false,"strength":500,"unit":"mg","form":"tablet","route":"oral","food":"with-food","instructions":"Swallow whole","prescriber":"Dr. Who","startDate":"2009-11-01T00:00:00Z","endDate":"2009-11-10T00:00:00Z","quantity":30,"quantityPerDose":0,"alertDays":0}`},
+		{"/users/1/pills/1", "POST", `{"name":"DoxyPoxy","form":"powder"}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"form must be one of tablet, capsule, liquid, injection","fields":{"form":"form must be one of tablet, capsule, liquid, injection"}}`},
+		{"/users/1/pills/1", "POST", `{"name":"DoxyPoxy","strength":500}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"a unit must be supplied with the strength"}`},
+		{"/users/1/pills/1", "POST", `{"name":"DoxyPoxy","startDate":"2009-11-10T00:00:00Z","endDate":"2009-11-01T00:00:00Z"}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"the end date must not be before the start date"}`},
+		{"/users/1/pills/1", "POST", `{"name":"DoxyPoxy","daysOfWeek":[0]}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"daysOfWeek[0] must be a day of the week from 1 for Monday to 7 for Sunday","fields":{"daysOfWeek[0]":"daysOfWeek[0] must be a day of the week from 1 for Monday to 7 for Sunday"}}`},
+		{"/users/1/pills/1", "POST", `{"name":"DoxyPoxy","timesOfDay":["08:00","12:00","8:00"]}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"timesOfDay must not have the same time twice","fields":{"timesOfDay":"timesOfDay must not have the same time twice"}}`},
 	}
 
-	d := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
 	pSvc.PillFn = func(id int) (*domain.Pill, error) {
-		return &domain.Pill{ID: id, UserID: 1, Name: "DoxyPoxy", DaysOfWeek: []int{1, 2, 3, 4, 5, 6, 7}, TimesOfDay: []time.Time{d}, Archived: false}, nil
+		return &domain.Pill{ID: id, UserID: 1, Name: "DoxyPoxy", DaysOfWeek: []int{1, 2, 3, 4, 5, 6, 7}, TimesOfDay: []domain.TimeOfDay{{Hour: 23}}, Archived: false, Quantity: 30, Alerted: true}, nil
 	}
 
 	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
@@ -111,8 +115,17 @@ func TestUpdatePill(t *testing.T) {
 		if id != 1 {
 			return errors.New("pill not found")
 		}
+		if !pill.Alerted {
+			return errors.New("expected the low stock alert to stay sent")
+		}
 		return nil
 	}
+	pSvc.PillsFn = func(id int) ([]*domain.Pill, error) {
+		return []*domain.Pill{}, nil
+	}
+
+	iSvc := mock.InteractionService{}
+	pAPI.InteractionService = &iSvc
 
 	r := chi.NewRouter()
 	r.Route("/users/{userId}", func(r chi.Router) {
@@ -125,3 +138,192 @@ func TestUpdatePill(t *testing.T) {
 
 	runTests(t, r, tests)
 }
+
+func TestStock(t *testing.T) {
+	pAPI := PillAPI{}
+	pSvc := mock.PillService{}
+	sSvc := mock.StockService{}
+	pAPI.PillService = &pSvc
+	pAPI.StockService = &sSvc
+	uSvc := mock.UserService{}
+	pAPI.Refills = &RefillAPI{PillService: &pSvc, UserService: &uSvc}
+
+	uAPI := UserAPI{}
+	uAPI.UserService = &uSvc
+
+	tests := []*test{
+		{"/users/1/pills/1/stock", "GET", "", nil, http.StatusOK, `{"quantity":10,"quantityPerDose":0,"runOut":null,"adjustments":[{"id":1,"pillId":1,"change":10,"reason":"refill","time":"2009-11-10T23:00:00Z"}]}`},
+		{"/users/2/pills/1/stock", "GET", "", nil, http.StatusNotFound, `{"message":"pill not found"}`},
+		{"/users/1/pills/1/stock", "POST", `{"change":-1,"reason":"dropped one"}`, map[string]string{"Content-Type": "application/json"}, http.StatusCreated, ""},
+		{"/users/1/pills/1/stock", "POST", `{"change":-1}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"reason is required","fields":{"reason":"reason is required"}}`},
+		{"/users/1/pills/1/stock", "POST", `{"change":-1,"reason":"dose"}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"dose adjustments are made automatically"}`},
+	}
+
+	d := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	pill := &domain.Pill{ID: 1, UserID: 1, Name: "DoxyPoxy", DaysOfWeek: []int{1, 2, 3, 4, 5, 6, 7}, TimesOfDay: []domain.TimeOfDay{{Hour: 23}}, Quantity: 10}
+	pSvc.PillFn = func(id int) (*domain.Pill, error) {
+		return pill, nil
+	}
+
+	adjustments := []*domain.StockAdjustment{{ID: 1, PillID: 1, Change: 10, Reason: "refill", Time: d}}
+	sSvc.AdjustmentsFn = func(pillID int) ([]*domain.StockAdjustment, error) {
+		return adjustments, nil
+	}
+	sSvc.InsertAdjustmentFn = func(adjustment *domain.StockAdjustment) error {
+		adjustments = append(adjustments, adjustment)
+		return nil
+	}
+
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id, Email: "jacob.smith@unb.ca", Password: "password", FirstName: "Jacob", LastName: "Smith", Archived: false}, nil
+	}
+
+	r := chi.NewRouter()
+	r.Route("/users/{userId}/pills/{pillId}", func(r chi.Router) {
+		r.Use(uAPI.UserCtx)
+		r.Use(pAPI.PillCtx)
+		r.Get("/stock", pAPI.Stock)
+		r.Post("/stock", pAPI.AdjustStock)
+	})
+
+	runTests(t, r, tests)
+
+	if len(adjustments) != 2 || adjustments[1].PillID != 1 || adjustments[1].Change != -1 || adjustments[1].Reason != "dropped one" {
+		t.Errorf("expected the manual adjustment to be inserted, got %v", adjustments)
+	}
+
+	// 9 twice daily doses of 1 last until the evening of the 5th day
+	pill.Quantity = 9
+	pill.QuantityPerDose = 1
+	pill.TimesOfDay = []domain.TimeOfDay{{Hour: 23}, {Hour: 9}}
+	runOut, ok := pill.RunOut(d.Add(time.Minute))
+	if expected := time.Date(2009, time.November, 15, 23, 0, 0, 0, time.UTC); !ok || !runOut.Equal(expected) {
+		t.Errorf("expected the pill to run out at %v, got %v", expected, runOut)
+	}
+}
+
+func TestCourse(t *testing.T) {
+	d := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	start := time.Date(2009, time.November, 11, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2009, time.November, 13, 0, 0, 0, 0, time.UTC)
+	pill := &domain.Pill{ID: 1, UserID: 1, Name: "DoxyPoxy", Strength: 0.5, Unit: "mg", DaysOfWeek: []int{1, 2, 3, 4, 5, 6, 7}, TimesOfDay: []domain.TimeOfDay{{Hour: 23}}, StartDate: &start, EndDate: &end}
+
+	if dosage := pill.Dosage(); dosage != "0.5 mg" {
+		t.Errorf("expected 0.5 mg, got %s", dosage)
+	}
+
+	// the course includes its start and end dates
+	doses := pill.Doses(d.AddDate(0, 0, -1), d.AddDate(0, 0, 7))
+	if len(doses) != 3 || !doses[0].Equal(d.AddDate(0, 0, 1)) || !doses[2].Equal(d.AddDate(0, 0, 3)) {
+		t.Errorf("expected doses on the 11th through the 13th, got %v", doses)
+	}
+
+	if pill.Ended(end.Add(23*time.Hour)) || !pill.Ended(end.AddDate(0, 0, 1)) {
+		t.Error("expected the course to end after the end date")
+	}
+}
+
+func TestPreview(t *testing.T) {
+	pAPI := PillAPI{}
+	pSvc := mock.PillService{}
+	pAPI.PillService = &pSvc
+
+	uAPI := UserAPI{}
+	uSvc := mock.UserService{}
+	uAPI.UserService = &uSvc
+
+	schedule := func(s string) string {
+		return `{"name":"DoxyPoxy","quantityPerDose":1,"schedule":` + s + `}`
+	}
+	json := map[string]string{"Content-Type": "application/json"}
+
+	tests := []*test{
+		{"/users/1/pills/preview?from=2018-01-01T00:00:00Z&count=4", "POST", schedule(`{"start":"2018-01-01T06:00:00Z","rrule":"FREQ=HOURLY;INTERVAL=8"}`), json, http.StatusOK, `[{"time":"2018-01-01T06:00:00Z","strength":0,"quantity":1},{"time":"2018-01-01T14:00:00Z","strength":0,"quantity":1},{"time":"2018-01-01T22:00:00Z","strength":0,"quantity":1},{"time":"2018-01-02T06:00:00Z","strength":0,"quantity":1}]`},
+		{"/users/1/pills/preview?from=2018-01-01T00:00:00Z&count=2", "POST", schedule(`{"start":"2015-01-01T00:00:00Z","rrule":"FREQ=HOURLY;INTERVAL=8"}`), json, http.StatusOK, `[{"time":"2018-01-01T00:00:00Z","strength":0,"quantity":1},{"time":"2018-01-01T08:00:00Z","strength":0,"quantity":1}]`},
+		{"/users/1/pills/preview?from=2018-01-01T00:00:00Z&count=3", "POST", schedule(`{"start":"2018-01-01T08:00:00Z","rrule":"FREQ=DAILY;INTERVAL=2"}`), json, http.StatusOK, `[{"time":"2018-01-01T08:00:00Z","strength":0,"quantity":1},{"time":"2018-01-03T08:00:00Z","strength":0,"quantity":1},{"time":"2018-01-05T08:00:00Z","strength":0,"quantity":1}]`},
+		{"/users/1/pills/preview?from=2018-01-01T00:00:00Z&count=4", "POST", schedule(`{"start":"2018-01-01T08:00:00Z","rrule":"FREQ=WEEKLY;BYDAY=MO,WE,FR;BYHOUR=8,20;BYMINUTE=0"}`), json, http.StatusOK, `[{"time":"2018-01-01T08:00:00Z","strength":0,"quantity":1},{"time":"2018-01-01T20:00:00Z","strength":0,"quantity":1},{"time":"2018-01-03T08:00:00Z","strength":0,"quantity":1},{"time":"2018-01-03T20:00:00Z","strength":0,"quantity":1}]`},
+		{"/users/1/pills/preview?from=2018-01-01T00:00:00Z&count=3", "POST", schedule(`{"start":"2018-01-01T09:00:00Z","rrule":"FREQ=MONTHLY;BYDAY=1MO"}`), json, http.StatusOK, `[{"time":"2018-01-01T09:00:00Z","strength":0,"quantity":1},{"time":"2018-02-05T09:00:00Z","strength":0,"quantity":1},{"time":"2018-03-05T09:00:00Z","strength":0,"quantity":1}]`},
+		{"/users/1/pills/preview?from=2018-01-01T00:00:00Z&count=2", "POST", schedule(`{"start":"2018-01-01T09:00:00Z","rrule":"FREQ=MONTHLY;BYDAY=-1FR"}`), json, http.StatusOK, `[{"time":"2018-01-26T09:00:00Z","strength":0,"quantity":1},{"time":"2018-02-23T09:00:00Z","strength":0,"quantity":1}]`},
+		{"/users/1/pills/preview?from=2018-01-01T00:00:00Z", "POST", schedule(`{"start":"2018-01-01T08:00:00Z","rrule":"FREQ=DAILY;COUNT=2"}`), json, http.StatusOK, `[{"time":"2018-01-01T08:00:00Z","strength":0,"quantity":1},{"time":"2018-01-02T08:00:00Z","strength":0,"quantity":1}]`},
+		{"/users/1/pills/preview?from=2018-01-07T00:00:00Z&count=3", "POST", schedule(`{"start":"2018-01-01T08:00:00Z","rrule":"FREQ=DAILY","taper":[{"days":7,"strength":40},{"days":7,"strength":30},{"days":7,"strength":20}]}`), json, http.StatusOK, `[{"time":"2018-01-07T08:00:00Z","strength":40,"quantity":1},{"time":"2018-01-08T08:00:00Z","strength":30,"quantity":1},{"time":"2018-01-09T08:00:00Z","strength":30,"quantity":1}]`},
+		{"/users/1/pills/preview?from=2018-01-21T00:00:00Z&count=3", "POST", schedule(`{"start":"2018-01-01T08:00:00Z","rrule":"FREQ=DAILY","taper":[{"days":7,"strength":40},{"days":7,"strength":30},{"days":7,"strength":20}]}`), json, http.StatusOK, `[{"time":"2018-01-21T08:00:00Z","strength":20,"quantity":1}]`},
+		{"/users/1/pills/preview?from=2018-01-01T00:00:00Z", "POST", schedule(`{"start":"2018-01-01T08:00:00Z","asNeeded":true,"minInterval":240}`), json, http.StatusOK, `[]`},
+		{"/users/1/pills/preview?from=2018-01-01T00:00:00Z&count=2", "POST", `{"name":"DoxyPoxy","quantityPerDose":1,"daysOfWeek":[1],"timesOfDay":["2009-11-10T23:00:00Z"]}`, json, http.StatusOK, `[{"time":"2018-01-01T23:00:00Z","strength":0,"quantity":1},{"time":"2018-01-08T23:00:00Z","strength":0,"quantity":1}]`},
+		{"/users/1/pills/preview", "POST", schedule(`{"start":"2018-01-01T08:00:00Z","rrule":"FREQ=YEARLY"}`), json, http.StatusBadRequest, `{"message":"unsupported frequency YEARLY"}`},
+		{"/users/1/pills/preview", "POST", schedule(`{"start":"2018-01-01T08:00:00Z","rrule":"FREQ=WEEKLY;BYDAY=1MO"}`), json, http.StatusBadRequest, `{"message":"numbered BYDAY entries are only supported with FREQ=MONTHLY"}`},
+		{"/users/1/pills/preview", "POST", schedule(`{"start":"2018-01-01T08:00:00Z"}`), json, http.StatusBadRequest, `{"message":"a rule must be supplied unless the pill is taken as needed"}`},
+		{"/users/1/pills/preview?count=0", "POST", schedule(`{"start":"2018-01-01T08:00:00Z","rrule":"FREQ=DAILY"}`), json, http.StatusBadRequest, `{"message":"count must be between 1 and 100"}`},
+		{"/users/1/pills/1/preview?from=2018-01-01T00:00:00Z&count=1", "GET", "", nil, http.StatusOK, `[{"time":"2018-01-01T08:00:00Z","strength":0,"quantity":0}]`},
+		{"/users/2/pills/1/preview", "GET", "", nil, http.StatusNotFound, `{"message":"pill not found"}`},
+	}
+
+	start := time.Date(2018, time.January, 1, 8, 0, 0, 0, time.UTC)
+	pSvc.PillFn = func(id int) (*domain.Pill, error) {
+		return &domain.Pill{ID: id, UserID: 1, Name: "DoxyPoxy", Schedule: &domain.Schedule{Start: start, RRule: "FREQ=DAILY"}}, nil
+	}
+
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id, Email: "jacob.smith@unb.ca", Password: "password", FirstName: "Jacob", LastName: "Smith", Archived: false}, nil
+	}
+
+	r := chi.NewRouter()
+	r.Route("/users/{userId}/pills", func(r chi.Router) {
+		r.Use(uAPI.UserCtx)
+		r.Post("/preview", pAPI.PreviewPill)
+		r.With(pAPI.PillCtx).Get("/{pillId}/preview", pAPI.Preview)
+	})
+
+	runTests(t, r, tests)
+
+	// schedules which can be expressed with days and times are kept readable for older clients
+	days, times := (&domain.Schedule{Start: start, RRule: "FREQ=WEEKLY;BYDAY=WE,MO;BYHOUR=8,20"}).Legacy()
+	if len(days) != 2 || days[0] != 1 || days[1] != 3 || len(times) != 2 || times[1] != (domain.TimeOfDay{Hour: 20}) {
+		t.Errorf("expected mondays and wednesdays at 8 and 20, got %v %v", days, times)
+	}
+	if days, times := (&domain.Schedule{Start: start, RRule: "FREQ=HOURLY;INTERVAL=8"}).Legacy(); len(days) != 0 || len(times) != 0 {
+		t.Errorf("expected every 8 hours to have no equivalent, got %v %v", days, times)
+	}
+}
+
+func TestTimezones(t *testing.T) {
+	pAPI := PillAPI{}
+	pSvc := mock.PillService{}
+	pAPI.PillService = &pSvc
+
+	uAPI := UserAPI{}
+	uSvc := mock.UserService{}
+	uAPI.UserService = &uSvc
+
+	users := map[int]*domain.User{
+		1: {ID: 1, Email: "jacob.smith@unb.ca", Password: "password", FirstName: "Jacob", LastName: "Smith", Timezone: "America/Halifax"},
+		2: {ID: 2, Email: "jacob.smith@unb.ca", FirstName: "Jacob", LastName: "Smith", Timezone: "America/Halifax", TravelTimezone: "Europe/Paris", TravelMode: domain.LocalTravelMode},
+		3: {ID: 3, Email: "jacob.smith@unb.ca", FirstName: "Jacob", LastName: "Smith", Timezone: "America/Halifax", TravelTimezone: "Europe/Paris", TravelMode: domain.HomeTravelMode},
+	}
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return users[id], nil
+	}
+	uSvc.UpdateUserFn = func(id int, user *domain.User) error {
+		return nil
+	}
+
+	pSvc.PillFn = func(id int) (*domain.Pill, error) {
+		return &domain.Pill{ID: id, UserID: id, Name: "DoxyPoxy", DaysOfWeek: []int{1, 2, 3, 4, 5, 6, 7}, TimesOfDay: []domain.TimeOfDay{{Hour: 8}}}, nil
+	}
+
+	tests := []*test{
+		// dose times stay at 8 in the morning across the change to daylight saving time
+		{"/users/1/pills/1/preview?from=2018-03-10T00:00:00Z&count=3", "GET", "", nil, http.StatusOK, `[{"time":"2018-03-10T08:00:00-04:00","strength":0,"quantity":0},{"time":"2018-03-11T08:00:00-03:00","strength":0,"quantity":0},{"time":"2018-03-12T08:00:00-03:00","strength":0,"quantity":0}]`},
+		{"/users/2/pills/2/preview?from=2018-03-10T00:00:00Z&count=1", "GET", "", nil, http.StatusOK, `[{"time":"2018-03-10T08:00:00+01:00","strength":0,"quantity":0}]`},
+		{"/users/3/pills/3/preview?from=2018-03-10T00:00:00Z&count=1", "GET", "", nil, http.StatusOK, `[{"time":"2018-03-10T08:00:00-04:00","strength":0,"quantity":0}]`},
+		{"/users/1", "POST", `{"email":"jacob.smith@unb.ca","password":"password","firstName":"Jacob","lastName":"Smith","timezone":"Mars/Olympus"}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"unknown timezone Mars/Olympus"}`},
+	}
+
+	r := chi.NewRouter()
+	r.Route("/users/{userId}", func(r chi.Router) {
+		r.Use(uAPI.UserCtx)
+		r.Post("/", uAPI.UpdateUser)
+		r.With(pAPI.PillCtx).Get("/pills/{pillId}/preview", pAPI.Preview)
+	})
+
+	runTests(t, r, tests)
+}
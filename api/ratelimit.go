@@ -0,0 +1,53 @@
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/log"
+	"github.com/jacsmith21/lukabox/ext/render"
+)
+
+// RateLimitAPI the services used
+type RateLimitAPI struct {
+	RateLimiter domain.RateLimiter
+}
+
+// ByIP limits the rate of requests from each ip address using the policy
+func (a *RateLimitAPI) ByIP(policy domain.RateLimitPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := remoteIP(r)
+			allowed, wait, err := a.RateLimiter.Allow("ip:"+ip, policy)
+			if err != nil {
+				log.WithError(err).Error("error checking rate limit")
+				render.WithError(err).InternalServerError(w, r)
+				return
+			}
+			if !allowed {
+				log.WithField("ip", ip).Debug("ip rate limited")
+				tooManyRequests(w, r, wait)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func tooManyRequests(w http.ResponseWriter, r *http.Request, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	render.WithMessage("too many requests").TooManyRequests(w, r)
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/db"
+)
+
+func TestByIP(t *testing.T) {
+	rlAPI := RateLimitAPI{}
+	rlAPI.RateLimiter = &db.RateLimiter{}
+
+	tests := []*test{
+		{"/login", "POST", "", nil, http.StatusOK, "This is a test!"},
+		{"/login", "POST", "", nil, http.StatusOK, "This is a test!"},
+		{"/login", "POST", "", nil, http.StatusTooManyRequests, `{"message":"too many requests"}`},
+	}
+
+	policy := domain.RateLimitPolicy{Capacity: 2, Interval: time.Hour}
+
+	r := chi.NewRouter()
+	r.With(rlAPI.ByIP(policy)).Post("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("This is a test!"))
+	})
+
+	runTests(t, r, tests)
+}
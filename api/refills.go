@@ -0,0 +1,142 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/log"
+	"github.com/jacsmith21/lukabox/ext/refill"
+	"github.com/jacsmith21/lukabox/ext/render"
+	"github.com/jacsmith21/lukabox/stc"
+)
+
+// refillDays how many days of doses a refill request asks for
+const refillDays = 28
+
+// RefillAPI the services used
+type RefillAPI struct {
+	PillService      domain.PillService
+	UserService      domain.UserService
+	CaregiverService domain.CaregiverService
+	Mailer           domain.Mailer
+	RefillChannel    domain.RefillChannel
+	Webhooks         *WebhookAPI
+	Stream           *StreamAPI
+}
+
+// CheckStock alerts the patient and their caregivers when a pill is running low,
+// alerts are only sent once until the pill is refilled above its threshold
+func (a *RefillAPI) CheckStock(pillID int, now time.Time) error {
+	pill, err := a.PillService.Pill(pillID)
+	if err != nil {
+		return err
+	}
+
+	user, err := a.UserService.UserByID(pill.UserID)
+	if err != nil {
+		return err
+	}
+	now = now.In(user.Location())
+
+	if !pill.LowStock(now) {
+		if !pill.Alerted {
+			return nil
+		}
+		pill.Alerted = false
+		return a.PillService.UpdatePill(pill.ID, pill)
+	}
+	if pill.Alerted {
+		return nil
+	}
+
+	caregivers, err := a.CaregiverService.Caregivers(user.ID)
+	if err != nil {
+		return err
+	}
+
+	runOut, _ := pill.RunOut(now)
+	log.WithField("pillId", pill.ID).WithField("runOut", runOut).Info("pill running low")
+
+	for _, u := range append([]*domain.User{user}, caregivers...) {
+		alert := &domain.Email{
+			To:      u.Email,
+			Subject: fmt.Sprintf("%s is running low", pill.Name),
+			Body:    fmt.Sprintf("%s %s will run out of %s on %s, request a refill soon.", user.FirstName, user.LastName, pill.Name, runOut.Format("Monday, January 2")),
+		}
+		if err := a.Mailer.Send(alert); err != nil {
+			log.WithError(err).Error("error sending low stock alert")
+		}
+	}
+
+	alert := &domain.StockAlert{PillID: pill.ID, Pill: pill.Name, Quantity: pill.Quantity, RunOut: runOut}
+	if err := a.Webhooks.Publish(user.ID, domain.LowStockEvent, alert, now); err != nil {
+		log.WithError(err).Error("unable to publish low stock event")
+	}
+	a.Stream.Publish(user.ID, domain.LowStockEvent, alert, now)
+
+	pill.Alerted = true
+	return a.PillService.UpdatePill(pill.ID, pill)
+}
+
+// refillRequest creates a refill request for enough of a pill to last refillDays
+func refillRequest(user *domain.User, pill *domain.Pill, now time.Time) *domain.RefillRequest {
+	now = now.In(user.Location())
+	quantity := 0.0
+	for _, o := range pill.Occurrences(now, now.AddDate(0, 0, refillDays)) {
+		quantity += o.Quantity
+	}
+	return &domain.RefillRequest{
+		Patient:    domain.RefillPatient{ID: user.ID, FirstName: user.FirstName, LastName: user.LastName, Email: user.Email},
+		Medication: domain.RefillMedication{PillID: pill.ID, Name: pill.Name, Strength: pill.Dosage()},
+		Quantity:   quantity,
+		Created:    now,
+	}
+}
+
+// RefillRequest returns a refill request for a pill as JSON, or as a PDF when requested with the .pdf extension
+func (a *RefillAPI) RefillRequest(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "RefillRequest").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+	pill := r.Context().Value("pill").(*domain.Pill)
+
+	if pill.UserID != user.ID {
+		render.WithMessage("pill not found").NotFound(w, r)
+		return
+	}
+
+	request := refillRequest(user, pill, time.Now())
+
+	if format, _ := r.Context().Value(middleware.URLFormatCtxKey).(string); format == "pdf" {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=refill-%d.pdf", pill.ID))
+		w.Write(refill.PDF(request))
+		return
+	}
+
+	if err := render.Instance(w, r, stc.NewRefillRequestResponse(request)); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
+
+// SendRefillRequest sends a refill request for a pill to the pharmacy
+func (a *RefillAPI) SendRefillRequest(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "SendRefillRequest").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+	pill := r.Context().Value("pill").(*domain.Pill)
+
+	if pill.UserID != user.ID {
+		render.WithMessage("pill not found").NotFound(w, r)
+		return
+	}
+
+	if err := a.RefillChannel.SendRefill(refillRequest(user, pill, time.Now())); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/refill"
+	"github.com/jacsmith21/lukabox/mock"
+)
+
+func TestCheckStock(t *testing.T) {
+	rAPI := RefillAPI{}
+	pSvc := mock.PillService{}
+	uSvc := mock.UserService{}
+	cSvc := mock.CaregiverService{}
+	mailer := mock.Mailer{}
+	rAPI.PillService = &pSvc
+	rAPI.UserService = &uSvc
+	rAPI.CaregiverService = &cSvc
+	rAPI.Mailer = &mailer
+
+	now := time.Date(2009, time.November, 10, 12, 0, 0, 0, time.UTC)
+	pill := &domain.Pill{ID: 1, UserID: 1, Name: "DoxyPoxy", DaysOfWeek: []int{1, 2, 3, 4, 5, 6, 7}, TimesOfDay: []domain.TimeOfDay{{Hour: 23}}, Quantity: 5, QuantityPerDose: 1, AlertDays: 7}
+	pSvc.PillFn = func(id int) (*domain.Pill, error) {
+		return pill, nil
+	}
+	updated := 0
+	pSvc.UpdatePillFn = func(id int, p *domain.Pill) error {
+		updated++
+		return nil
+	}
+
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id, Email: "jacob.smith@unb.ca", FirstName: "Jacob", LastName: "Smith"}, nil
+	}
+	cSvc.CaregiversFn = func(patientID int) ([]*domain.User, error) {
+		return []*domain.User{{ID: 2, Email: "caregiver@unb.ca", Role: domain.CaregiverRole}}, nil
+	}
+
+	sent := []*domain.Email{}
+	mailer.SendFn = func(email *domain.Email) error {
+		sent = append(sent, email)
+		return nil
+	}
+
+	// 5 days remaining is below the 7 day threshold so the patient and their caregiver are alerted once
+	for i := 0; i < 2; i++ {
+		if err := rAPI.CheckStock(1, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(sent) != 2 || sent[0].To != "jacob.smith@unb.ca" || sent[1].To != "caregiver@unb.ca" || !pill.Alerted {
+		t.Fatalf("expected a single alert to the patient and caregiver, got %v", sent)
+	}
+	if expected := "Jacob Smith will run out of DoxyPoxy on Sunday, November 15, request a refill soon."; sent[0].Body != expected {
+		t.Errorf("expected %s, got %s", expected, sent[0].Body)
+	}
+
+	// refilling resets the alert
+	pill.Quantity = 30
+	if err := rAPI.CheckStock(1, now); err != nil {
+		t.Fatal(err)
+	}
+	if pill.Alerted || len(sent) != 2 || updated != 2 {
+		t.Errorf("expected the alert to be reset without sending, got %v", pill)
+	}
+}
+
+func TestRefillRequest(t *testing.T) {
+	rAPI := RefillAPI{}
+	pSvc := mock.PillService{}
+	uSvc := mock.UserService{}
+	outbox := refill.Outbox{}
+	rAPI.PillService = &pSvc
+	rAPI.RefillChannel = &outbox
+
+	uAPI := UserAPI{}
+	uAPI.UserService = &uSvc
+	pAPI := PillAPI{}
+	pAPI.PillService = &pSvc
+
+	pSvc.PillFn = func(id int) (*domain.Pill, error) {
+		return &domain.Pill{ID: 1, UserID: 1, Name: "DoxyPoxy", Strength: 100, Unit: "mg", DaysOfWeek: []int{1, 2, 3, 4, 5, 6, 7}, TimesOfDay: []domain.TimeOfDay{{Hour: 23}, {Hour: 23}}, QuantityPerDose: 0.5}, nil
+	}
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id, Email: "zoe.smith@unb.ca", FirstName: "Zoë 李", LastName: "Smith"}, nil
+	}
+
+	r := chi.NewRouter()
+	r.Use(middleware.URLFormat)
+	r.Route("/users/{userId}/pills/{pillId}", func(r chi.Router) {
+		r.Use(uAPI.UserCtx)
+		r.Use(pAPI.PillCtx)
+		r.Get("/refill", rAPI.RefillRequest)
+		r.Post("/refill", rAPI.SendRefillRequest)
+	})
+
+	tests := []*test{
+		{"/users/2/pills/1/refill", "GET", "", nil, http.StatusNotFound, `{"message":"pill not found"}`},
+		{"/users/1/pills/1/refill", "POST", "", nil, http.StatusAccepted, ""},
+	}
+	runTests(t, r, tests)
+
+	if len(outbox.Requests) != 1 || outbox.Requests[0].Quantity != 28 || outbox.Requests[0].Medication.Strength != "100 mg" {
+		t.Errorf("expected a refill request for 28 days of two half doses, got %v", outbox.Requests)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/users/1/pills/1/refill", nil))
+	request := &domain.RefillRequest{}
+	if err := json.NewDecoder(w.Body).Decode(request); err != nil {
+		t.Fatal(err)
+	}
+	if request.Patient.LastName != "Smith" || request.Medication.Name != "DoxyPoxy" || request.Quantity != 28 {
+		t.Errorf("unexpected refill request %v", request)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/users/1/pills/1/refill.pdf", nil))
+	if w.Header().Get("Content-Type") != "application/pdf" || !bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-1.4")) || !bytes.Contains(w.Body.Bytes(), []byte("(Strength: 100 mg) Tj")) {
+		t.Errorf("expected a refill request pdf, got %s", w.Body.String())
+	}
+
+	// names are WinAnsi encoded, characters outside of it are replaced
+	if !bytes.Contains(w.Body.Bytes(), []byte(`(Patient: Zo\353 ? Smith) Tj`)) || !bytes.Contains(w.Body.Bytes(), []byte("/Encoding /WinAnsiEncoding")) {
+		t.Errorf("expected the patient's name to be WinAnsi encoded, got %s", w.Body.String())
+	}
+}
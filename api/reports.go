@@ -0,0 +1,103 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/log"
+	"github.com/jacsmith21/lukabox/ext/render"
+	"github.com/jacsmith21/lukabox/ext/report"
+	"github.com/jacsmith21/lukabox/stc"
+)
+
+// maxReportDays the longest period a report can cover
+const maxReportDays = 366
+
+// ReportAPI the services used
+type ReportAPI struct {
+	PillService      domain.PillService
+	PillEventService domain.PillEventService
+}
+
+// AdherenceReport returns the user's adherence between ?from= and ?to= as JSON, CSV or PDF, negotiated by render.Negotiate.
+// Dates include the whole day in the user's timezone, to defaults to today and from to 30 days before it
+func (a *ReportAPI) AdherenceReport(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "AdherenceReport").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+	loc := user.Location()
+	now := time.Now().In(loc)
+
+	to := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, loc)
+	if t := r.URL.Query().Get("to"); t != "" {
+		var err error
+		if to, err = parseReportTime(t, loc, true); err != nil {
+			render.WithMessage("to must be a date or an RFC 3339 time").BadRequest(w, r)
+			return
+		}
+	}
+	from := to.AddDate(0, 0, -exportDays)
+	if f := r.URL.Query().Get("from"); f != "" {
+		var err error
+		if from, err = parseReportTime(f, loc, false); err != nil {
+			render.WithMessage("from must be a date or an RFC 3339 time").BadRequest(w, r)
+			return
+		}
+	}
+	if !from.Before(to) {
+		render.WithMessage("from must be before to").BadRequest(w, r)
+		return
+	}
+	if to.Sub(from) > maxReportDays*24*time.Hour {
+		render.WithMessage(fmt.Sprintf("reports can't cover more than %d days", maxReportDays)).BadRequest(w, r)
+		return
+	}
+
+	pills, err := a.PillService.Pills(user.ID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	taken := map[int][]*domain.PillEvent{}
+	for _, pill := range pills {
+		if taken[pill.ID], err = a.PillEventService.PillEvents(pill.ID); err != nil {
+			render.WithError(err).InternalServerError(w, r)
+			return
+		}
+	}
+
+	adherence := domain.NewAdherenceReport(pills, taken, from, to, now)
+
+	switch render.AcceptedContentType(r) {
+	case render.ContentTypeCSV:
+		w.Header().Set("Content-Type", render.MediaType(render.ContentTypeCSV)+"; charset=utf-8")
+		w.Header().Set("Content-Disposition", "attachment; filename=adherence.csv")
+		w.Write(report.CSV(adherence))
+	case render.ContentTypePDF:
+		w.Header().Set("Content-Type", render.MediaType(render.ContentTypePDF))
+		w.Header().Set("Content-Disposition", "attachment; filename=adherence.pdf")
+		w.Write(report.PDF(user, adherence))
+	default:
+		if err := render.Instance(w, r, stc.NewAdherenceReportResponse(adherence)); err != nil {
+			render.WithError(err).InternalServerError(w, r)
+			return
+		}
+	}
+}
+
+// parseReportTime parses an RFC 3339 time or a date in loc, the end of a period includes the whole date
+func parseReportTime(value string, loc *time.Location, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(loc), nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return t, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
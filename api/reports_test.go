@@ -0,0 +1,103 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/render"
+	"github.com/jacsmith21/lukabox/mock"
+)
+
+func TestAdherenceReport(t *testing.T) {
+	uAPI := UserAPI{}
+	uSvc := mock.UserService{}
+	uAPI.UserService = &uSvc
+	rAPI := ReportAPI{}
+	pSvc := mock.PillService{}
+	eSvc := mock.PillEventService{}
+	rAPI.PillService = &pSvc
+	rAPI.PillEventService = &eSvc
+
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id, FirstName: "Jacob", LastName: "Smith"}, nil
+	}
+	pSvc.PillsFn = func(id int) ([]*domain.Pill, error) {
+		return []*domain.Pill{
+			{ID: 1, UserID: 1, Name: "DoxyPoxy", Strength: 100, Unit: "mg", DaysOfWeek: []int{1, 2, 3, 4, 5, 6, 7}, TimesOfDay: []domain.TimeOfDay{{Hour: 8}}},
+			{ID: 2, UserID: 1, Name: "Advil", Schedule: &domain.Schedule{AsNeeded: true}},
+		}, nil
+	}
+
+	// monday's dose was taken, tuesday's was late and wednesday's was missed
+	dose := func(day int, hour int, minute int) time.Time {
+		return time.Date(2018, time.March, day, hour, minute, 0, 0, time.UTC)
+	}
+	eSvc.PillEventsFn = func(pillID int) ([]*domain.PillEvent, error) {
+		if pillID == 2 {
+			return []*domain.PillEvent{{ID: 3, PillID: 2, Time: dose(6, 12, 0)}}, nil
+		}
+		return []*domain.PillEvent{
+			{ID: 1, PillID: 1, Scheduled: dose(5, 8, 0), Time: dose(5, 8, 10)},
+			{ID: 2, PillID: 1, Scheduled: dose(6, 8, 0), Time: dose(6, 9, 0)},
+		}, nil
+	}
+
+	r := chi.NewRouter()
+	r.Use(render.SetContentType(render.ContentTypeJSON))
+	r.Route("/users/{userId}", func(r chi.Router) {
+		r.Use(uAPI.UserCtx)
+		r.With(render.Negotiate(render.ContentTypeJSON, render.ContentTypeCSV, render.ContentTypePDF)).Get("/reports/adherence", rAPI.AdherenceReport)
+	})
+
+	tests := []*test{
+		{"/users/1/reports/adherence?from=2018-03-05&to=2018-03-07", "GET", "", nil, http.StatusOK, `{"from":"2018-03-05T00:00:00Z","to":"2018-03-08T00:00:00Z","pills":[{"scheduled":3,"taken":1,"late":1,"missed":1,"pillId":1,"name":"DoxyPoxy","dosage":"100 mg","asNeeded":0},{"scheduled":0,"taken":0,"late":0,"missed":0,"pillId":2,"name":"Advil","dosage":"","asNeeded":1}],"days":[{"scheduled":1,"taken":1,"late":0,"missed":0,"day":"2018-03-05T00:00:00Z"},{"scheduled":1,"taken":0,"late":1,"missed":0,"day":"2018-03-06T00:00:00Z"},{"scheduled":1,"taken":0,"late":0,"missed":1,"day":"2018-03-07T00:00:00Z"}],"notes":["Adherence to DoxyPoxy was 67%, below 80%.","Advil is taken as needed, 1 dose was taken."]}`},
+		{"/users/1/reports/adherence?from=2018-03-05&to=2018-03-07&format=csv", "GET", "", nil, http.StatusOK, "pill,dosage,scheduled,taken,late,missed,as needed,adherence\nDoxyPoxy,100 mg,3,1,1,1,0,67%\nAdvil,,0,0,0,0,1,\n\ndate,scheduled,taken,late,missed,adherence\n2018-03-05,1,1,0,0,100%\n2018-03-06,1,0,1,0,100%\n2018-03-07,1,0,0,1,0%\n\nnote\n\"Adherence to DoxyPoxy was 67%, below 80%.\"\n\"Advil is taken as needed, 1 dose was taken.\""},
+		{"/users/1/reports/adherence?format=xml", "GET", "", nil, http.StatusBadRequest, `{"message":"format must be one of json, csv or pdf"}`},
+		{"/users/1/reports/adherence?from=March", "GET", "", nil, http.StatusBadRequest, `{"message":"from must be a date or an RFC 3339 time"}`},
+		{"/users/1/reports/adherence?from=2018-03-07&to=2018-03-05", "GET", "", nil, http.StatusBadRequest, `{"message":"from must be before to"}`},
+		{"/users/1/reports/adherence?from=2017-01-01&to=2018-03-05", "GET", "", nil, http.StatusBadRequest, `{"message":"reports can't cover more than 366 days"}`},
+	}
+	runTests(t, r, tests)
+
+	for _, c := range []struct {
+		url         string
+		accept      string
+		contentType string
+		contains    string
+	}{
+		{"/users/1/reports/adherence?from=2018-03-05&to=2018-03-07", "text/csv", "text/csv; charset=utf-8", "pill,dosage"},
+		{"/users/1/reports/adherence?from=2018-03-05&to=2018-03-07&format=pdf", "", "application/pdf", "(Week of    Mon Tue Wed Thu Fri Sat Sun) Tj"},
+		{"/users/1/reports/adherence?from=2018-03-05&to=2018-03-07", "application/pdf, */*", "application/pdf", "(Mar 5      @@@ @@@ ...) Tj"},
+	} {
+		req, err := http.NewRequest("GET", c.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept", c.accept)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if contentType := w.Header().Get("Content-Type"); contentType != c.contentType {
+			t.Errorf("expected %s, got %s", c.contentType, contentType)
+		}
+		if !bytes.Contains(w.Body.Bytes(), []byte(c.contains)) {
+			t.Errorf("expected the report to contain %q:\n%s", c.contains, strings.TrimSpace(w.Body.String()))
+		}
+	}
+
+	// pill names which spreadsheets would run as formulas are quoted
+	pSvc.PillsFn = func(id int) ([]*domain.Pill, error) {
+		return []*domain.Pill{{ID: 2, UserID: 1, Name: "=HYPERLINK(\"http://example.com\")", Schedule: &domain.Schedule{AsNeeded: true}}}, nil
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/users/1/reports/adherence?from=2018-03-05&to=2018-03-07&format=csv", nil))
+	if !strings.Contains(w.Body.String(), "\n\"'=HYPERLINK(\"\"http://example.com\"\")\",,0,0,0,0,1,\n") {
+		t.Errorf("expected the formula to be quoted, got %s", w.Body.String())
+	}
+}
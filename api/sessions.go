@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/log"
+	"github.com/jacsmith21/lukabox/ext/render"
+	"github.com/jacsmith21/lukabox/stc"
+)
+
+// SessionAPI the services used
+type SessionAPI struct {
+	SessionService domain.SessionService
+}
+
+// SessionCtx is used to create a session context by id, the session must belong to the user in the context
+func (a *SessionAPI) SessionCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.WithField("method", "SessionCtx").Info("starting")
+		user := r.Context().Value("user").(*domain.User)
+
+		id := chi.URLParam(r, "sessionId")
+		if id == "" {
+			render.WithMessage("session id must be supplied").BadRequest(w, r)
+			return
+		}
+
+		session, err := a.SessionService.Session(id)
+		if err != nil {
+			render.WithError(err).InternalServerError(w, r)
+			return
+		}
+		if session == nil || session.UserID != user.ID {
+			render.WithMessage("session not found").NotFound(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "userSession", session)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Sessions lists the user's sessions
+func (a *SessionAPI) Sessions(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "Sessions").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+
+	sessions, err := a.SessionService.Sessions(user.ID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	if err := render.List(w, r, stc.NewSessionListResponse(sessions)); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
+
+// DeleteSession revokes one of the user's sessions
+func (a *SessionAPI) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "DeleteSession").Info("starting")
+	session := r.Context().Value("userSession").(*domain.Session)
+
+	if err := a.SessionService.DeleteSession(session.ID); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
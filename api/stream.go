@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/log"
+	"github.com/jacsmith21/lukabox/ext/render"
+	"github.com/jacsmith21/lukabox/ext/websocket"
+)
+
+// defaultHeartbeat how often idle streams are sent a heartbeat when StreamAPI.Heartbeat isn't set
+const defaultHeartbeat = 15 * time.Second
+
+// streamMargin how long before the request's deadline a stream ends, so it ends cleanly before the timeout
+// middleware cuts it off and the client resumes from its last event
+const streamMargin = 5 * time.Second
+
+// sseRetry how long SSE clients wait before reconnecting, in milliseconds
+const sseRetry = 1000
+
+// StreamAPI the services used to push events to live streams, Origins are the origins of the pages besides the
+// api's own which can open WebSockets, ie. https://dashboard.lukabox.ca
+type StreamAPI struct {
+	Broker    domain.EventBroker
+	Heartbeat time.Duration
+	Origins   []string
+}
+
+// Publish pushes an event to the user's live streams. A nil StreamAPI publishes nothing
+// so streams are optional for the apis which publish events
+func (a *StreamAPI) Publish(userID int, eventType string, data interface{}, now time.Time) {
+	if a == nil {
+		return
+	}
+	a.Broker.Publish(&domain.StreamEvent{UserID: userID, Type: eventType, Time: now, Data: data})
+}
+
+// Stream streams the user's events as Server-Sent Events, or over a WebSocket when the request is an upgrade.
+// Clients resume after the id in the Last-Event-ID header or the lastEventId parameter, which browsers need
+// for WebSockets. Streams end before the request times out and are expected to be resumed
+func (a *StreamAPI) Stream(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "Stream").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+
+	last := r.Header.Get("Last-Event-ID")
+	if last == "" {
+		last = r.URL.Query().Get("lastEventId")
+	}
+	var lastEventID int64
+	if last != "" {
+		var err error
+		if lastEventID, err = strconv.ParseInt(last, 10, 64); err != nil {
+			render.WithMessage("the last event id must be a number").BadRequest(w, r)
+			return
+		}
+	}
+
+	if websocket.IsUpgrade(r) {
+		a.webSocket(w, r, user, lastEventID)
+		return
+	}
+	a.serverSentEvents(w, r, user, lastEventID)
+}
+
+// serverSentEvents streams events as SSE, heartbeats are comments
+func (a *StreamAPI) serverSentEvents(w http.ResponseWriter, r *http.Request, user *domain.User, lastEventID int64) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		render.WithMessage("streaming isn't supported").InternalServerError(w, r)
+		return
+	}
+
+	subscription := a.Broker.Subscribe(user.ID, lastEventID)
+	defer subscription.Cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry)
+
+	send := func(event *domain.StreamEvent) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+		return err
+	}
+	heartbeat := func() error {
+		_, err := fmt.Fprint(w, ": heartbeat\n\n")
+		return err
+	}
+
+	a.pump(r.Context(), subscription, send, heartbeat, flusher.Flush)
+}
+
+// webSocket streams events as JSON text messages, heartbeats are pings
+func (a *StreamAPI) webSocket(w http.ResponseWriter, r *http.Request, user *domain.User, lastEventID int64) {
+	conn, err := websocket.Upgrade(w, r, a.Origins)
+	if err == websocket.ErrOrigin {
+		log.WithField("origin", r.Header.Get("Origin")).Info("websocket from an origin which isn't allowed")
+		render.WithError(err).Forbidden(w, r)
+		return
+	} else if err != nil {
+		render.WithError(err).BadRequest(w, r)
+		return
+	}
+
+	subscription := a.Broker.Subscribe(user.ID, lastEventID)
+	defer subscription.Cancel()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		<-conn.Done()
+		cancel()
+	}()
+
+	send := func(event *domain.StreamEvent) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		return conn.WriteText(data)
+	}
+
+	a.pump(ctx, subscription, send, conn.Ping, func() {})
+	conn.Close(websocket.GoingAway, "resume from the last event")
+}
+
+// pump sends the backlog then the events of a subscription with heartbeats while idle, until the context is
+// done, the subscriber is dropped or the stream is about to reach its deadline
+func (a *StreamAPI) pump(ctx context.Context, subscription *domain.StreamSubscription, send func(*domain.StreamEvent) error, heartbeat func() error, flush func()) {
+	for _, event := range subscription.Backlog {
+		if err := send(event); err != nil {
+			return
+		}
+	}
+	flush()
+
+	interval := a.Heartbeat
+	if interval <= 0 {
+		interval = defaultHeartbeat
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var end <-chan time.Time
+	if deadline, ok := ctx.Deadline(); ok {
+		remaining := time.Until(deadline)
+		margin := streamMargin
+		if margin > remaining/2 {
+			margin = remaining / 2
+		}
+		timer := time.NewTimer(remaining - margin)
+		defer timer.Stop()
+		end = timer.C
+	}
+
+	for {
+		select {
+		case event, ok := <-subscription.Events:
+			if !ok {
+				log.Info("stream fell behind")
+				return
+			}
+			if err := send(event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := heartbeat(); err != nil {
+				return
+			}
+		case <-end:
+			return
+		case <-ctx.Done():
+			return
+		}
+		flush()
+	}
+}
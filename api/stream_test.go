@@ -0,0 +1,183 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/stream"
+	"github.com/jacsmith21/lukabox/ext/websocket"
+	"github.com/jacsmith21/lukabox/mock"
+)
+
+func streamRouter(sAPI *StreamAPI, timeout time.Duration) *chi.Mux {
+	uAPI := UserAPI{}
+	uSvc := mock.UserService{}
+	uAPI.UserService = &uSvc
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id}, nil
+	}
+
+	r := chi.NewRouter()
+	r.Use(middleware.Timeout(timeout))
+	r.Route("/users/{userId}", func(r chi.Router) {
+		r.Use(uAPI.UserCtx)
+		r.Get("/stream", sAPI.Stream)
+	})
+	return r
+}
+
+func TestStream(t *testing.T) {
+	broker := &stream.Broker{}
+	sAPI := &StreamAPI{Broker: broker, Heartbeat: 50 * time.Millisecond}
+	now := time.Date(2018, time.March, 1, 8, 0, 0, 0, time.UTC)
+	sAPI.Publish(1, domain.BoxOpenedEvent, map[string]int{"compartment": 1}, now)
+	sAPI.Publish(1, domain.BoxClosedEvent, map[string]int{"compartment": 1}, now)
+	sAPI.Publish(2, domain.BoxOpenedEvent, map[string]int{"compartment": 2}, now)
+
+	server := httptest.NewServer(streamRouter(sAPI, 400*time.Millisecond))
+	defer server.Close()
+
+	runTests(t, streamRouter(sAPI, time.Second), []*test{
+		{"/users/1/stream", "GET", "", map[string]string{"Last-Event-ID": "one"}, http.StatusBadRequest, `{"message":"the last event id must be a number"}`},
+	})
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		sAPI.Publish(1, domain.DoseTakenEvent, map[string]int{"pillId": 1}, now)
+	}()
+
+	req, err := http.NewRequest("GET", server.URL+"/users/1/stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// the stream ends before the timeout middleware cuts it off
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	events := string(body)
+	for _, expected := range []string{
+		"retry: 1000\n\n",
+		"id: 2\nevent: box.closed\ndata: {\"id\":2,\"userId\":1,\"type\":\"box.closed\",\"time\":\"2018-03-01T08:00:00Z\",\"data\":{\"compartment\":1}}\n\n",
+		"id: 4\nevent: dose.taken\n",
+		": heartbeat\n\n",
+	} {
+		if !strings.Contains(events, expected) {
+			t.Errorf("expected the stream to contain %q, got:\n%s", expected, events)
+		}
+	}
+	for _, unexpected := range []string{"id: 1\n", "id: 3\n"} {
+		if strings.Contains(events, unexpected) {
+			t.Errorf("expected the stream not to contain %q, got:\n%s", unexpected, events)
+		}
+	}
+}
+
+func TestStreamWebSocket(t *testing.T) {
+	broker := &stream.Broker{}
+	sAPI := &StreamAPI{Broker: broker}
+	now := time.Date(2018, time.March, 1, 8, 0, 0, 0, time.UTC)
+	sAPI.Publish(1, domain.LowStockEvent, &domain.StockAlert{PillID: 1, Pill: "DoxyPoxy", Quantity: 4, RunOut: now}, now)
+
+	server := httptest.NewServer(streamRouter(sAPI, time.Second))
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	io.WriteString(conn, "GET /users/1/stream?lastEventId=0 HTTP/1.1\r\nHost: lukabox\r\nConnection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Key: "+key+"\r\n\r\n")
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("expected the upgrade to be accepted, got %d %s", resp.StatusCode, resp.Header.Get("Sec-WebSocket-Accept"))
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		sAPI.Publish(1, domain.DoseMissedEvent, &domain.MissedDose{PillID: 1, Pill: "DoxyPoxy", Scheduled: now}, now)
+	}()
+
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		t.Fatal(err)
+	}
+	if header[0] != 0x81 || header[1] > 126 {
+		t.Fatalf("expected an unmasked text frame, got % x", header)
+	}
+	n := int(header[1])
+	if n == 126 {
+		extended := make([]byte, 2)
+		if _, err := io.ReadFull(reader, extended); err != nil {
+			t.Fatal(err)
+		}
+		n = int(extended[0])<<8 | int(extended[1])
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		t.Fatal(err)
+	}
+
+	event := &domain.StreamEvent{}
+	if err := json.Unmarshal(payload, event); err != nil {
+		t.Fatal(err)
+	}
+	if event.ID != 2 || event.Type != domain.DoseMissedEvent || event.UserID != 1 {
+		t.Errorf("expected the missed dose event, got %s", payload)
+	}
+
+	if websocket.Accept(key) != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("expected the accept key from RFC 6455, got %s", websocket.Accept(key))
+	}
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	sAPI := &StreamAPI{Broker: &stream.Broker{}, Origins: []string{"https://dashboard.lukabox.ca"}}
+	r := streamRouter(sAPI, time.Second)
+
+	upgrade := func(origin string) map[string]string {
+		headers := map[string]string{"Connection": "Upgrade", "Upgrade": "websocket", "Sec-WebSocket-Version": "13", "Sec-WebSocket-Key": "dGhlIHNhbXBsZSBub25jZQ=="}
+		if origin != "" {
+			headers["Origin"] = origin
+		}
+		return headers
+	}
+
+	// the recorder can't be hijacked so upgrades which pass the origin check fail after it
+	tests := []*test{
+		{"/users/1/stream", "GET", "", upgrade("https://evil.example"), http.StatusForbidden, `{"message":"origin not allowed"}`},
+		{"/users/1/stream", "GET", "", upgrade("null"), http.StatusForbidden, `{"message":"origin not allowed"}`},
+		{"/users/1/stream", "GET", "", upgrade("https://dashboard.lukabox.ca"), http.StatusBadRequest, `{"message":"the connection can't be hijacked"}`},
+		{"/users/1/stream", "GET", "", upgrade(""), http.StatusBadRequest, `{"message":"the connection can't be hijacked"}`},
+	}
+	runTests(t, r, tests)
+}
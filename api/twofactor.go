@@ -0,0 +1,256 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/log"
+	"github.com/jacsmith21/lukabox/ext/render"
+	"github.com/jacsmith21/lukabox/ext/totp"
+	"github.com/jacsmith21/lukabox/stc"
+)
+
+const (
+	twoFactorChallengeTTL = 5 * time.Minute
+	twoFactorIssuer       = "Lukabox"
+	recoveryCodeCount     = 10
+)
+
+// login finishes a login for an authenticated user, users with two factor authentication get a challenge instead of a token
+func (a *AuthenticationAPI) login(w http.ResponseWriter, r *http.Request, user *domain.User) {
+	twoFactor, err := a.TwoFactorService.TwoFactor(user.ID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	if twoFactor == nil || !twoFactor.Enabled {
+		a.token(w, r, user, a.RequireTwoFactor[user.Role])
+		return
+	}
+
+	challenge, err := a.OneTimeTokenService.Issue(user.ID, domain.TwoFactorPurpose, twoFactorChallengeTTL)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	log.WithField("id", user.ID).Debug("two factor challenge issued")
+	if err := render.Instance(w, r, stc.NewTwoFactorChallengeResponse(&domain.TwoFactorChallenge{Challenge: challenge.Token})); err != nil {
+		render.WithError(err).BadRequest(w, r)
+		return
+	}
+}
+
+// AllowEnrolment allows tokens which can only be used to enrol in two factor authentication
+func (a *AuthenticationAPI) AllowEnrolment(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), "enrolment", true)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// TwoFactorLogin the second step of a login, exchanges a challenge and a code or recovery code for a token
+func (a *AuthenticationAPI) TwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "TwoFactorLogin").Info("starting")
+
+	data := &stc.TwoFactorLoginRequest{}
+	if err := render.Bind(r, data); err != nil {
+		render.WithError(err).BadRequest(w, r)
+		return
+	}
+
+	challenge, err := a.OneTimeTokenService.Redeem(data.Challenge, domain.TwoFactorPurpose)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+	if challenge == nil {
+		render.WithMessage("invalid challenge").BadRequest(w, r)
+		return
+	}
+
+	user, err := a.UserService.UserByID(challenge.UserID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	twoFactor, err := a.TwoFactorService.TwoFactor(user.ID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+	if twoFactor == nil || !twoFactor.Enabled {
+		render.WithMessage("two factor authentication is not enabled").BadRequest(w, r)
+		return
+	}
+
+	redeemed, err := a.redeemTwoFactor(twoFactor, data.Code, data.RecoveryCode)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+	if !redeemed {
+		if err := a.fail(user.Email); err != nil {
+			render.WithError(err).InternalServerError(w, r)
+			return
+		}
+		render.WithMessage("invalid code").Forbidden(w, r)
+		return
+	}
+
+	a.token(w, r, user, false)
+}
+
+// redeemTwoFactor checks a code or recovery code, codes can't be reused and recovery codes are removed once used.
+// The store checks and uses up the code under its lock so two requests can't redeem the same one
+func (a *AuthenticationAPI) redeemTwoFactor(twoFactor *domain.TwoFactor, code string, recoveryCode string) (bool, error) {
+	if code != "" {
+		step, ok := totp.Validate(twoFactor.Secret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		return a.TwoFactorService.RedeemStep(twoFactor.UserID, step)
+	}
+	return a.TwoFactorService.RedeemRecoveryCode(twoFactor.UserID, totp.Hash(recoveryCode))
+}
+
+// EnrolTwoFactor starts two factor enrolment by creating a secret for the user's authenticator app
+func (a *AuthenticationAPI) EnrolTwoFactor(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "EnrolTwoFactor").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+
+	twoFactor, err := a.TwoFactorService.TwoFactor(user.ID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+	if twoFactor != nil && twoFactor.Enabled {
+		render.WithMessage("two factor authentication already enabled").Conflict(w, r)
+		return
+	}
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	twoFactor = &domain.TwoFactor{UserID: user.ID, Secret: secret}
+	if err := a.TwoFactorService.SaveTwoFactor(twoFactor); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	enrolment := &stc.TwoFactorEnrolmentResponse{Secret: secret, URI: totp.URI(twoFactorIssuer, user.Email, secret)}
+	if err := render.Instance(w, r, enrolment); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
+
+// ConfirmTwoFactor enables two factor authentication once the user has entered a code from their authenticator app
+func (a *AuthenticationAPI) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "ConfirmTwoFactor").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+
+	data := &stc.TwoFactorCodeRequest{}
+	if err := render.Bind(r, data); err != nil {
+		render.WithError(err).BadRequest(w, r)
+		return
+	}
+
+	twoFactor, err := a.TwoFactorService.TwoFactor(user.ID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+	if twoFactor == nil {
+		render.WithMessage("two factor enrolment has not been started").BadRequest(w, r)
+		return
+	}
+	if twoFactor.Enabled {
+		render.WithMessage("two factor authentication already enabled").Conflict(w, r)
+		return
+	}
+
+	step, ok := totp.Validate(twoFactor.Secret, data.Code, time.Now())
+	if !ok {
+		render.WithMessage("invalid code").BadRequest(w, r)
+		return
+	}
+
+	codes, err := totp.RecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	twoFactor.Enabled = true
+	twoFactor.LastStep = step
+	twoFactor.RecoveryCodes = []string{}
+	for _, c := range codes {
+		twoFactor.RecoveryCodes = append(twoFactor.RecoveryCodes, totp.Hash(c))
+	}
+
+	if err := a.TwoFactorService.SaveTwoFactor(twoFactor); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	if err := render.Instance(w, r, &stc.RecoveryCodesResponse{RecoveryCodes: codes}); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
+
+// DisableTwoFactor disables two factor authentication unless the user's role requires it, a code or recovery code
+// is required so a stolen token can't turn off the second factor
+func (a *AuthenticationAPI) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "DisableTwoFactor").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+
+	if a.RequireTwoFactor[user.Role] {
+		render.WithMessage("two factor authentication is required for your role").Forbidden(w, r)
+		return
+	}
+
+	data := &stc.TwoFactorDisableRequest{}
+	if err := render.Bind(r, data); err != nil {
+		render.WithError(err).BadRequest(w, r)
+		return
+	}
+
+	twoFactor, err := a.TwoFactorService.TwoFactor(user.ID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+	if twoFactor == nil {
+		render.WithMessage("two factor authentication is not enabled").BadRequest(w, r)
+		return
+	}
+
+	redeemed, err := a.redeemTwoFactor(twoFactor, data.Code, data.RecoveryCode)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+	if !redeemed {
+		if err := a.fail(user.Email); err != nil {
+			render.WithError(err).InternalServerError(w, r)
+			return
+		}
+		render.WithMessage("invalid code").Forbidden(w, r)
+		return
+	}
+
+	if err := a.TwoFactorService.DeleteTwoFactor(user.ID); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
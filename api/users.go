@@ -4,9 +4,9 @@ import (
 	"context"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi"
-	"github.com/go-playground/validator"
 	"github.com/jacsmith21/lukabox/domain"
 	"github.com/jacsmith21/lukabox/ext/log"
 	"github.com/jacsmith21/lukabox/ext/render"
@@ -15,9 +15,14 @@ import (
 
 //UserAPI the services used
 type UserAPI struct {
-	UserService domain.UserService
+	UserService         domain.UserService
+	OneTimeTokenService domain.OneTimeTokenService
+	Mailer              domain.Mailer
+	SessionService      domain.SessionService
 }
 
+const verificationTTL = 24 * time.Hour
+
 // UserCtx is used to create a user context by id
 func (a *UserAPI) UserCtx(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -54,7 +59,8 @@ func (a *UserAPI) UserCtx(next http.Handler) http.Handler {
 	})
 }
 
-// UserRequestCtx a user request context generator
+// UserRequestCtx a user request context generator, the whole user is validated here so signups are rejected
+// before the availability of the email is checked
 func (a *UserAPI) UserRequestCtx(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		log.WithField("method", "UserRequestCtx").Info("starting")
@@ -68,6 +74,11 @@ func (a *UserAPI) UserRequestCtx(next http.Handler) http.Handler {
 		}
 
 		user := userRequest.User
+		if user == nil {
+			render.WithMessage("a user must be supplied").BadRequest(w, r)
+			return
+		}
+
 		log.WithField("user", user).Debug("user from user request")
 
 		ctx := context.WithValue(r.Context(), "user", user)
@@ -79,6 +90,9 @@ func (a *UserAPI) UserRequestCtx(next http.Handler) http.Handler {
 func (a *UserAPI) UserByID(w http.ResponseWriter, r *http.Request) {
 	log.WithField("method", "UserByID").Info("starting")
 	user := r.Context().Value("user").(*domain.User)
+	if notModified(w, r, etag(user.Version)) {
+		return
+	}
 	if err := render.Instance(w, r, stc.NewUserResponse(user)); err != nil {
 		log.WithError(err).Error("unable to render user response")
 		render.WithError(err).InternalServerError(w, r)
@@ -107,16 +121,16 @@ func (a *UserAPI) Users(w http.ResponseWriter, r *http.Request) {
 func (a *UserAPI) CreateUser(w http.ResponseWriter, r *http.Request) {
 	log.WithField("method", "CreateUser").Info("starting")
 	user := r.Context().Value("user").(*domain.User)
-
-	validate := validator.New()
-	if err := validate.Struct(user); err != nil {
-		log.WithError(err).Debug("user wasn't validated")
-		render.WithError(err).BadRequest(w, r)
+	user.Verified = false
+	user.Role = domain.PatientRole
+	if err := a.UserService.InsertUser(user); err != nil {
+		log.WithError(err).Error("error inserting user")
+		render.WithError(err).InternalServerError(w, r)
 		return
 	}
 
-	if err := a.UserService.InsertUser(user); err != nil {
-		log.WithError(err).Error("error inserting user")
+	if err := a.sendVerification(user); err != nil {
+		log.WithError(err).Error("error issuing verification token")
 		render.WithError(err).InternalServerError(w, r)
 		return
 	}
@@ -124,18 +138,137 @@ func (a *UserAPI) CreateUser(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusCreated)
 }
 
-// UpdateUser updates the user
+// sendVerification issues a verification token for the user's email and emails it to them
+func (a *UserAPI) sendVerification(user *domain.User) error {
+	token, err := a.OneTimeTokenService.Issue(user.ID, domain.VerificationPurpose, verificationTTL)
+	if err != nil {
+		return err
+	}
+
+	verification := &domain.Email{
+		To:      user.Email,
+		Subject: "Verify your Lukabox email",
+		Body:    "Use this token to verify your email: " + token.Token,
+	}
+	if err := a.Mailer.Send(verification); err != nil {
+		log.WithError(err).Error("error sending verification email")
+	}
+	return nil
+}
+
+// emailTaken whether another user already has the email
+func (a *UserAPI) emailTaken(user *domain.User, email string) bool {
+	other, err := a.UserService.UserByEmail(email)
+	return err == nil && other != nil && other.ID != user.ID
+}
+
+// UpdateUser updates the user, users can't change their own role or verify their own email. Changing the password
+// invalidates every token and session of the user like a reset does, changing the email has to be verified again
 func (a *UserAPI) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	user := r.Context().Value("user").(*domain.User)
 
-	data := &stc.UserRequest{User: user}
+	if !preconditionMet(r, etag(user.Version)) {
+		render.WithMessage("the user has been updated since it was read").PreconditionFailed(w, r)
+		return
+	}
+
+	// the request is bound onto a copy so the user in the context is left as it was read
+	updated := *user
+	data := &stc.UserRequest{User: &updated, CurrentPassword: user.Password}
 	if err := render.Bind(r, data); err != nil {
 		render.WithError(err).BadRequest(w, r)
 		return
 	}
 
-	user = data.User
-	if err := a.UserService.UpdateUser(user.ID, user); err != nil {
+	updated.ID = user.ID
+	updated.Role = user.Role
+	updated.Verified = user.Verified
+	emailChanged := updated.Email != user.Email
+	if emailChanged {
+		if a.emailTaken(user, updated.Email) {
+			render.WithMessage("the email is already in use").Conflict(w, r)
+			return
+		}
+		updated.Verified = false
+	}
+	changed := updated.Password != user.Password
+	if changed {
+		updated.TokenVersion++
+	}
+	if err := a.UserService.UpdateUser(user.ID, &updated); err == domain.ErrStale {
+		render.WithMessage("the user has been updated since it was read").PreconditionFailed(w, r)
+		return
+	} else if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	if changed {
+		if err := a.SessionService.DeleteSessions(user.ID); err != nil {
+			render.WithError(err).InternalServerError(w, r)
+			return
+		}
+	}
+	if emailChanged {
+		if err := a.sendVerification(&updated); err != nil {
+			render.WithError(err).InternalServerError(w, r)
+			return
+		}
+	}
+	w.Header().Set("ETag", etag(updated.Version))
+}
+
+// PatchUser applies a merge patch or JSON patch to a user, the patched user is validated like a full update and a
+// patched email has to be verified again
+func (a *UserAPI) PatchUser(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "PatchUser").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+
+	if !preconditionMet(r, etag(user.Version)) {
+		render.WithMessage("the user has been updated since it was read").PreconditionFailed(w, r)
+		return
+	}
+
+	doc, ok := patchDocument(w, r, stc.NewUserResponse(user), userFields, []string{"id"})
+	if !ok {
+		return
+	}
+
+	// the patched user is bound from scratch so removed members are cleared, only the members which aren't
+	// represented are carried over
+	patched := domain.User{TokenVersion: user.TokenVersion, Version: user.Version}
+	data := &stc.UserRequest{User: &patched, CurrentPassword: user.Password}
+	if err := render.Bind(patchedRequest(r, doc), data); err != nil {
+		render.WithError(err).BadRequest(w, r)
+		return
+	}
+
+	emailChanged := patched.Email != user.Email
+	if emailChanged {
+		if a.emailTaken(user, patched.Email) {
+			render.WithMessage("the email is already in use").Conflict(w, r)
+			return
+		}
+		patched.Verified = false
+	}
+
+	if err := a.UserService.UpdateUser(patched.ID, &patched); err == domain.ErrStale {
+		render.WithMessage("the user has been updated since it was read").PreconditionFailed(w, r)
+		return
+	} else if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	if emailChanged {
+		if err := a.sendVerification(&patched); err != nil {
+			render.WithError(err).InternalServerError(w, r)
+			return
+		}
+	}
+
+	w.Header().Set("ETag", etag(patched.Version))
+	if err := render.Instance(w, r, stc.NewUserResponse(&patched)); err != nil {
 		render.WithError(err).InternalServerError(w, r)
 		return
 	}
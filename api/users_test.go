@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/jacsmith21/lukabox/domain"
@@ -88,8 +89,9 @@ func TestUserRequestCtx(t *testing.T) {
 	uAPI.UserService = &uSvc
 
 	tests := []*test{
-		{"/users", "PUT", `{"email":"jacob.smith@unb.ca","password":"password","firstName":"Jacob","lastName":"Smith"}`, map[string]string{"Content-Type": "application/json"}, http.StatusOK, "This is a test!"},
-		{"/users", "PUT", `{"whatisthis":"jacob.smith@unb.ca","password":"password","firstName":"Jacob","lastName":"Smith"}`, map[string]string{"Content-Type": "application/json"}, http.StatusOK, "This is a test!"},
+		{"/users", "PUT", `{"email":"jacob.smith@unb.ca","password":"password1","firstName":"Jacob","lastName":"Smith"}`, map[string]string{"Content-Type": "application/json"}, http.StatusOK, "This is a test!"},
+		{"/users", "PUT", `{"whatisthis":"jacob.smith@unb.ca","password":"password","firstName":"Jacob","lastName":"Smith"}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"email is required","fields":{"email":"email is required"}}`},
+		{"/users", "PUT", `{"email":"jacob.smith","password":"password","firstName":"Jacob","lastName":"Smith"}`, map[string]string{"Content-Type": "application/json", "Accept-Language": "fr-CA,fr;q=0.9,en;q=0.8"}, http.StatusBadRequest, `{"message":"email doit être une adresse courriel valide","fields":{"email":"email doit être une adresse courriel valide"}}`},
 	}
 
 	r := chi.NewRouter()
@@ -109,7 +111,7 @@ func TestUserByID(t *testing.T) {
 	uAPI.UserService = &uSvc
 
 	tests := []*test{
-		{"/users/1", "GET", "", nil, http.StatusOK, `{"id":1,"password":"password","email":"jacob.smith@unb.ca","firstName":"Jacob","lastName":"Smith","archived":false}`},
+		{"/users/1", "GET", "", nil, http.StatusOK, `{"id":1,"password":"password","email":"jacob.smith@unb.ca","firstName":"Jacob","lastName":"Smith","archived":false,"verified":false,"role":"","timezone":"","travelTimezone":"","travelMode":""}`},
 		{"/users/3", "GET", "", nil, http.StatusInternalServerError, `{"message":"test error"}`},
 		{"/users/4", "GET", "", nil, http.StatusNotFound, `{"message":"user not found"}`},
 	}
@@ -138,7 +140,7 @@ func TestUsers(t *testing.T) {
 	uAPI.UserService = &uSvc
 
 	tests := []*test{
-		{"/users", "GET", "", nil, http.StatusOK, `[{"id":1,"password":"password","email":"jacob.smith@unb.ca","firstName":"Jacob","lastName":"Smith","archived":false}]`},
+		{"/users", "GET", "", nil, http.StatusOK, `[{"id":1,"password":"password","email":"jacob.smith@unb.ca","firstName":"Jacob","lastName":"Smith","archived":false,"verified":false,"role":"","timezone":"","travelTimezone":"","travelMode":""}]`},
 		{"/users", "GET", "", nil, http.StatusOK, `[]`},
 		{"/users", "GET", "", nil, http.StatusInternalServerError, `{"message":"test error"}`},
 	}
@@ -169,9 +171,10 @@ func TestCreateUser(t *testing.T) {
 	uAPI.UserService = &uSvc
 
 	tests := []*test{
-		{"/users", "PUT", `{"email":"jacob.smith@unb.ca","password":"password","firstName":"Jacob","lastName":"Smith"}`, map[string]string{"Content-Type": "application/json"}, http.StatusInternalServerError, `{"message":"test error"}`},
-		{"/users", "PUT", `{"email":"jacob.smith@unb.ca","password":"password","firstName":"Jacob","lastName":"Smith"}`, map[string]string{"Content-Type": "application/json"}, http.StatusCreated, ""},
-		{"/users", "PUT", `{"email":"jacob.smith@unb.ca","password":"password","firstame":"Jacob","lastName":"Smith"}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"Key: 'User.FirstName' Error:Field validation for 'FirstName' failed on the 'required' tag"}`},
+		{"/users", "PUT", `{"email":"jacob.smith@unb.ca","password":"password1","firstName":"Jacob","lastName":"Smith"}`, map[string]string{"Content-Type": "application/json"}, http.StatusInternalServerError, `{"message":"test error"}`},
+		{"/users", "PUT", `{"email":"jacob.smith@unb.ca","password":"password1","firstName":"Jacob","lastName":"Smith"}`, map[string]string{"Content-Type": "application/json"}, http.StatusCreated, ""},
+		{"/users", "PUT", `{"email":"jacob.smith@unb.ca","password":"password","firstName":"Jacob","lastName":"Smith"}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"password must be at least 8 characters with a letter and a number","fields":{"password":"password must be at least 8 characters with a letter and a number"}}`},
+		{"/users", "PUT", `{"email":"jacob.smith@unb.ca","password":"password","firstame":"Jacob","lastName":"Smith"}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"firstName is required","fields":{"firstName":"firstName is required"}}`},
 	}
 
 	count := 0
@@ -183,6 +186,20 @@ func TestCreateUser(t *testing.T) {
 		return nil
 	}
 
+	tSvc := mock.OneTimeTokenService{}
+	tSvc.IssueFn = func(userID int, purpose domain.TokenPurpose, ttl time.Duration) (*domain.OneTimeToken, error) {
+		return &domain.OneTimeToken{Token: "verify", UserID: userID, Purpose: purpose}, nil
+	}
+	uAPI.OneTimeTokenService = &tSvc
+
+	sent := []*domain.Email{}
+	mailer := mock.Mailer{}
+	mailer.SendFn = func(email *domain.Email) error {
+		sent = append(sent, email)
+		return nil
+	}
+	uAPI.Mailer = &mailer
+
 	r := chi.NewRouter()
 	r.Route("/users", func(r chi.Router) {
 		r.Use(uAPI.UserRequestCtx)
@@ -190,6 +207,10 @@ func TestCreateUser(t *testing.T) {
 	})
 
 	runTests(t, r, tests)
+
+	if len(sent) != 1 || sent[0].To != "jacob.smith@unb.ca" {
+		t.Errorf("expected a single verification email to jacob.smith@unb.ca, got %v", sent)
+	}
 }
 
 func TestUpdateUser(t *testing.T) {
@@ -201,18 +222,62 @@ func TestUpdateUser(t *testing.T) {
 		{"/users/1", "POST", `{"ID":1,"email":"jacob.smith@unb.ca","password":"password","firstName":"Jacob","lastName":"Smith","Archived":false}`, map[string]string{"Content-Type": "application/json"}, http.StatusOK, ""},
 		{"/users/1", "POST", `{"ID":"1","email":"jacob.smith@unb.ca","password":"password","firstName":"Jacob","lastName":"Smith","Archived":false}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"json: cannot unmarshal string into Go struct field UserRequest.id of type int"}`},
 		{"/users/1", "POST", `{"ID":1,"email":"jacob.smith@unb.ca","password":"password","firstName":"Jacob","lastName":"Smith","Archived":"false"}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"json: cannot unmarshal string into Go struct field UserRequest.archived of type bool"}`},
+		{"/users/1", "POST", `{"id":1,"email":"jacob.smith@unb.ca","password":"password","firstName":"Jacob","lastName":"Smith","verified":true,"role":"admin"}`, map[string]string{"Content-Type": "application/json"}, http.StatusOK, ""},
+		{"/users/1", "POST", `{"id":1,"email":"jacob.smith@unb.ca","password":"password2","firstName":"Jacob","lastName":"Smith"}`, map[string]string{"Content-Type": "application/json"}, http.StatusOK, ""},
+		{"/users/1", "POST", `{"id":1,"email":"jacob.smith@unb.ca","password":"guess","firstName":"Jacob","lastName":"Smith"}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, `{"message":"password must be at least 8 characters with a letter and a number","fields":{"password":"password must be at least 8 characters with a letter and a number"}}`},
+		{"/users/1", "POST", `{"id":1,"email":"jake.smith@unb.ca","password":"password","firstName":"Jacob","lastName":"Smith"}`, map[string]string{"Content-Type": "application/json"}, http.StatusOK, ""},
+		{"/users/1", "POST", `{"id":1,"email":"taken@unb.ca","password":"password","firstName":"Jacob","lastName":"Smith"}`, map[string]string{"Content-Type": "application/json"}, http.StatusConflict, `{"message":"the email is already in use"}`},
 	}
 
+	var read *domain.User
 	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
-		return &domain.User{ID: id, Email: "jacob.smith@unb.ca", Password: "password", FirstName: "Jacob", LastName: "Smith", Archived: false}, nil
+		read = &domain.User{ID: id, Email: "jacob.smith@unb.ca", Password: "password", FirstName: "Jacob", LastName: "Smith", Archived: false}
+		return read, nil
+	}
+	uSvc.UserByEmailFn = func(email string) (*domain.User, error) {
+		if email == "taken@unb.ca" {
+			return &domain.User{ID: 2, Email: email}, nil
+		}
+		return nil, errors.New("user not found")
 	}
 
 	uSvc.UpdateUserFn = func(id int, user *domain.User) error {
 		if id != 1 {
 			return errors.New("expected id to be 1")
 		}
+		if user == read || read.Password != "password" {
+			return errors.New("expected a copy of the user to be updated")
+		}
+		if user.Verified || user.Role != "" {
+			return errors.New("expected the user not to verify themselves or change their role")
+		}
+		if (user.Password != "password") != (user.TokenVersion == 1) {
+			return errors.New("expected the tokens to be invalidated only when the password changes")
+		}
+		return nil
+	}
+
+	revoked := 0
+	sSvc := mock.SessionService{}
+	sSvc.DeleteSessionsFn = func(userID int) error {
+		revoked++
+		return nil
+	}
+	uAPI.SessionService = &sSvc
+
+	tSvc := mock.OneTimeTokenService{}
+	tSvc.IssueFn = func(userID int, purpose domain.TokenPurpose, ttl time.Duration) (*domain.OneTimeToken, error) {
+		return &domain.OneTimeToken{Token: "verify", UserID: userID, Purpose: purpose}, nil
+	}
+	uAPI.OneTimeTokenService = &tSvc
+
+	sent := []*domain.Email{}
+	mailer := mock.Mailer{}
+	mailer.SendFn = func(email *domain.Email) error {
+		sent = append(sent, email)
 		return nil
 	}
+	uAPI.Mailer = &mailer
 
 	r := chi.NewRouter()
 	r.Route("/users/{userId}", func(r chi.Router) {
@@ -221,4 +286,11 @@ func TestUpdateUser(t *testing.T) {
 	})
 
 	runTests(t, r, tests)
+
+	if revoked != 1 {
+		t.Errorf("expected the sessions to be revoked once when the password changed, got %d", revoked)
+	}
+	if len(sent) != 1 || sent[0].To != "jake.smith@unb.ca" {
+		t.Errorf("expected a single verification email to the new email, got %v", sent)
+	}
 }
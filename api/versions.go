@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/render"
+	"github.com/jacsmith21/lukabox/stc"
+)
+
+// The versions of the api, v2 renames the ids of pills to id and userId
+const (
+	V1 = 1
+	V2 = 2
+)
+
+// Versioned adds the version of the api the routes are mounted under to the context
+func Versioned(version int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), "version", version)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// version the version of the api of the request, V1 when the routes aren't versioned
+func version(r *http.Request) int {
+	if v, ok := r.Context().Value("version").(int); ok {
+		return v
+	}
+	return V1
+}
+
+// Deprecation a deprecated version of the api mounted under Prefix. Its responses have Deprecation and
+// Sunset headers and link to the same route under Successor
+type Deprecation struct {
+	Prefix    string
+	Successor string
+	Date      time.Time
+	Sunset    time.Time
+}
+
+// Handler adds the deprecation headers to the responses
+func (d *Deprecation) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", d.Date.Unix()))
+		w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+		w.Header().Add("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, d.Successor, strings.TrimPrefix(r.URL.Path, d.Prefix)))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bindPill binds the pill request in the representation of the version of the request
+func bindPill(r *http.Request) (*stc.PillRequest, error) {
+	if version(r) >= V2 {
+		data := &stc.PillV2Request{}
+		if err := render.Bind(r, data); err != nil {
+			return nil, err
+		}
+		return data.PillRequest, nil
+	}
+
+	data := &stc.PillRequest{}
+	if err := render.Bind(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// renderPill renders a pill in the representation of the version of the request
+func renderPill(w http.ResponseWriter, r *http.Request, pill *domain.Pill, warnings []*domain.InteractionWarning) error {
+	if version(r) >= V2 {
+		return render.Instance(w, r, stc.NewPillV2Response(pill, warnings))
+	}
+	return render.Instance(w, r, stc.NewPillInteractionsResponse(pill, warnings))
+}
+
+// renderPills renders a list of pills in the representation of the version of the request
+func renderPills(w http.ResponseWriter, r *http.Request, pills []*domain.Pill) error {
+	if version(r) >= V2 {
+		return render.List(w, r, stc.NewPillV2ListResponse(pills))
+	}
+	return render.List(w, r, stc.NewPillListResponse(pills))
+}
+
+// pillRepresentation a pill in the representation of the version of the request without its warnings
+func pillRepresentation(r *http.Request, pill *domain.Pill) interface{} {
+	if version(r) >= V2 {
+		return stc.NewPillV2Response(pill, nil)
+	}
+	return stc.NewPillResponse(pill)
+}
+
+// pillIDFields the members identifying a pill in the representation of the version of the request
+func pillIDFields(r *http.Request) []string {
+	if version(r) >= V2 {
+		return []string{"id", "userId"}
+	}
+	return []string{"pillId", "id"}
+}
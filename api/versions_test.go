@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/mock"
+)
+
+func TestVersions(t *testing.T) {
+	uAPI := UserAPI{}
+	uSvc := mock.UserService{}
+	uAPI.UserService = &uSvc
+	pAPI := PillAPI{}
+	pSvc := mock.PillService{}
+	pAPI.PillService = &pSvc
+	iSvc := mock.InteractionService{}
+	pAPI.InteractionService = &iSvc
+
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id}, nil
+	}
+	pSvc.PillsFn = func(id int) ([]*domain.Pill, error) {
+		return []*domain.Pill{{ID: 1, UserID: id, Name: "DoxyPoxy", DaysOfWeek: []int{1}, TimesOfDay: []domain.TimeOfDay{{Hour: 23}}}}, nil
+	}
+	iSvc.InteractionsFn = func(pill string, other string) ([]*domain.Interaction, error) {
+		return []*domain.Interaction{}, nil
+	}
+	pSvc.CreatePillFn = func(pill *domain.Pill) error {
+		pill.ID = 2
+		return nil
+	}
+
+	routes := func(r chi.Router) {
+		r.Route("/users/{userId}/pills", func(r chi.Router) {
+			r.Use(uAPI.UserCtx)
+			r.Get("/", pAPI.Pills)
+			r.Post("/", pAPI.CreatePill)
+		})
+	}
+	deprecation := &Deprecation{Prefix: "/v1", Successor: "/v2", Date: time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC), Sunset: time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC)}
+
+	r := chi.NewRouter()
+	r.Route("/v1", func(r chi.Router) {
+		r.Use(Versioned(V1))
+		r.Use(deprecation.Handler)
+		routes(r)
+	})
+	r.Route("/v2", func(r chi.Router) {
+		r.Use(Versioned(V2))
+		routes(r)
+	})
+
+	jsonHeader := map[string]string{"Content-Type": "application/json"}
+	tests := []*test{
+		{"/v1/users/1/pills", "GET", "", nil, http.StatusOK, `[{"pillId":1,"id":1,"name":"DoxyPoxy","medicationId":"","generic":"","daysOfWeek":[1],"timesOfDay":["23:00"],"schedule":null,"archived":false,"strength":0,"unit":"","form":"","route":"","food":"","instructions":"","prescriber":"","startDate":null,"endDate":null,"quantity":0,"quantityPerDose":0,"alertDays":0}]`},
+		{"/v2/users/1/pills", "GET", "", nil, http.StatusOK, `[{"id":1,"userId":1,"name":"DoxyPoxy","medicationId":"","generic":"","daysOfWeek":[1],"timesOfDay":["23:00"],"schedule":null,"archived":false,"strength":0,"unit":"","form":"","route":"","food":"","instructions":"","prescriber":"","startDate":null,"endDate":null,"quantity":0,"quantityPerDose":0,"alertDays":0}]`},
+		{"/v2/users/1/pills", "POST", `{"id":7,"userId":1,"name":"Calcium"}`, jsonHeader, http.StatusCreated, `{"id":2,"userId":1,"name":"Calcium","medicationId":"","generic":"","daysOfWeek":null,"timesOfDay":null,"schedule":null,"archived":false,"strength":0,"unit":"","form":"","route":"","food":"","instructions":"","prescriber":"","startDate":null,"endDate":null,"quantity":0,"quantityPerDose":0,"alertDays":0}`},
+		{"/v2/users/1/pills", "POST", `{"acknowledgeInteractions":true}`, jsonHeader, http.StatusBadRequest, `{"message":"a pill must be supplied"}`},
+	}
+	runTests(t, r, tests)
+
+	for _, url := range []string{"/v1/users/1/pills", "/v2/users/1/pills"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		deprecated := url == "/v1/users/1/pills"
+		if got := w.Header().Get("Deprecation") != ""; got != deprecated {
+			t.Errorf("expected %s to be deprecated %v, got the headers %v", url, deprecated, w.Header())
+		}
+		if deprecated && (w.Header().Get("Deprecation") != "@1792368000" || w.Header().Get("Sunset") != "Mon, 19 Apr 2027 00:00:00 GMT" || w.Header().Get("Link") != `</v2/users/1/pills>; rel="successor-version"`) {
+			t.Errorf("unexpected deprecation headers %v", w.Header())
+		}
+	}
+}
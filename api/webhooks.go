@@ -0,0 +1,301 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/log"
+	"github.com/jacsmith21/lukabox/ext/render"
+	"github.com/jacsmith21/lukabox/stc"
+)
+
+// WebhookAPI the services used to manage webhooks and deliver their events
+type WebhookAPI struct {
+	WebhookService domain.WebhookService
+	Sender         domain.WebhookSender
+}
+
+// Publish queues an event for every webhook of the user subscribed to it, the deliveries are sent by
+// DeliverDue. A nil WebhookAPI publishes nothing so webhooks are optional for the apis which publish events
+func (a *WebhookAPI) Publish(userID int, eventType string, data interface{}, now time.Time) error {
+	if a == nil {
+		return nil
+	}
+
+	webhooks, err := a.WebhookService.Webhooks(userID)
+	if err != nil {
+		return err
+	}
+
+	event := &domain.WebhookEvent{ID: fmt.Sprintf("evt_%d_%d", userID, now.UnixNano()), Type: eventType, UserID: userID, Created: now, Data: data}
+	for _, webhook := range webhooks {
+		if !webhook.Subscribed(eventType) {
+			continue
+		}
+		if _, err := a.queue(webhook, event, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queue creates a pending delivery of an event to a webhook, due at next
+func (a *WebhookAPI) queue(webhook *domain.Webhook, event *domain.WebhookEvent, next time.Time) (*domain.WebhookDelivery, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := &domain.WebhookDelivery{
+		WebhookID:   webhook.ID,
+		EventID:     event.ID,
+		EventType:   event.Type,
+		Payload:     payload,
+		Status:      domain.PendingDelivery,
+		Created:     event.Created,
+		NextAttempt: next,
+	}
+	if err := a.WebhookService.InsertDelivery(delivery); err != nil {
+		return nil, err
+	}
+	log.WithField("webhookId", webhook.ID).WithField("event", event.Type).Debug("webhook delivery queued")
+	return delivery, nil
+}
+
+// DeliverDue attempts the deliveries which are due, failed deliveries are retried with exponential backoff
+// until they have used up their attempts. Deliveries to deleted webhooks are dead
+func (a *WebhookAPI) DeliverDue(now time.Time) error {
+	deliveries, err := a.WebhookService.DueDeliveries(now)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		webhook, err := a.WebhookService.Webhook(delivery.WebhookID)
+		if err != nil {
+			return err
+		}
+		if webhook == nil {
+			delivery.Status = domain.DeadDelivery
+			delivery.Error = "the webhook was deleted"
+			if err := a.WebhookService.UpdateDelivery(delivery); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := a.attempt(webhook, delivery, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attempt sends a delivery and records the outcome
+func (a *WebhookAPI) attempt(webhook *domain.Webhook, delivery *domain.WebhookDelivery, now time.Time) error {
+	status, err := a.Sender.Send(webhook, delivery)
+	if err != nil {
+		delivery.Failed(now, status, err)
+		log.WithError(err).WithField("deliveryId", delivery.ID).WithField("attempts", delivery.Attempts).Info("webhook delivery failed")
+	} else {
+		delivery.Succeeded(now, status)
+	}
+	return a.WebhookService.UpdateDelivery(delivery)
+}
+
+// WebhookCtx adds the webhook in the url to the context, webhooks of other users aren't found
+func (a *WebhookAPI) WebhookCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.WithField("method", "WebhookCtx").Info("starting")
+		user := r.Context().Value("user").(*domain.User)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "webhookId"))
+		if err != nil {
+			render.WithMessage("unable to parse parameter webhook id").BadRequest(w, r)
+			return
+		}
+
+		webhook, err := a.WebhookService.Webhook(id)
+		if err != nil {
+			render.WithError(err).InternalServerError(w, r)
+			return
+		}
+		if webhook == nil || webhook.UserID != user.ID {
+			render.WithMessage("webhook not found").NotFound(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "webhook", webhook)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Webhooks lists the user's webhooks
+func (a *WebhookAPI) Webhooks(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "Webhooks").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+
+	webhooks, err := a.WebhookService.Webhooks(user.ID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	if err := render.List(w, r, stc.NewWebhookListResponse(webhooks)); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
+
+// CreateWebhook subscribes a url to events, the response is the only time the signing secret is shown
+func (a *WebhookAPI) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "CreateWebhook").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+
+	data := &stc.WebhookRequest{}
+	if err := render.Bind(r, data); err != nil {
+		render.WithError(err).BadRequest(w, r)
+		return
+	}
+
+	webhook := data.Webhook
+	webhook.ID = 0
+	webhook.UserID = user.ID
+	if err := a.WebhookService.InsertWebhook(webhook); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	if err := render.Instance(w, r, stc.NewWebhookSecretResponse(webhook)); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
+
+// Webhook returns a webhook
+func (a *WebhookAPI) Webhook(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "Webhook").Info("starting")
+	webhook := r.Context().Value("webhook").(*domain.Webhook)
+
+	if err := render.Instance(w, r, stc.NewWebhookResponse(webhook)); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
+
+// DeleteWebhook unsubscribes a webhook, its pending deliveries won't be sent
+func (a *WebhookAPI) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "DeleteWebhook").Info("starting")
+	webhook := r.Context().Value("webhook").(*domain.Webhook)
+
+	if err := a.WebhookService.DeleteWebhook(webhook.ID); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Deliveries the delivery log of a webhook, newest first. ?status=dead lists the dead letters
+func (a *WebhookAPI) Deliveries(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "Deliveries").Info("starting")
+	webhook := r.Context().Value("webhook").(*domain.Webhook)
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", domain.PendingDelivery, domain.DeliveredDelivery, domain.DeadDelivery:
+	default:
+		render.WithMessage("status must be one of pending, delivered or dead").BadRequest(w, r)
+		return
+	}
+
+	deliveries, err := a.WebhookService.Deliveries(webhook.ID)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	filtered := []*domain.WebhookDelivery{}
+	for _, d := range deliveries {
+		if status == "" || d.Status == status {
+			filtered = append(filtered, d)
+		}
+	}
+
+	if err := render.List(w, r, stc.NewWebhookDeliveryListResponse(filtered)); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
+
+// SendTestEvent sends a test event to a webhook straight away and returns the delivery,
+// a failed test is retried like any other delivery
+func (a *WebhookAPI) SendTestEvent(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "SendTestEvent").Info("starting")
+	user := r.Context().Value("user").(*domain.User)
+	webhook := r.Context().Value("webhook").(*domain.Webhook)
+
+	// the delivery is queued as if its first attempt is in flight so DeliverDue doesn't send it as well
+	now := time.Now()
+	event := &domain.WebhookEvent{ID: fmt.Sprintf("evt_%d_%d", user.ID, now.UnixNano()), Type: domain.TestEvent, UserID: user.ID, Created: now, Data: map[string]int{"webhookId": webhook.ID}}
+	delivery, err := a.queue(webhook, event, now.Add(domain.WebhookBackoff))
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+	if err := a.attempt(webhook, delivery, now); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	if err := render.Instance(w, r, stc.NewWebhookDeliveryResponse(delivery)); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
+
+// Redeliver queues a dead delivery to be attempted again with a fresh set of attempts
+func (a *WebhookAPI) Redeliver(w http.ResponseWriter, r *http.Request) {
+	log.WithField("method", "Redeliver").Info("starting")
+	webhook := r.Context().Value("webhook").(*domain.Webhook)
+
+	id, err := strconv.Atoi(chi.URLParam(r, "deliveryId"))
+	if err != nil {
+		render.WithMessage("unable to parse parameter delivery id").BadRequest(w, r)
+		return
+	}
+
+	delivery, err := a.WebhookService.Delivery(id)
+	if err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+	if delivery == nil || delivery.WebhookID != webhook.ID {
+		render.WithMessage("delivery not found").NotFound(w, r)
+		return
+	}
+	if delivery.Status != domain.DeadDelivery {
+		render.WithMessage("only dead deliveries can be redelivered").Conflict(w, r)
+		return
+	}
+
+	delivery.Status = domain.PendingDelivery
+	delivery.Attempts = 0
+	delivery.NextAttempt = time.Now()
+	if err := a.WebhookService.UpdateDelivery(delivery); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+
+	if err := render.Instance(w, r, stc.NewWebhookDeliveryResponse(delivery)); err != nil {
+		render.WithError(err).InternalServerError(w, r)
+		return
+	}
+}
@@ -0,0 +1,253 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/webhook"
+	"github.com/jacsmith21/lukabox/mock"
+)
+
+func TestWebhooks(t *testing.T) {
+	uAPI := UserAPI{}
+	uSvc := mock.UserService{}
+	uAPI.UserService = &uSvc
+	wAPI := WebhookAPI{}
+	wSvc := mock.WebhookService{}
+	wAPI.WebhookService = &wSvc
+
+	created := time.Date(2009, time.November, 10, 12, 0, 0, 0, time.UTC)
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id}, nil
+	}
+	wSvc.InsertWebhookFn = func(w *domain.Webhook) error {
+		w.ID = 1
+		w.Secret = "shh"
+		w.Created = created
+		return nil
+	}
+	wSvc.WebhookFn = func(id int) (*domain.Webhook, error) {
+		if id == 1 {
+			return &domain.Webhook{ID: 1, UserID: 1, URL: "https://example.com/hook", Events: []string{domain.BoxOpenedEvent}, Secret: "shh", Created: created}, nil
+		}
+		return nil, nil
+	}
+	wSvc.DeliveriesFn = func(webhookID int) ([]*domain.WebhookDelivery, error) {
+		return []*domain.WebhookDelivery{
+			{ID: 2, WebhookID: webhookID, EventID: "evt_2", EventType: domain.BoxOpenedEvent, Payload: []byte(`{}`), Status: domain.DeadDelivery, Attempts: 8, ResponseStatus: 500, Error: "failed", Created: created, NextAttempt: created},
+			{ID: 1, WebhookID: webhookID, EventID: "evt_1", EventType: domain.BoxOpenedEvent, Payload: []byte(`{}`), Status: domain.DeliveredDelivery, Attempts: 1, ResponseStatus: 200, Created: created, NextAttempt: created, Delivered: &created},
+		}, nil
+	}
+
+	r := chi.NewRouter()
+	r.Route("/users/{userId}/webhooks", func(r chi.Router) {
+		r.Use(uAPI.UserCtx)
+		r.Post("/", wAPI.CreateWebhook)
+		r.Route("/{webhookId}", func(r chi.Router) {
+			r.Use(wAPI.WebhookCtx)
+			r.Get("/", wAPI.Webhook)
+			r.Get("/deliveries", wAPI.Deliveries)
+		})
+	})
+
+	jsonHeader := map[string]string{"Content-Type": "application/json"}
+	tests := []*test{
+		{"/users/1/webhooks", "POST", `{"url":"https://example.com/hook","events":["box.opened"]}`, jsonHeader, http.StatusCreated, `{"id":1,"userId":1,"url":"https://example.com/hook","events":["box.opened"],"created":"2009-11-10T12:00:00Z","secret":"shh"}`},
+		{"/users/1/webhooks", "POST", `{"url":"https://example.com/hook","events":["box.exploded"]}`, jsonHeader, http.StatusBadRequest, `{"message":"events[0] must be one of box.opened, dose.missed, pill.changed, stock.low","fields":{"events[0]":"events[0] must be one of box.opened, dose.missed, pill.changed, stock.low"}}`},
+		{"/users/1/webhooks", "POST", `{"url":"http://example.com/hook","events":["box.opened"]}`, jsonHeader, http.StatusBadRequest, `{"message":"url must use https"}`},
+		{"/users/1/webhooks", "POST", `{"url":"https://169.254.169.254/latest/meta-data","events":["box.opened"]}`, jsonHeader, http.StatusBadRequest, `{"message":"url must be a public address"}`},
+		{"/users/1/webhooks", "POST", `{"url":"https://localhost:3001/users","events":["box.opened"]}`, jsonHeader, http.StatusBadRequest, `{"message":"url must be a public address"}`},
+		{"/users/1/webhooks", "POST", `{"url":"https://[::1]/hook","events":["box.opened"]}`, jsonHeader, http.StatusBadRequest, `{"message":"url must be a public address"}`},
+		{"/users/1/webhooks/1", "GET", "", nil, http.StatusOK, `{"id":1,"userId":1,"url":"https://example.com/hook","events":["box.opened"],"created":"2009-11-10T12:00:00Z"}`},
+		{"/users/2/webhooks/1", "GET", "", nil, http.StatusNotFound, `{"message":"webhook not found"}`},
+		{"/users/1/webhooks/1/deliveries?status=dead", "GET", "", nil, http.StatusOK, `[{"id":2,"webhookId":1,"eventId":"evt_2","eventType":"box.opened","payload":{},"status":"dead","attempts":8,"responseStatus":500,"error":"failed","created":"2009-11-10T12:00:00Z","nextAttempt":"2009-11-10T12:00:00Z","delivered":null}]`},
+		{"/users/1/webhooks/1/deliveries?status=lost", "GET", "", nil, http.StatusBadRequest, `{"message":"status must be one of pending, delivered or dead"}`},
+	}
+	runTests(t, r, tests)
+}
+
+func TestSendTestEvent(t *testing.T) {
+	uAPI := UserAPI{}
+	uSvc := mock.UserService{}
+	uAPI.UserService = &uSvc
+	wAPI := WebhookAPI{}
+	wSvc := mock.WebhookService{}
+	wAPI.WebhookService = &wSvc
+
+	var signature, payload string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		payload = string(body)
+		signature = r.Header.Get(webhook.SignatureHeader)
+		if r.Header.Get(webhook.EventHeader) != domain.TestEvent {
+			t.Errorf("expected a %s event, got %s", domain.TestEvent, r.Header.Get(webhook.EventHeader))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	// the test server is on this host, which the default client refuses to connect to
+	wAPI.Sender = &webhook.Sender{Client: server.Client()}
+
+	uSvc.UserByIDFn = func(id int) (*domain.User, error) {
+		return &domain.User{ID: id}, nil
+	}
+	wSvc.WebhookFn = func(id int) (*domain.Webhook, error) {
+		return &domain.Webhook{ID: id, UserID: 1, URL: server.URL, Events: []string{domain.DoseMissedEvent}, Secret: "shh"}, nil
+	}
+	var delivery *domain.WebhookDelivery
+	var due time.Time
+	wSvc.InsertDeliveryFn = func(d *domain.WebhookDelivery) error {
+		d.ID = 1
+		delivery = d
+		due = d.NextAttempt
+		return nil
+	}
+	wSvc.UpdateDeliveryFn = func(d *domain.WebhookDelivery) error {
+		return nil
+	}
+
+	r := chi.NewRouter()
+	r.Route("/users/{userId}/webhooks/{webhookId}", func(r chi.Router) {
+		r.Use(uAPI.UserCtx)
+		r.Use(wAPI.WebhookCtx)
+		r.Post("/test", wAPI.SendTestEvent)
+	})
+
+	req, err := http.NewRequest("POST", "/users/1/webhooks/1/test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected the test event to be sent, got %d %s", w.Code, w.Body.String())
+	}
+	if delivery.Status != domain.DeliveredDelivery || delivery.ResponseStatus != http.StatusNoContent || delivery.Attempts != 1 {
+		t.Errorf("expected the delivery to succeed on the first attempt, got %+v", delivery)
+	}
+	if !due.After(time.Now()) {
+		t.Errorf("expected the test delivery to be queued in flight so it isn't delivered twice, it was due at %s", due)
+	}
+
+	event := &domain.WebhookEvent{}
+	if err := json.Unmarshal([]byte(payload), event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != domain.TestEvent || event.UserID != 1 {
+		t.Errorf("expected a test event for user 1, got %+v", event)
+	}
+
+	unix, err := strconv.ParseInt(strings.TrimPrefix(strings.Split(signature, ",")[0], "t="), 10, 64)
+	if err != nil {
+		t.Fatalf("expected the signature to start with a timestamp, got %s", signature)
+	}
+	if expected := webhook.Sign("shh", time.Unix(unix, 0), []byte(payload)); signature != expected {
+		t.Errorf("expected the signature %s, got %s", expected, signature)
+	}
+}
+
+func TestDeliverDue(t *testing.T) {
+	wAPI := WebhookAPI{}
+	wSvc := mock.WebhookService{}
+	sender := mock.WebhookSender{}
+	wAPI.WebhookService = &wSvc
+	wAPI.Sender = &sender
+
+	now := time.Date(2018, time.March, 1, 8, 0, 0, 0, time.UTC)
+	delivery := &domain.WebhookDelivery{ID: 1, WebhookID: 1, Status: domain.PendingDelivery, NextAttempt: now}
+	orphan := &domain.WebhookDelivery{ID: 2, WebhookID: 2, Status: domain.PendingDelivery, NextAttempt: now}
+
+	wSvc.DueDeliveriesFn = func(t time.Time) ([]*domain.WebhookDelivery, error) {
+		due := []*domain.WebhookDelivery{}
+		for _, d := range []*domain.WebhookDelivery{delivery, orphan} {
+			if d.Status == domain.PendingDelivery && !d.NextAttempt.After(t) {
+				due = append(due, d)
+			}
+		}
+		return due, nil
+	}
+	wSvc.WebhookFn = func(id int) (*domain.Webhook, error) {
+		if id == 1 {
+			return &domain.Webhook{ID: 1, UserID: 1}, nil
+		}
+		return nil, nil
+	}
+	wSvc.UpdateDeliveryFn = func(d *domain.WebhookDelivery) error {
+		return nil
+	}
+	sender.SendFn = func(w *domain.Webhook, d *domain.WebhookDelivery) (int, error) {
+		return http.StatusServiceUnavailable, errors.New("unavailable")
+	}
+
+	if err := wAPI.DeliverDue(now); err != nil {
+		t.Fatal(err)
+	}
+	if orphan.Status != domain.DeadDelivery {
+		t.Errorf("expected the delivery to a deleted webhook to be dead, got %s", orphan.Status)
+	}
+	if delivery.Attempts != 1 || !delivery.NextAttempt.Equal(now.Add(domain.WebhookBackoff)) {
+		t.Errorf("expected a retry after %s, got %s", domain.WebhookBackoff, delivery.NextAttempt)
+	}
+
+	// each retry waits twice as long until the attempts are used up
+	for i := 1; i < domain.MaxWebhookAttempts; i++ {
+		now = delivery.NextAttempt
+		if err := wAPI.DeliverDue(now); err != nil {
+			t.Fatal(err)
+		}
+		if i < domain.MaxWebhookAttempts-1 && !delivery.NextAttempt.Equal(now.Add(domain.WebhookBackoff<<uint(i))) {
+			t.Errorf("expected attempt %d to be retried after %s, got %s", i+1, domain.WebhookBackoff<<uint(i), delivery.NextAttempt.Sub(now))
+		}
+	}
+	if delivery.Status != domain.DeadDelivery || delivery.Attempts != domain.MaxWebhookAttempts || delivery.Error != "unavailable" {
+		t.Errorf("expected the delivery to be dead after %d attempts, got %+v", domain.MaxWebhookAttempts, delivery)
+	}
+}
+
+func TestWebhookSender(t *testing.T) {
+	hits := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		http.Redirect(w, r, "https://169.254.169.254/", http.StatusFound)
+	}))
+	defer server.Close()
+
+	delivery := &domain.WebhookDelivery{ID: 1, EventType: domain.TestEvent, Payload: []byte(`{}`)}
+	sends := []struct {
+		sender *webhook.Sender
+		url    string
+		status int
+		err    string
+	}{
+		{&webhook.Sender{}, server.URL, 0, "127.0.0.1 is not a public address"},
+		{&webhook.Sender{}, strings.Replace(server.URL, "https", "http", 1), 0, "url must use https"},
+		{&webhook.Sender{Client: webhook.NewClient()}, "https://localhost:1/hook", 0, "localhost is not a public address"},
+	}
+	for i, s := range sends {
+		status, err := s.sender.Send(&domain.Webhook{URL: s.url, Secret: "shh"}, delivery)
+		if status != s.status || err == nil || !strings.Contains(err.Error(), s.err) {
+			t.Errorf("expected %d and an error containing %q, got %d and %v on iteration %d", s.status, s.err, status, err, i)
+		}
+	}
+	if hits != 0 {
+		t.Errorf("expected the server on this host not to be reached, it was reached %d times", hits)
+	}
+
+	// redirects aren't followed, they fail like any other response which isn't 2xx
+	client := server.Client()
+	client.CheckRedirect = webhook.NewClient().CheckRedirect
+	status, err := (&webhook.Sender{Client: client}).Send(&domain.Webhook{URL: server.URL, Secret: "shh"}, delivery)
+	if status != http.StatusFound || err == nil || hits != 1 {
+		t.Errorf("expected the redirect not to be followed, got %d and %v after %d requests", status, err, hits)
+	}
+}
@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+// DoseWindow how far an open event can be from a scheduled dose and still be attributed to it
+const DoseWindow = 2 * time.Hour
+
+// Attribute the scheduled dose an open event at t is attributed to, false if there isn't a dose
+// within the window which hasn't already been taken
+func (p *Pill) Attribute(t time.Time, taken []*PillEvent) (time.Time, bool) {
+	var closest time.Time
+	found := false
+	for _, dose := range p.Doses(t.Add(-DoseWindow), t.Add(DoseWindow)) {
+		if DoseTaken(dose, taken) {
+			continue
+		}
+		if !found || abs(dose.Sub(t)) < abs(closest.Sub(t)) {
+			closest = dose
+			found = true
+		}
+	}
+	return closest, found
+}
+
+// DoseTaken whether one of the taken events was attributed to the scheduled dose
+func DoseTaken(dose time.Time, taken []*PillEvent) bool {
+	for _, e := range taken {
+		if e.Scheduled.Equal(dose) {
+			return true
+		}
+	}
+	return false
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// PillEventService database services
+type PillEventService interface {
+	PillEvents(pillID int) ([]*PillEvent, error)
+	InsertPillEvent(pillEvent *PillEvent) error
+}
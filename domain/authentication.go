@@ -6,9 +6,15 @@ type Credentials struct {
 	Password string `json:"password" validate:"required"`
 }
 
-//Token jwt token
+//Token jwt token, EnrolmentRequired is set when the token can only be used to enrol in two factor authentication
 type Token struct {
-	Token string `json:"token"`
+	Token             string `json:"token"`
+	EnrolmentRequired bool   `json:"enrolmentRequired,omitempty"`
+}
+
+// TwoFactorChallenge returned instead of a token when a user has to enter a two factor code
+type TwoFactorChallenge struct {
+	Challenge string `json:"challenge"`
 }
 
 //AuthenticationService credentials services
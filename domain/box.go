@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"sort"
+	"time"
+)
 
 // Box a box
 type Box struct {
@@ -13,7 +16,7 @@ type OpenEvent struct {
 	ID     int       `json:"id"`
 	CompID int       `json:"compId" validate:"required"`
 	UserID int       `json:"userId"`
-	Time   time.Time `json:"time" validate:"required"`
+	Time   time.Time `json:"time" validate:"required,notfuture"`
 }
 
 // CloseEvent a closing event
@@ -21,11 +24,110 @@ type CloseEvent struct {
 	ID     int       `json:"id"`
 	CompID int       `json:"compId" validate:"required"`
 	UserID int       `json:"userId"`
-	Time   time.Time `json:"time" validate:"required"`
+	Time   time.Time `json:"time" validate:"required,notfuture"`
+}
+
+// LeftOpenThreshold how long a compartment can stay open before it is flagged as left open
+const LeftOpenThreshold = 10 * time.Minute
+
+// The states of a compartment session
+const (
+	// OpenSession the compartment hasn't been closed yet
+	OpenSession = "open"
+	// ClosedSession the compartment was opened and then closed
+	ClosedSession = "closed"
+	// UnclosedSession the compartment was opened again without being closed, the close event is missing
+	UnclosedSession = "unclosed"
+)
+
+// CompartmentSession a compartment being opened until it's closed. Seconds is how long it stayed open, or has
+// been open so far, and is null when the close is missing. Closes without an open are counted as duplicates
+// of the session before them
+type CompartmentSession struct {
+	CompID          int        `json:"compId"`
+	State           string     `json:"state"`
+	OpenEventID     int        `json:"openEventId"`
+	CloseEventID    int        `json:"closeEventId,omitempty"`
+	Opened          time.Time  `json:"opened"`
+	Closed          *time.Time `json:"closed"`
+	Seconds         *int       `json:"seconds"`
+	LeftOpen        bool       `json:"leftOpen"`
+	DuplicateCloses int        `json:"duplicateCloses"`
+}
+
+// boxEvent an open or close event of a compartment
+type boxEvent struct {
+	open  *OpenEvent
+	close *CloseEvent
+	comp  int
+	time  time.Time
+}
+
+// Sessions pairs the open and close events of a box's compartments into sessions, ordered by when they were
+// opened. Every compartment starts closed, an open starts a session which the next close of the compartment
+// ends. Sessions open longer than threshold, or still open at now after it, are flagged as left open
+func Sessions(opens []*OpenEvent, closes []*CloseEvent, now time.Time, threshold time.Duration) []*CompartmentSession {
+	events := make([]boxEvent, 0, len(opens)+len(closes))
+	for _, e := range opens {
+		events = append(events, boxEvent{open: e, comp: e.CompID, time: e.Time})
+	}
+	for _, e := range closes {
+		events = append(events, boxEvent{close: e, comp: e.CompID, time: e.Time})
+	}
+	// a close at the same time as an open is after it
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].time.Equal(events[j].time) {
+			return events[i].open != nil && events[j].open == nil
+		}
+		return events[i].time.Before(events[j].time)
+	})
+
+	sessions := []*CompartmentSession{}
+	open := map[int]*CompartmentSession{}
+	last := map[int]*CompartmentSession{}
+	for _, e := range events {
+		current := open[e.comp]
+		if e.open != nil {
+			if current != nil {
+				current.State = UnclosedSession
+			}
+			session := &CompartmentSession{CompID: e.comp, State: OpenSession, OpenEventID: e.open.ID, Opened: e.time}
+			sessions = append(sessions, session)
+			open[e.comp], last[e.comp] = session, session
+			continue
+		}
+
+		if current == nil {
+			if previous := last[e.comp]; previous != nil {
+				previous.DuplicateCloses++
+			}
+			continue
+		}
+		closed := e.time
+		current.State = ClosedSession
+		current.CloseEventID = e.close.ID
+		current.Closed = &closed
+		current.setDuration(closed.Sub(current.Opened), threshold)
+		delete(open, e.comp)
+	}
+
+	for _, session := range open {
+		session.setDuration(now.Sub(session.Opened), threshold)
+	}
+	return sessions
+}
+
+// setDuration sets how long the session has been open and flags it when that's longer than threshold
+func (s *CompartmentSession) setDuration(d time.Duration, threshold time.Duration) {
+	seconds := int(d / time.Second)
+	s.Seconds = &seconds
+	s.LeftOpen = d > threshold
 }
 
 // BoxService database service
 type BoxService interface {
 	InsertOpenEvent(openEvent *OpenEvent) error
 	InsertCloseEvent(closeEvent *CloseEvent) error
+	OpenEvents(userID int) ([]*OpenEvent, error)
+	CloseEvents(userID int) ([]*CloseEvent, error)
 }
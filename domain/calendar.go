@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// FeedToken an unguessable token which authenticates a user's calendar feed,
+// calendar apps can't send a JWT so the token is part of the feed's url
+type FeedToken struct {
+	Token   string    `json:"token"`
+	UserID  int       `json:"-"`
+	Created time.Time `json:"created"`
+}
+
+// FeedTokenService feed token service
+type FeedTokenService interface {
+	FeedToken(token string) (*FeedToken, error)
+	IssueFeedToken(userID int) (*FeedToken, error)
+	RevokeFeedToken(userID int) error
+}
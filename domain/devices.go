@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"time"
+)
+
+// The commands which can be sent to a device
+const (
+	RefreshCommand = "refresh"
+	RingCommand    = "ring"
+	UnlockCommand  = "unlock"
+)
+
+// Device a box registered to a user, it authenticates with its id and secret. Only the hash of the secret is stored
+type Device struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"userId"`
+	Name       string    `json:"name"`
+	Secret     string    `json:"-"`
+	Registered time.Time `json:"registered"`
+	LastSeen   time.Time `json:"lastSeen"`
+}
+
+// HashDeviceSecret hashes a device secret for storage, secrets are random so a fast hash is enough
+func HashDeviceSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Authenticates whether secret is the secret of the device
+func (d *Device) Authenticates(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(d.Secret), []byte(HashDeviceSecret(secret))) == 1
+}
+
+// DeviceCommand a command queued for a device, refresh asks the device to fetch its configuration again,
+// ring sounds its alarm and unlock unlocks a compartment
+type DeviceCommand struct {
+	ID        int        `json:"id"`
+	DeviceID  int        `json:"deviceId"`
+	Type      string     `json:"type" validate:"required,oneof=refresh ring unlock"`
+	CompID    int        `json:"compId" validate:"gte=0"`
+	Created   time.Time  `json:"created"`
+	Delivered *time.Time `json:"delivered"`
+}
+
+// DeviceService database services, PendingCommands are the commands which haven't been delivered, oldest first.
+// InsertDevice sets the device's Secret to the generated secret and stores its hash
+type DeviceService interface {
+	Device(id int) (*Device, error)
+	Devices(userID int) ([]*Device, error)
+	InsertDevice(device *Device) error
+	DeleteDevice(id int) error
+	TouchDevice(id int, lastSeen time.Time) error
+	InsertCommand(command *DeviceCommand) error
+	PendingCommands(deviceID int) ([]*DeviceCommand, error)
+	DeliverCommand(id int, delivered time.Time) error
+}
@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+// IdempotencyRecord the first response to a request with an Idempotency-Key, it is replayed to retries of the
+// request. Keys are scoped to a user, UserID is 0 for requests without a token like signing up
+type IdempotencyRecord struct {
+	UserID      int
+	Key         string
+	Fingerprint string
+	Expires     time.Time
+
+	// Status is 0 while the first request is in progress
+	Status int
+	Header map[string][]string
+	Body   []byte
+}
+
+// Completed whether the response of the first request has been stored
+func (r *IdempotencyRecord) Completed() bool {
+	return r.Status != 0
+}
+
+// IdempotencyService stores the responses of requests with an Idempotency-Key
+type IdempotencyService interface {
+	Begin(record *IdempotencyRecord) (*IdempotencyRecord, error)
+	Complete(record *IdempotencyRecord) error
+	Release(userID int, key string) error
+}
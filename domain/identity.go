@@ -0,0 +1,47 @@
+package domain
+
+import "time"
+
+// ExternalIdentity an identity verified by an external OpenID Connect provider
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+}
+
+// IdentityProvider an external OpenID Connect provider
+type IdentityProvider interface {
+	AuthorizationURL(state string, nonce string, challenge string) (string, error)
+	Exchange(code string, verifier string, nonce string) (*ExternalIdentity, error)
+}
+
+// Identity links an external identity to a user
+type Identity struct {
+	Provider string
+	Subject  string
+	UserID   int
+}
+
+// IdentityService database services
+type IdentityService interface {
+	Identity(provider string, subject string) (*Identity, error)
+	InsertIdentity(identity *Identity) error
+}
+
+// AuthorizationState the state kept between redirecting to a provider and the provider's callback
+type AuthorizationState struct {
+	State    string
+	Provider string
+	Verifier string
+	Nonce    string
+	Expires  time.Time
+}
+
+// AuthorizationStateService database services
+type AuthorizationStateService interface {
+	InsertState(state *AuthorizationState) error
+	TakeState(state string) (*AuthorizationState, error)
+}
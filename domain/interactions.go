@@ -0,0 +1,92 @@
+package domain
+
+import "sort"
+
+// The severities of interactions, from most to least severe
+const (
+	ContraindicatedSeverity = "contraindicated"
+	MajorSeverity           = "major"
+	ModerateSeverity        = "moderate"
+	MinorSeverity           = "minor"
+)
+
+// severityRanks ranks severities, most severe first
+var severityRanks = map[string]int{
+	ContraindicatedSeverity: 0,
+	MajorSeverity:           1,
+	ModerateSeverity:        2,
+	MinorSeverity:           3,
+}
+
+// ValidSeverity whether a severity is known
+func ValidSeverity(severity string) bool {
+	_, ok := severityRanks[severity]
+	return ok
+}
+
+// Interaction a known interaction between two drugs
+type Interaction struct {
+	Drug        string `json:"drug"`
+	Other       string `json:"other"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+}
+
+// InteractionWarning an interaction between two pills of a regimen
+type InteractionWarning struct {
+	PillID      int    `json:"pillId"`
+	Pill        string `json:"pill"`
+	OtherPillID int    `json:"otherPillId"`
+	Other       string `json:"other"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+}
+
+// InteractionService looks up interactions between drugs by name
+type InteractionService interface {
+	Interactions(pill string, other string) ([]*Interaction, error)
+}
+
+// Interactions the interactions between a pill and the rest of a regimen, most severe first.
+// Pills are matched by their drug names, archived pills and the pill itself aren't part of the regimen
+func Interactions(s InteractionService, pill *Pill, regimen []*Pill) ([]*InteractionWarning, error) {
+	warnings := []*InteractionWarning{}
+	if pill.Archived {
+		return warnings, nil
+	}
+	for _, other := range regimen {
+		if other.Archived || (pill.ID != 0 && other.ID == pill.ID) {
+			continue
+		}
+		interactions, err := s.Interactions(pill.DrugName(), other.DrugName())
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range interactions {
+			warnings = append(warnings, &InteractionWarning{PillID: pill.ID, Pill: pill.Name, OtherPillID: other.ID, Other: other.Name, Severity: i.Severity, Description: i.Description})
+		}
+	}
+	SortInteractionWarnings(warnings)
+	return warnings, nil
+}
+
+// RegimenInteractions the interactions between every pair of pills in a regimen, most severe first
+func RegimenInteractions(s InteractionService, regimen []*Pill) ([]*InteractionWarning, error) {
+	warnings := []*InteractionWarning{}
+	for i, pill := range regimen {
+		pillWarnings, err := Interactions(s, pill, regimen[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, pillWarnings...)
+	}
+	SortInteractionWarnings(warnings)
+	return warnings, nil
+}
+
+// SortInteractionWarnings sorts warnings by severity, most severe first
+func SortInteractionWarnings(warnings []*InteractionWarning) {
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return severityRanks[warnings[i].Severity] < severityRanks[warnings[j].Severity]
+	})
+}
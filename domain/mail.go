@@ -0,0 +1,21 @@
+package domain
+
+// Email an email message
+type Email struct {
+	To          string
+	Subject     string
+	Body        string
+	Attachments []*Attachment
+}
+
+// Attachment a file attached to an email
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Mailer sends emails
+type Mailer interface {
+	Send(email *Email) error
+}
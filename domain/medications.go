@@ -0,0 +1,17 @@
+package domain
+
+// Medication an entry of the medication catalogue, Codes holds codes such as rxnorm or din by system
+type Medication struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	GenericName string            `json:"genericName"`
+	Strengths   []string          `json:"strengths"`
+	Forms       []string          `json:"forms"`
+	Codes       map[string]string `json:"codes"`
+}
+
+// MedicationService the medication catalogue
+type MedicationService interface {
+	Medication(id string) (*Medication, error)
+	SearchMedications(query string, limit int) ([]*Medication, error)
+}
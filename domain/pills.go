@@ -1,22 +1,179 @@
 package domain
 
-import "time"
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
 
-//Pill a pill or other form of medication
+//Pill a pill or other form of medication, Version is incremented by every update and is the ETag of the pill
 type Pill struct {
-	ID         int         `json:"pillId"`
-	UserID     int         `json:"id"`
-	Name       string      `json:"name"`
-	DaysOfWeek []int       `json:"daysOfWeek"`
-	TimesOfDay []time.Time `json:"timesOfDay"`
-	Archived   bool        `json:"archived"`
+	ID              int         `json:"pillId"`
+	UserID          int         `json:"id"`
+	Name            string      `json:"name" validate:"required"`
+	MedicationID    string      `json:"medicationId"`
+	Generic         string      `json:"generic"`
+	DaysOfWeek      []int       `json:"daysOfWeek" validate:"dive,day"`
+	TimesOfDay      []TimeOfDay `json:"timesOfDay" validate:"nooverlap"`
+	Schedule        *Schedule   `json:"schedule"`
+	Archived        bool        `json:"archived"`
+	Strength        float64     `json:"strength" validate:"gte=0"`
+	Unit            string      `json:"unit" validate:"omitempty,oneof=mg mcg g ml iu units"`
+	Form            string      `json:"form" validate:"omitempty,oneof=tablet capsule liquid injection"`
+	Route           string      `json:"route" validate:"omitempty,oneof=oral sublingual topical inhaled subcutaneous intramuscular intravenous rectal"`
+	Food            string      `json:"food" validate:"omitempty,oneof=with-food empty-stomach"`
+	Instructions    string      `json:"instructions"`
+	Prescriber      string      `json:"prescriber"`
+	StartDate       *time.Time  `json:"startDate"`
+	EndDate         *time.Time  `json:"endDate"`
+	Quantity        float64     `json:"quantity"`
+	QuantityPerDose float64     `json:"quantityPerDose" validate:"gte=0"`
+	AlertDays       int         `json:"alertDays" validate:"gte=0"`
+	Alerted         bool        `json:"-"`
+	Version         int         `json:"-"`
 }
 
-// PillEvent a pill event
+// PillEvent a dose of a pill being taken, Scheduled is the dose it was attributed to
 type PillEvent struct {
-	ID     int
-	PillID int
-	Time   time.Time
+	ID          int       `json:"id"`
+	PillID      int       `json:"pillId"`
+	OpenEventID int       `json:"openEventId"`
+	Scheduled   time.Time `json:"scheduled"`
+	Time        time.Time `json:"time"`
+}
+
+// Occurrences the scheduled doses from from until to, the schedule supersedes DaysOfWeek and TimesOfDay.
+// Dose times are wall clock times in the location of from, which should be the user's location
+func (p *Pill) Occurrences(from time.Time, to time.Time) []Occurrence {
+	var times []time.Time
+	switch {
+	case p.Schedule == nil:
+		times = p.weekly(from, to)
+	case p.Schedule.AsNeeded:
+		times = []time.Time{}
+	default:
+		rule, err := ParseRule(p.Schedule.RRule)
+		if err != nil {
+			return []Occurrence{}
+		}
+		times = rule.Occurrences(p.Schedule.Begin(from.Location()), from, to)
+	}
+
+	occurrences := []Occurrence{}
+	for _, t := range times {
+		if !p.Active(t) {
+			continue
+		}
+		if strength, quantity, ok := p.Dose(t); ok {
+			occurrences = append(occurrences, Occurrence{Time: t, Strength: strength, Quantity: quantity})
+		}
+	}
+	return occurrences
+}
+
+// Doses the times of the scheduled doses from from until to
+func (p *Pill) Doses(from time.Time, to time.Time) []time.Time {
+	doses := []time.Time{}
+	for _, o := range p.Occurrences(from, to) {
+		doses = append(doses, o.Time)
+	}
+	return doses
+}
+
+// weekly the doses from DaysOfWeek and TimesOfDay, DaysOfWeek uses 1 for Monday through 7 for Sunday
+func (p *Pill) weekly(from time.Time, to time.Time) []time.Time {
+	doses := []time.Time{}
+	loc := from.Location()
+	for day := date(from, loc); day.Before(to); day = day.AddDate(0, 0, 1) {
+		if !containsInt(p.DaysOfWeek, isoWeekday(day.Weekday())) {
+			continue
+		}
+		for _, t := range p.TimesOfDay {
+			dose := t.On(day)
+			if !dose.Before(from) && dose.Before(to) {
+				doses = append(doses, dose)
+			}
+		}
+	}
+	sort.Slice(doses, func(i, j int) bool { return doses[i].Before(doses[j]) })
+	return doses
+}
+
+// DrugName the name and generic name of the pill which drugs are matched against, ie. Advil ibuprofen
+func (p *Pill) DrugName() string {
+	return strings.TrimSpace(p.Name + " " + p.Generic)
+}
+
+// Dosage the strength and unit of the pill, ie. 500 mg
+func (p *Pill) Dosage() string {
+	if p.Strength == 0 {
+		return ""
+	}
+	return strings.TrimSpace(strconv.FormatFloat(p.Strength, 'f', -1, 64) + " " + p.Unit)
+}
+
+// Active whether the course of the pill includes the day, start and end dates are inclusive
+func (p *Pill) Active(day time.Time) bool {
+	day = date(day, day.Location())
+	if p.StartDate != nil && day.Before(date(*p.StartDate, day.Location())) {
+		return false
+	}
+	if p.EndDate != nil && day.After(date(*p.EndDate, day.Location())) {
+		return false
+	}
+	return true
+}
+
+// Ended whether the course of the pill ended before now
+func (p *Pill) Ended(now time.Time) bool {
+	return p.EndDate != nil && date(now, now.Location()).After(date(*p.EndDate, now.Location()))
+}
+
+// date the start of the day of t in loc
+func date(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// RunOut when the pill is projected to run out, false if it doesn't run out within a year
+func (p *Pill) RunOut(from time.Time) (time.Time, bool) {
+	remaining := p.Quantity
+	for _, o := range p.Occurrences(from, from.AddDate(1, 0, 0)) {
+		if o.Quantity <= 0 {
+			continue
+		}
+		if remaining+1e-9 < o.Quantity {
+			return o.Time, true
+		}
+		remaining -= o.Quantity
+	}
+	return time.Time{}, false
+}
+
+// NextAllowed when an as needed pill can next be taken after a dose at last
+func (p *Pill) NextAllowed(last time.Time) time.Time {
+	if p.Schedule == nil {
+		return last
+	}
+	return last.Add(time.Duration(p.Schedule.MinInterval) * time.Minute)
+}
+
+// LowStock whether the pill will run out within AlertDays, pills without a threshold are never low
+func (p *Pill) LowStock(now time.Time) bool {
+	if p.AlertDays <= 0 {
+		return false
+	}
+	runOut, ok := p.RunOut(now)
+	return ok && runOut.Before(now.AddDate(0, 0, p.AlertDays))
+}
+
+func containsInt(list []int, i int) bool {
+	for _, v := range list {
+		if v == i {
+			return true
+		}
+	}
+	return false
 }
 
 //PillService database services
@@ -25,4 +182,23 @@ type PillService interface {
 	Pills(userID int) ([]*Pill, error)
 	CreatePill(pill *Pill) error
 	UpdatePill(id int, pill *Pill) error
+	ArchiveEndedPills(now time.Time) (int, error)
+}
+
+// StockAdjustment a change to the quantity of a pill on hand
+type StockAdjustment struct {
+	ID     int       `json:"id"`
+	PillID int       `json:"pillId"`
+	Change float64   `json:"change" validate:"required"`
+	Reason string    `json:"reason" validate:"required"`
+	Time   time.Time `json:"time"`
+}
+
+// DoseReason the reason for stock adjustments made automatically when a dose is taken
+const DoseReason = "dose"
+
+// StockService database services, inserting an adjustment applies it to the pill's quantity
+type StockService interface {
+	Adjustments(pillID int) ([]*StockAdjustment, error)
+	InsertAdjustment(adjustment *StockAdjustment) error
 }
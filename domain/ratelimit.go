@@ -0,0 +1,43 @@
+package domain
+
+import "time"
+
+// RateLimitPolicy a token bucket policy, the bucket holds Capacity tokens and one token is added every Interval
+type RateLimitPolicy struct {
+	Capacity int
+	Interval time.Duration
+}
+
+// RateLimiter rate limiting service, keys are things like ip addresses or emails
+type RateLimiter interface {
+	Allow(key string, policy RateLimitPolicy) (bool, time.Duration, error)
+	Reset(key string) error
+}
+
+// LockoutPolicy progressive lockout policy, an account is locked for Duration after Threshold failures and the duration doubles with every failure after that up to Max
+type LockoutPolicy struct {
+	Threshold int
+	Duration  time.Duration
+	Max       time.Duration
+}
+
+// Lockout the failed authentication attempts for an email
+type Lockout struct {
+	Email    string
+	Failures int
+	Until    time.Time
+	Token    string
+}
+
+// Locked whether the lockout is currently in effect
+func (l *Lockout) Locked() bool {
+	return l != nil && time.Now().Before(l.Until)
+}
+
+// LockoutService lockout service
+type LockoutService interface {
+	Lockout(email string) (*Lockout, error)
+	Fail(email string) (*Lockout, error)
+	Clear(email string) error
+	Unlock(token string) (bool, error)
+}
@@ -0,0 +1,422 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPeriods how many periods of a rule are expanded before giving up, a year of hourly doses
+const maxPeriods = 366 * 24
+
+// Schedule an RRULE style recurrence for a pill which supersedes DaysOfWeek and TimesOfDay,
+// the wall clock time of Start is interpreted in the user's location
+type Schedule struct {
+	Start       time.Time   `json:"start" validate:"required"`
+	RRule       string      `json:"rrule"`
+	Taper       []TaperStep `json:"taper" validate:"dive"`
+	AsNeeded    bool        `json:"asNeeded"`
+	MinInterval int         `json:"minInterval" validate:"gte=0"`
+}
+
+// TaperStep a step of a tapering course, each step lasts Days starting when the previous one ends
+type TaperStep struct {
+	Days            int     `json:"days" validate:"min=1"`
+	Strength        float64 `json:"strength" validate:"gte=0"`
+	QuantityPerDose float64 `json:"quantityPerDose" validate:"gte=0"`
+}
+
+// Occurrence a scheduled dose with the strength and quantity to take
+type Occurrence struct {
+	Time     time.Time `json:"time"`
+	Strength float64   `json:"strength"`
+	Quantity float64   `json:"quantity"`
+}
+
+// Validate checks the rule of a schedule, as needed schedules don't need one
+func (s *Schedule) Validate() error {
+	if s.AsNeeded {
+		if s.RRule != "" || len(s.Taper) > 0 {
+			return errors.New("as needed schedules can't have a rule or taper")
+		}
+		return nil
+	}
+	if s.RRule == "" {
+		return errors.New("a rule must be supplied unless the pill is taken as needed")
+	}
+	_, err := ParseRule(s.RRule)
+	return err
+}
+
+// Begin the wall clock time of Start in loc
+func (s *Schedule) Begin(loc *time.Location) time.Time {
+	return time.Date(s.Start.Year(), s.Start.Month(), s.Start.Day(), s.Start.Hour(), s.Start.Minute(), s.Start.Second(), 0, loc)
+}
+
+// Legacy the days of the week and times of day equivalent to the schedule,
+// empty when the rule can't be expressed that way, ie. every 8 hours
+func (s *Schedule) Legacy() ([]int, []TimeOfDay) {
+	days, times := []int{}, []TimeOfDay{}
+	rule, err := ParseRule(s.RRule)
+	if err != nil || s.AsNeeded || rule.Interval != 1 || len(rule.ByMonthDay) > 0 {
+		return days, times
+	}
+
+	switch {
+	case rule.Freq == "DAILY" && len(rule.ByDay) == 0:
+		days = []int{1, 2, 3, 4, 5, 6, 7}
+	case rule.Freq == "DAILY" || rule.Freq == "WEEKLY":
+		for _, d := range rule.ByDay {
+			if d.N != 0 {
+				return []int{}, times
+			}
+			days = append(days, isoWeekday(d.Weekday))
+		}
+		if len(days) == 0 {
+			days = append(days, isoWeekday(s.Start.Weekday()))
+		}
+	default:
+		return days, times
+	}
+
+	sort.Ints(days)
+	for _, t := range rule.times(s.Start, date(s.Start, s.Start.Location())) {
+		times = append(times, TimeOfDay{Hour: t.Hour(), Minute: t.Minute()})
+	}
+	return days, times
+}
+
+// Dose the strength and quantity of a dose at t, false once a taper has finished
+func (p *Pill) Dose(t time.Time) (float64, float64, bool) {
+	strength, quantity := p.Strength, p.QuantityPerDose
+	if p.Schedule == nil || len(p.Schedule.Taper) == 0 {
+		return strength, quantity, true
+	}
+
+	begin := date(p.Schedule.Begin(t.Location()), t.Location())
+	for _, step := range p.Schedule.Taper {
+		end := begin.AddDate(0, 0, step.Days)
+		if t.Before(end) {
+			if step.Strength > 0 {
+				strength = step.Strength
+			}
+			if step.QuantityPerDose > 0 {
+				quantity = step.QuantityPerDose
+			}
+			return strength, quantity, true
+		}
+		begin = end
+	}
+	return 0, 0, false
+}
+
+// Rule a parsed RFC 5545 recurrence rule, FREQ may be HOURLY, DAILY, WEEKLY or MONTHLY
+type Rule struct {
+	Freq       string
+	Interval   int
+	ByDay      []RuleDay
+	ByMonthDay []int
+	ByHour     []int
+	ByMinute   []int
+	Count      int
+	Until      time.Time
+}
+
+// RuleDay a BYDAY entry, N is the nth of the weekday in the month counting back from the end when negative, 0 for every one
+type RuleDay struct {
+	N       int
+	Weekday time.Weekday
+}
+
+var ruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// ParseRule parses an RRULE, ie. FREQ=HOURLY;INTERVAL=8 or FREQ=MONTHLY;BYDAY=1MO
+func ParseRule(s string) (*Rule, error) {
+	rule := &Rule{Interval: 1}
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+
+	for _, part := range strings.Split(s, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+
+		var err error
+		switch key, value := kv[0], kv[1]; key {
+		case "FREQ":
+			switch value {
+			case "HOURLY", "DAILY", "WEEKLY", "MONTHLY":
+				rule.Freq = value
+			default:
+				return nil, fmt.Errorf("unsupported frequency %s", value)
+			}
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(value)
+			if err != nil || rule.Interval < 1 {
+				return nil, errors.New("INTERVAL must be a positive number")
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(value)
+			if err != nil || rule.Count < 1 {
+				return nil, errors.New("COUNT must be a positive number")
+			}
+		case "UNTIL":
+			if rule.Until, err = parseUntil(value); err != nil {
+				return nil, err
+			}
+		case "BYDAY":
+			if rule.ByDay, err = parseDays(value); err != nil {
+				return nil, err
+			}
+		case "BYMONTHDAY":
+			if rule.ByMonthDay, err = parseInts(key, value, -31, 31); err != nil {
+				return nil, err
+			}
+		case "BYHOUR":
+			if rule.ByHour, err = parseInts(key, value, 0, 23); err != nil {
+				return nil, err
+			}
+		case "BYMINUTE":
+			if rule.ByMinute, err = parseInts(key, value, 0, 59); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rule part %s", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, errors.New("FREQ must be supplied")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, errors.New("COUNT and UNTIL can't both be supplied")
+	}
+	for _, d := range rule.ByDay {
+		if d.N != 0 && rule.Freq != "MONTHLY" {
+			return nil, errors.New("numbered BYDAY entries are only supported with FREQ=MONTHLY")
+		}
+	}
+	return rule, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL %s", value)
+}
+
+func parseDays(value string) ([]RuleDay, error) {
+	days := []RuleDay{}
+	for _, d := range strings.Split(value, ",") {
+		if len(d) < 2 {
+			return nil, fmt.Errorf("invalid BYDAY %s", d)
+		}
+		weekday, ok := ruleWeekdays[d[len(d)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid BYDAY %s", d)
+		}
+		n := 0
+		if prefix := d[:len(d)-2]; prefix != "" {
+			var err error
+			if n, err = strconv.Atoi(prefix); err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, fmt.Errorf("invalid BYDAY %s", d)
+			}
+		}
+		days = append(days, RuleDay{N: n, Weekday: weekday})
+	}
+	return days, nil
+}
+
+func parseInts(key string, value string, min int, max int) ([]int, error) {
+	ints := []int{}
+	for _, v := range strings.Split(value, ",") {
+		i, err := strconv.Atoi(v)
+		if err != nil || i < min || i > max || (min < 0 && i == 0) {
+			return nil, fmt.Errorf("invalid %s %s", key, v)
+		}
+		ints = append(ints, i)
+	}
+	return ints, nil
+}
+
+// Occurrences the occurrences of the rule starting at start, from until to
+func (r *Rule) Occurrences(start time.Time, from time.Time, to time.Time) []time.Time {
+	occurrences := []time.Time{}
+	count := 0
+	first := 0
+	if r.Count == 0 {
+		first = r.skip(start, from)
+	}
+	for k := first; k < first+maxPeriods; k++ {
+		begin, candidates := r.period(start, k)
+		if !begin.Before(to) || (!r.Until.IsZero() && begin.After(r.Until)) {
+			break
+		}
+		for _, c := range candidates {
+			if c.Before(start) {
+				continue
+			}
+			if !c.Before(to) || (!r.Until.IsZero() && c.After(r.Until)) {
+				return occurrences
+			}
+			count++
+			if r.Count > 0 && count > r.Count {
+				return occurrences
+			}
+			if !c.Before(from) {
+				occurrences = append(occurrences, c)
+			}
+		}
+	}
+	return occurrences
+}
+
+// skip the number of whole periods between start and from which can't have occurrences from from onwards
+func (r *Rule) skip(start time.Time, from time.Time) int {
+	if !from.After(start) {
+		return 0
+	}
+
+	var periods int
+	switch r.Freq {
+	case "HOURLY":
+		periods = int(from.Sub(start) / time.Hour)
+	case "DAILY":
+		periods = int(from.Sub(start) / (24 * time.Hour))
+	case "WEEKLY":
+		periods = int(from.Sub(start) / (7 * 24 * time.Hour))
+	default:
+		periods = (from.Year()-start.Year())*12 + int(from.Month()) - int(start.Month())
+	}
+
+	// one period of slack for daylight saving time and partial periods
+	k := periods/r.Interval - 1
+	if k < 0 {
+		return 0
+	}
+	return k
+}
+
+// period the beginning and the candidate occurrences of the kth period of the rule, in order
+func (r *Rule) period(start time.Time, k int) (time.Time, []time.Time) {
+	loc := start.Location()
+	n := k * r.Interval
+
+	switch r.Freq {
+	case "HOURLY":
+		t := start.Add(time.Duration(n) * time.Hour)
+		if !r.weekday(t) || !r.monthDay(t) || (len(r.ByHour) > 0 && !containsInt(r.ByHour, t.Hour())) {
+			return t, nil
+		}
+		return t, []time.Time{t}
+	case "DAILY":
+		day := date(start, loc).AddDate(0, 0, n)
+		if !r.weekday(day) || !r.monthDay(day) {
+			return day, nil
+		}
+		return day, r.times(start, day)
+	case "WEEKLY":
+		week := date(start, loc).AddDate(0, 0, 1-isoWeekday(start.Weekday())+7*n)
+		candidates := []time.Time{}
+		for i := 0; i < 7; i++ {
+			day := week.AddDate(0, 0, i)
+			if (len(r.ByDay) == 0 && day.Weekday() == start.Weekday()) || (len(r.ByDay) > 0 && r.weekday(day)) {
+				candidates = append(candidates, r.times(start, day)...)
+			}
+		}
+		return week, candidates
+	default:
+		month := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, loc).AddDate(0, n, 0)
+		candidates := []time.Time{}
+		for day := month; day.Month() == month.Month(); day = day.AddDate(0, 0, 1) {
+			if r.monthlyDay(start, day) {
+				candidates = append(candidates, r.times(start, day)...)
+			}
+		}
+		return month, candidates
+	}
+}
+
+// monthlyDay whether a day is included in a monthly rule
+func (r *Rule) monthlyDay(start time.Time, day time.Time) bool {
+	if len(r.ByMonthDay) > 0 {
+		return r.monthDay(day) && r.weekday(day)
+	}
+	if len(r.ByDay) == 0 {
+		return day.Day() == start.Day()
+	}
+
+	last := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+	for _, d := range r.ByDay {
+		if d.Weekday != day.Weekday() {
+			continue
+		}
+		if d.N == 0 || (d.N > 0 && (day.Day()-1)/7+1 == d.N) || (d.N < 0 && (last-day.Day())/7+1 == -d.N) {
+			return true
+		}
+	}
+	return false
+}
+
+// weekday whether the weekday of t is allowed by BYDAY, only used for unnumbered entries
+func (r *Rule) weekday(t time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, d := range r.ByDay {
+		if d.Weekday == t.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// monthDay whether the day of the month of t is allowed by BYMONTHDAY
+func (r *Rule) monthDay(t time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	last := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	for _, d := range r.ByMonthDay {
+		if d == t.Day() || (d < 0 && last+d+1 == t.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+// times the times of the rule on a day, BYHOUR and BYMINUTE default to the time of start
+func (r *Rule) times(start time.Time, day time.Time) []time.Time {
+	hours, minutes := r.ByHour, r.ByMinute
+	if len(hours) == 0 {
+		hours = []int{start.Hour()}
+	}
+	if len(minutes) == 0 {
+		minutes = []int{start.Minute()}
+	}
+
+	times := []time.Time{}
+	for _, h := range hours {
+		for _, m := range minutes {
+			times = append(times, time.Date(day.Year(), day.Month(), day.Day(), h, m, start.Second(), 0, day.Location()))
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	return times
+}
+
+// isoWeekday the weekday numbered 1 for Monday through 7 for Sunday like DaysOfWeek
+func isoWeekday(weekday time.Weekday) int {
+	if weekday == time.Sunday {
+		return 7
+	}
+	return int(weekday)
+}
@@ -0,0 +1,51 @@
+package domain
+
+import "time"
+
+// RefillRequest a request sent to a pharmacy to refill a pill
+type RefillRequest struct {
+	Patient    RefillPatient    `json:"patient"`
+	Medication RefillMedication `json:"medication"`
+	Quantity   float64          `json:"quantity"`
+	Created    time.Time        `json:"created"`
+}
+
+// RefillPatient the patient a refill is for
+type RefillPatient struct {
+	ID        int    `json:"id"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `json:"email"`
+}
+
+// RefillMedication the medication to refill
+type RefillMedication struct {
+	PillID   int    `json:"pillId"`
+	Name     string `json:"name"`
+	Strength string `json:"strength"`
+}
+
+// RefillChannel sends refill requests to a pharmacy, ie. by email, through a fax gateway or to a webhook
+type RefillChannel interface {
+	SendRefill(request *RefillRequest) error
+}
+
+// CaregiverLink a caregiver invited by a patient, the caregiver only gets the patient's low stock alerts once they
+// have accepted the invitation
+type CaregiverLink struct {
+	PatientID   int
+	CaregiverID int
+	Invited     time.Time
+	Accepted    bool
+}
+
+// CaregiverService database services, caregivers get the low stock alerts of their patients. Caregivers are the
+// verified users who accepted, CaregiverLinks includes the pending invitations. AcceptCaregiver returns false
+// when the caregiver wasn't invited
+type CaregiverService interface {
+	Caregivers(patientID int) ([]*User, error)
+	CaregiverLinks(patientID int) ([]*CaregiverLink, error)
+	InviteCaregiver(patientID int, caregiverID int) error
+	AcceptCaregiver(patientID int, caregiverID int) (bool, error)
+	RemoveCaregiver(patientID int, caregiverID int) error
+}
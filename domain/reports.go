@@ -0,0 +1,137 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// LateAfter how long after a scheduled dose it can be taken before it counts as late
+const LateAfter = 30 * time.Minute
+
+// lowAdherence the adherence rate below which a pill is called out in a report's notes
+const lowAdherence = 0.8
+
+// AdherenceCounts what happened to the scheduled doses of a pill or a day, doses which are neither
+// taken, late nor missed are still pending
+type AdherenceCounts struct {
+	Scheduled int `json:"scheduled"`
+	Taken     int `json:"taken"`
+	Late      int `json:"late"`
+	Missed    int `json:"missed"`
+}
+
+// Rate the share of the doses with an outcome which were taken, late or not, 1 when there aren't any
+func (c *AdherenceCounts) Rate() float64 {
+	done := c.Taken + c.Late + c.Missed
+	if done == 0 {
+		return 1
+	}
+	return float64(c.Taken+c.Late) / float64(done)
+}
+
+// PillAdherence the adherence to a pill, AsNeeded counts the doses of pills taken as needed
+type PillAdherence struct {
+	AdherenceCounts
+	PillID   int    `json:"pillId"`
+	Name     string `json:"name"`
+	Dosage   string `json:"dosage"`
+	AsNeeded int    `json:"asNeeded"`
+}
+
+// DayAdherence the adherence to every pill on a day
+type DayAdherence struct {
+	AdherenceCounts
+	Day time.Time `json:"day"`
+}
+
+// AdherenceReport the adherence to a user's pills from From until To
+type AdherenceReport struct {
+	From  time.Time        `json:"from"`
+	To    time.Time        `json:"to"`
+	Pills []*PillAdherence `json:"pills"`
+	Days  []*DayAdherence  `json:"days"`
+	Notes []string         `json:"notes"`
+}
+
+// NewAdherenceReport creates an adherence report from the doses taken of each pill, keyed by pill id.
+// Days are in the location of from and doses are only missed once their window has passed at now
+func NewAdherenceReport(pills []*Pill, taken map[int][]*PillEvent, from time.Time, to time.Time, now time.Time) *AdherenceReport {
+	loc := from.Location()
+	report := &AdherenceReport{From: from, To: to, Pills: []*PillAdherence{}, Days: []*DayAdherence{}, Notes: []string{}}
+
+	days := map[time.Time]*DayAdherence{}
+	for day := date(from, loc); day.Before(to); day = day.AddDate(0, 0, 1) {
+		d := &DayAdherence{Day: day}
+		days[day] = d
+		report.Days = append(report.Days, d)
+	}
+
+	pending := 0
+	for _, pill := range pills {
+		adherence := &PillAdherence{PillID: pill.ID, Name: pill.Name, Dosage: pill.Dosage()}
+		events := taken[pill.ID]
+
+		if pill.Schedule != nil && pill.Schedule.AsNeeded {
+			for _, e := range events {
+				if !e.Time.Before(from) && e.Time.Before(to) {
+					adherence.AsNeeded++
+				}
+			}
+			if adherence.AsNeeded == 0 && pill.Archived {
+				continue
+			}
+			report.Pills = append(report.Pills, adherence)
+			doses := "doses were"
+			if adherence.AsNeeded == 1 {
+				doses = "dose was"
+			}
+			report.Notes = append(report.Notes, fmt.Sprintf("%s is taken as needed, %d %s taken.", pill.Name, adherence.AsNeeded, doses))
+			continue
+		}
+
+		for _, dose := range pill.Doses(from, to) {
+			counts := []*AdherenceCounts{&adherence.AdherenceCounts}
+			if day, ok := days[date(dose, loc)]; ok {
+				counts = append(counts, &day.AdherenceCounts)
+			}
+			event := scheduledEvent(dose, events)
+
+			for _, c := range counts {
+				c.Scheduled++
+				switch {
+				case event != nil && event.Time.Sub(dose) > LateAfter:
+					c.Late++
+				case event != nil:
+					c.Taken++
+				case dose.Add(DoseWindow).Before(now):
+					c.Missed++
+				}
+			}
+			if event == nil && !dose.Add(DoseWindow).Before(now) {
+				pending++
+			}
+		}
+
+		if adherence.Scheduled == 0 && pill.Archived {
+			continue
+		}
+		report.Pills = append(report.Pills, adherence)
+		if adherence.Rate() < lowAdherence {
+			report.Notes = append(report.Notes, fmt.Sprintf("Adherence to %s was %.0f%%, below %.0f%%.", pill.Name, adherence.Rate()*100, lowAdherence*100))
+		}
+	}
+
+	if pending > 0 {
+		report.Notes = append(report.Notes, fmt.Sprintf("%d scheduled doses are still pending.", pending))
+	}
+	return report
+}
+
+func scheduledEvent(dose time.Time, taken []*PillEvent) *PillEvent {
+	for _, e := range taken {
+		if e.Scheduled.Equal(dose) {
+			return e
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package domain
+
+import "time"
+
+// Session a logged in device, identified by the jti of the token issued to it
+type Session struct {
+	ID        string    `json:"id"`
+	UserID    int       `json:"userId"`
+	Device    string    `json:"device"`
+	UserAgent string    `json:"userAgent"`
+	IP        string    `json:"ip"`
+	Created   time.Time `json:"created"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// SessionPolicy how long sessions last, a session expires once it hasn't been seen for Idle or once it was created
+// Lifetime ago. Zero durations don't expire sessions
+type SessionPolicy struct {
+	Idle     time.Duration
+	Lifetime time.Duration
+}
+
+// Expired whether the session has expired at now
+func (p SessionPolicy) Expired(session *Session, now time.Time) bool {
+	if p.Idle > 0 && now.Sub(session.LastSeen) > p.Idle {
+		return true
+	}
+	return p.Lifetime > 0 && now.Sub(session.Created) > p.Lifetime
+}
+
+// SessionService database services
+type SessionService interface {
+	Session(id string) (*Session, error)
+	Sessions(userID int) ([]*Session, error)
+	InsertSession(session *Session) error
+	TouchSession(id string, lastSeen time.Time) error
+	DeleteSession(id string) error
+	DeleteSessions(userID int) error
+}
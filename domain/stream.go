@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// The types of events only sent to live streams, streams also get the webhook event types
+const (
+	BoxClosedEvent = "box.closed"
+	DoseTakenEvent = "dose.taken"
+)
+
+// StreamEvent an event pushed to a user's live streams, ids increase so clients can resume after the last one
+type StreamEvent struct {
+	ID     int64       `json:"id"`
+	UserID int         `json:"userId"`
+	Type   string      `json:"type"`
+	Time   time.Time   `json:"time"`
+	Data   interface{} `json:"data"`
+}
+
+// StreamSubscription the events of a user's stream, Backlog are the recent events after the last event the
+// client saw. Events is closed when the subscriber falls too far behind and Cancel unsubscribes
+type StreamSubscription struct {
+	Backlog []*StreamEvent
+	Events  <-chan *StreamEvent
+	Cancel  func()
+}
+
+// EventBroker fans the events of each user out to their live streams
+type EventBroker interface {
+	Publish(event *StreamEvent)
+	Subscribe(userID int, lastEventID int64) *StreamSubscription
+}
@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// TokenPurpose what a one time token can be used for
+type TokenPurpose string
+
+// The purposes of one time tokens
+const (
+	VerificationPurpose  TokenPurpose = "verification"
+	PasswordResetPurpose TokenPurpose = "passwordReset"
+	TwoFactorPurpose     TokenPurpose = "twoFactor"
+)
+
+// OneTimeToken a single use token which expires
+type OneTimeToken struct {
+	Token   string
+	UserID  int
+	Purpose TokenPurpose
+	Expires time.Time
+	Used    bool
+}
+
+// OneTimeTokenService one time token service
+type OneTimeTokenService interface {
+	Issue(userID int, purpose TokenPurpose, ttl time.Duration) (*OneTimeToken, error)
+	Redeem(token string, purpose TokenPurpose) (*OneTimeToken, error)
+}
@@ -0,0 +1,20 @@
+package domain
+
+// TwoFactor a user's TOTP two factor authentication, recovery codes are stored hashed
+type TwoFactor struct {
+	UserID        int
+	Secret        string
+	Enabled       bool
+	LastStep      int64
+	RecoveryCodes []string
+}
+
+// TwoFactorService database services, RedeemStep and RedeemRecoveryCode check and use up a code in one call so
+// concurrent logins can't both use the same one
+type TwoFactorService interface {
+	TwoFactor(userID int) (*TwoFactor, error)
+	SaveTwoFactor(twoFactor *TwoFactor) error
+	DeleteTwoFactor(userID int) error
+	RedeemStep(userID int, step int64) (bool, error)
+	RedeemRecoveryCode(userID int, hash string) (bool, error)
+}
@@ -1,13 +1,58 @@
 package domain
 
+import (
+	"errors"
+	"time"
+)
+
+// The travel modes, doses either stay at home time or shift to the local time of the travel timezone
+const (
+	HomeTravelMode  = "home"
+	LocalTravelMode = "local"
+)
+
+// The roles of users
+const (
+	PatientRole   = "patient"
+	CaregiverRole = "caregiver"
+	ClinicianRole = "clinician"
+	AdminRole     = "admin"
+)
+
 //User a reguler user
 type User struct {
 	ID        int    `json:"id"`
 	Password  string `json:"password" validate:"required"`
-	Email     string `json:"email" validate:"required"`
+	Email     string `json:"email" validate:"required,email"`
 	FirstName string `json:"firstName" validate:"required"`
 	LastName  string `json:"lastName" validate:"required"`
 	Archived  bool   `json:"archived"`
+	Verified  bool   `json:"verified"`
+	Role      string `json:"role"`
+
+	// Timezone is the IANA timezone dose times are interpreted in, UTC when empty
+	Timezone       string `json:"timezone"`
+	TravelTimezone string `json:"travelTimezone"`
+	TravelMode     string `json:"travelMode" validate:"omitempty,oneof=home local"`
+
+	// TokenVersion is incremented to invalidate every token issued to the user
+	TokenVersion int `json:"-"`
+
+	// Version is incremented by every update, it is the ETag of the user
+	Version int `json:"-"`
+}
+
+// Location the timezone dose times are interpreted in, the travel timezone when travelling in local mode
+func (u *User) Location() *time.Location {
+	name := u.Timezone
+	if u.TravelTimezone != "" && u.TravelMode == LocalTravelMode {
+		name = u.TravelTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
 }
 
 //UserService database services
@@ -18,3 +63,7 @@ type UserService interface {
 	InsertUser(user *User) error
 	UpdateUser(id int, user *User) error
 }
+
+// ErrStale the error of updating a user or pill which has been updated since it was read,
+// updates are only made to the Version they read
+var ErrStale = errors.New("the record has been updated since it was read")
@@ -0,0 +1,131 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// The types of events webhooks can subscribe to
+const (
+	BoxOpenedEvent   = "box.opened"
+	DoseMissedEvent  = "dose.missed"
+	PillChangedEvent = "pill.changed"
+	LowStockEvent    = "stock.low"
+	TestEvent        = "webhook.test"
+)
+
+// The statuses of webhook deliveries, dead deliveries have used up their attempts
+const (
+	PendingDelivery   = "pending"
+	DeliveredDelivery = "delivered"
+	DeadDelivery      = "dead"
+)
+
+// MaxWebhookAttempts how many times a delivery is attempted before it is dead
+const MaxWebhookAttempts = 8
+
+// WebhookBackoff how long after the first failed attempt a delivery is retried, doubling after each attempt
+const WebhookBackoff = 30 * time.Second
+
+// Webhook a subscription of a user to events, payloads are signed with the secret
+type Webhook struct {
+	ID      int       `json:"id"`
+	UserID  int       `json:"userId"`
+	URL     string    `json:"url" validate:"required,url"`
+	Events  []string  `json:"events" validate:"required,min=1,dive,oneof=box.opened dose.missed pill.changed stock.low"`
+	Secret  string    `json:"-"`
+	Created time.Time `json:"created"`
+}
+
+// Subscribed whether the webhook is subscribed to an event type, every webhook receives test events
+func (w *Webhook) Subscribed(eventType string) bool {
+	if eventType == TestEvent {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEvent the payload of a delivery
+type WebhookEvent struct {
+	ID      string      `json:"id"`
+	Type    string      `json:"type"`
+	UserID  int         `json:"userId"`
+	Created time.Time   `json:"created"`
+	Data    interface{} `json:"data"`
+}
+
+// StockAlert the data of a low stock event
+type StockAlert struct {
+	PillID   int       `json:"pillId"`
+	Pill     string    `json:"pill"`
+	Quantity float64   `json:"quantity"`
+	RunOut   time.Time `json:"runOut"`
+}
+
+// MissedDose the data of a missed dose event
+type MissedDose struct {
+	PillID    int       `json:"pillId"`
+	Pill      string    `json:"pill"`
+	Scheduled time.Time `json:"scheduled"`
+}
+
+// WebhookDelivery an attempt to deliver an event to a webhook, retried with exponential backoff
+type WebhookDelivery struct {
+	ID             int             `json:"id"`
+	WebhookID      int             `json:"webhookId"`
+	EventID        string          `json:"eventId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"responseStatus"`
+	Error          string          `json:"error"`
+	Created        time.Time       `json:"created"`
+	NextAttempt    time.Time       `json:"nextAttempt"`
+	Delivered      *time.Time      `json:"delivered"`
+}
+
+// Succeeded records a successful attempt
+func (d *WebhookDelivery) Succeeded(now time.Time, status int) {
+	d.Attempts++
+	d.ResponseStatus = status
+	d.Error = ""
+	d.Status = DeliveredDelivery
+	d.Delivered = &now
+}
+
+// Failed records a failed attempt, the delivery is retried with exponential backoff until it is dead
+func (d *WebhookDelivery) Failed(now time.Time, status int, err error) {
+	d.Attempts++
+	d.ResponseStatus = status
+	d.Error = err.Error()
+	if d.Attempts >= MaxWebhookAttempts {
+		d.Status = DeadDelivery
+		return
+	}
+	d.Status = PendingDelivery
+	d.NextAttempt = now.Add(WebhookBackoff << uint(d.Attempts-1))
+}
+
+// WebhookService database services, DueDeliveries are the pending deliveries whose next attempt is due
+type WebhookService interface {
+	Webhook(id int) (*Webhook, error)
+	Webhooks(userID int) ([]*Webhook, error)
+	InsertWebhook(webhook *Webhook) error
+	DeleteWebhook(id int) error
+	Delivery(id int) (*WebhookDelivery, error)
+	Deliveries(webhookID int) ([]*WebhookDelivery, error)
+	DueDeliveries(now time.Time) ([]*WebhookDelivery, error)
+	InsertDelivery(delivery *WebhookDelivery) error
+	UpdateDelivery(delivery *WebhookDelivery) error
+}
+
+// WebhookSender sends a delivery to a webhook, returning the response status
+type WebhookSender interface {
+	Send(webhook *Webhook, delivery *WebhookDelivery) (int, error)
+}
@@ -0,0 +1,206 @@
+package catalogue
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// header the columns of a drug list, strengths and forms are separated by semicolons
+var header = []string{"id", "name", "generic", "strengths", "forms", "rxnorm", "din"}
+
+// Catalogue an in memory implementation of domain.MedicationService loaded from a local drug list
+type Catalogue struct {
+	mu          sync.RWMutex
+	medications []*domain.Medication
+}
+
+// Load imports the catalogue from a CSV file
+func (c *Catalogue) Load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return c.Import(f)
+}
+
+// Import replaces the catalogue with the medications of a CSV file with id, name, generic, strengths, forms, rxnorm and din columns
+func (c *Catalogue) Import(r io.Reader) error {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 || strings.Join(records[0], ",") != strings.Join(header, ",") {
+		return fmt.Errorf("the first line must be %s", strings.Join(header, ","))
+	}
+
+	ids := map[string]bool{}
+	medications := []*domain.Medication{}
+	for i, record := range records[1:] {
+		if len(record) != len(header) {
+			return fmt.Errorf("line %d must have %d columns", i+2, len(header))
+		}
+		m := &domain.Medication{
+			ID:          strings.TrimSpace(record[0]),
+			Name:        strings.TrimSpace(record[1]),
+			GenericName: strings.TrimSpace(record[2]),
+			Strengths:   list(record[3]),
+			Forms:       list(record[4]),
+			Codes:       map[string]string{},
+		}
+		if m.ID == "" || m.Name == "" {
+			return fmt.Errorf("line %d must have an id and a name", i+2)
+		}
+		if ids[m.ID] {
+			return fmt.Errorf("line %d has a duplicate id %s", i+2, m.ID)
+		}
+		ids[m.ID] = true
+		for j, system := range header[5:] {
+			if code := strings.TrimSpace(record[5+j]); code != "" {
+				m.Codes[system] = code
+			}
+		}
+		medications = append(medications, m)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.medications = medications
+	return nil
+}
+
+// Medication retrieves a medication, returns nil if it isn't in the catalogue
+func (c *Catalogue) Medication(id string) (*domain.Medication, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, m := range c.medications {
+		if m.ID == id {
+			return m, nil
+		}
+	}
+	return nil, nil
+}
+
+// SearchMedications finds medications whose name or generic name start with the query, then the ones with a word
+// starting with it and finally the ones with a word within a typo or two of it, best matches first
+func (c *Catalogue) SearchMedications(query string, limit int) ([]*domain.Medication, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	query = strings.ToLower(strings.TrimSpace(query))
+	type match struct {
+		medication *domain.Medication
+		score      int
+	}
+	matches := []match{}
+	for _, m := range c.medications {
+		if score, ok := score(m, query); ok {
+			matches = append(matches, match{m, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score < matches[j].score
+		}
+		return strings.ToLower(matches[i].medication.Name) < strings.ToLower(matches[j].medication.Name)
+	})
+
+	medications := []*domain.Medication{}
+	for _, m := range matches {
+		if len(medications) == limit {
+			break
+		}
+		medications = append(medications, m.medication)
+	}
+	return medications, nil
+}
+
+// score how well a medication matches a query, lower is better
+func score(m *domain.Medication, query string) (int, bool) {
+	name, generic := strings.ToLower(m.Name), strings.ToLower(m.GenericName)
+	switch {
+	case query == "":
+		return 0, true
+	case strings.HasPrefix(name, query):
+		return 0, true
+	case generic != "" && strings.HasPrefix(generic, query):
+		return 1, true
+	}
+
+	words := strings.Fields(name + " " + generic)
+	for _, w := range words {
+		if strings.HasPrefix(w, query) {
+			return 2, true
+		}
+	}
+
+	// typos are only forgiven once the query is long enough to mean something
+	allowed := 0
+	switch {
+	case len(query) >= 8:
+		allowed = 2
+	case len(query) >= 4:
+		allowed = 1
+	}
+	best := allowed + 1
+	for _, w := range words {
+		// compare against prefixes a letter shorter or longer than the query to forgive a missing or extra letter
+		for n := len(query) - 1; n <= len(query)+1; n++ {
+			if n < 1 || n > len(w) {
+				continue
+			}
+			if d := distance(w[:n], query); d < best {
+				best = d
+			}
+		}
+	}
+	return 2 + best, best <= allowed
+}
+
+// distance the Levenshtein distance between a and b
+func distance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minimum(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func minimum(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+func list(s string) []string {
+	values := []string{}
+	for _, v := range strings.Split(s, ";") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
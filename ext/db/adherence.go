@@ -0,0 +1,36 @@
+package db
+
+import (
+	"sync"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+var pillEvents = []*domain.PillEvent{}
+var pillEventsMu sync.Mutex
+
+// PillEventService implementation of domain.PillEventService
+type PillEventService struct {
+}
+
+// PillEvents retrieves the doses taken of a pill
+func (s *PillEventService) PillEvents(pillID int) ([]*domain.PillEvent, error) {
+	pillEventsMu.Lock()
+	defer pillEventsMu.Unlock()
+	events := []*domain.PillEvent{}
+	for _, e := range pillEvents {
+		if e.PillID == pillID {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// InsertPillEvent inserts a dose taken into the database
+func (s *PillEventService) InsertPillEvent(pillEvent *domain.PillEvent) error {
+	pillEventsMu.Lock()
+	defer pillEventsMu.Unlock()
+	pillEvent.ID = len(pillEvents) + 1
+	pillEvents = append(pillEvents, pillEvent)
+	return nil
+}
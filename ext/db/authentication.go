@@ -6,6 +6,9 @@ type AuthenticationService struct {
 
 //Authenticate authenticates a user with credentials
 func (s *AuthenticationService) Authenticate(email string, password string) (bool, error) {
+	usersMu.Lock()
+	defer usersMu.Unlock()
+
 	for _, u := range users {
 		if u.Email == email {
 			if u.Password == password {
@@ -19,6 +22,9 @@ func (s *AuthenticationService) Authenticate(email string, password string) (boo
 
 // EmailAvailable checks email availability
 func (s *AuthenticationService) EmailAvailable(email string) (bool, error) {
+	usersMu.Lock()
+	defer usersMu.Unlock()
+
 	for _, u := range users {
 		if u.Email == email {
 			return false, nil
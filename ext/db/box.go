@@ -0,0 +1,59 @@
+package db
+
+import (
+	"sync"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+var openEvents = []*domain.OpenEvent{}
+var closeEvents = []*domain.CloseEvent{}
+var boxMu sync.Mutex
+
+// BoxService implementation of domain.BoxService
+type BoxService struct {
+}
+
+// InsertOpenEvent inserts an open event into the database
+func (s *BoxService) InsertOpenEvent(openEvent *domain.OpenEvent) error {
+	boxMu.Lock()
+	defer boxMu.Unlock()
+	openEvent.ID = len(openEvents) + 1
+	openEvents = append(openEvents, openEvent)
+	return nil
+}
+
+// InsertCloseEvent inserts a close event into the database
+func (s *BoxService) InsertCloseEvent(closeEvent *domain.CloseEvent) error {
+	boxMu.Lock()
+	defer boxMu.Unlock()
+	closeEvent.ID = len(closeEvents) + 1
+	closeEvents = append(closeEvents, closeEvent)
+	return nil
+}
+
+// OpenEvents retrieves the open events of a user
+func (s *BoxService) OpenEvents(userID int) ([]*domain.OpenEvent, error) {
+	boxMu.Lock()
+	defer boxMu.Unlock()
+	events := []*domain.OpenEvent{}
+	for _, e := range openEvents {
+		if e.UserID == userID {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// CloseEvents retrieves the close events of a user
+func (s *BoxService) CloseEvents(userID int) ([]*domain.CloseEvent, error) {
+	boxMu.Lock()
+	defer boxMu.Unlock()
+	events := []*domain.CloseEvent{}
+	for _, e := range closeEvents {
+		if e.UserID == userID {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
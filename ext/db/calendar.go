@@ -0,0 +1,55 @@
+package db
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// FeedTokenService in memory implementation of domain.FeedTokenService
+type FeedTokenService struct {
+	mu     sync.Mutex
+	tokens map[int]*domain.FeedToken
+}
+
+// FeedToken retrieves a feed token, returns nil if the token doesn't exist or has been revoked
+func (s *FeedTokenService) FeedToken(token string) (*domain.FeedToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.tokens {
+		if t.Token == token {
+			return t, nil
+		}
+	}
+	return nil, nil
+}
+
+// IssueFeedToken creates a feed token for the user, replacing their previous token
+func (s *FeedTokenService) IssueFeedToken(userID int) (*domain.FeedToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tokens == nil {
+		s.tokens = map[int]*domain.FeedToken{}
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	t := &domain.FeedToken{Token: token, UserID: userID, Created: time.Now()}
+	s.tokens[userID] = t
+	return t, nil
+}
+
+// RevokeFeedToken revokes the user's feed token
+func (s *FeedTokenService) RevokeFeedToken(userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tokens, userID)
+	return nil
+}
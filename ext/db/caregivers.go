@@ -0,0 +1,103 @@
+package db
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// caregivers the caregiver links of each patient, including the invitations which haven't been accepted
+var caregivers = map[int][]*domain.CaregiverLink{}
+
+// caregiversMu guards caregivers
+var caregiversMu sync.Mutex
+
+// CaregiverService implementation of domain.CaregiverService
+type CaregiverService struct {
+}
+
+// Caregivers retrieves a patient's caregivers who have accepted, unverified and archived caregivers are left out
+func (s *CaregiverService) Caregivers(patientID int) ([]*domain.User, error) {
+	caregiversMu.Lock()
+	ids := []int{}
+	for _, l := range caregivers[patientID] {
+		if l.Accepted {
+			ids = append(ids, l.CaregiverID)
+		}
+	}
+	caregiversMu.Unlock()
+
+	usersMu.Lock()
+	defer usersMu.Unlock()
+
+	patientCaregivers := []*domain.User{}
+	for _, id := range ids {
+		for _, u := range users {
+			if u.ID == id && !u.Archived && u.Verified {
+				caregiver := *u
+				patientCaregivers = append(patientCaregivers, &caregiver)
+			}
+		}
+	}
+	return patientCaregivers, nil
+}
+
+// CaregiverLinks retrieves a patient's caregivers and the invitations which haven't been accepted
+func (s *CaregiverService) CaregiverLinks(patientID int) ([]*domain.CaregiverLink, error) {
+	caregiversMu.Lock()
+	defer caregiversMu.Unlock()
+
+	links := []*domain.CaregiverLink{}
+	for _, l := range caregivers[patientID] {
+		link := *l
+		links = append(links, &link)
+	}
+	return links, nil
+}
+
+// InviteCaregiver invites a caregiver of a patient, inviting them again has no effect
+func (s *CaregiverService) InviteCaregiver(patientID int, caregiverID int) error {
+	caregiversMu.Lock()
+	defer caregiversMu.Unlock()
+
+	for _, l := range caregivers[patientID] {
+		if l.CaregiverID == caregiverID {
+			return nil
+		}
+	}
+	link := &domain.CaregiverLink{PatientID: patientID, CaregiverID: caregiverID, Invited: time.Now()}
+	caregivers[patientID] = append(caregivers[patientID], link)
+	return nil
+}
+
+// AcceptCaregiver accepts the invitation of a caregiver, returns false if they weren't invited
+func (s *CaregiverService) AcceptCaregiver(patientID int, caregiverID int) (bool, error) {
+	caregiversMu.Lock()
+	defer caregiversMu.Unlock()
+
+	for i, l := range caregivers[patientID] {
+		if l.CaregiverID == caregiverID {
+			accepted := *l
+			accepted.Accepted = true
+			caregivers[patientID][i] = &accepted
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RemoveCaregiver unlinks a caregiver from a patient or withdraws their invitation
+func (s *CaregiverService) RemoveCaregiver(patientID int, caregiverID int) error {
+	caregiversMu.Lock()
+	defer caregiversMu.Unlock()
+
+	links := []*domain.CaregiverLink{}
+	for _, l := range caregivers[patientID] {
+		if l.CaregiverID != caregiverID {
+			links = append(links, l)
+		}
+	}
+	caregivers[patientID] = links
+	return nil
+}
@@ -0,0 +1,145 @@
+package db
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// DeviceService in memory implementation of domain.DeviceService, devices are copied in and out of the store
+type DeviceService struct {
+	mu       sync.Mutex
+	devices  []*domain.Device
+	commands []*domain.DeviceCommand
+}
+
+// Device retrieves a device, returns nil if the device doesn't exist
+func (s *DeviceService) Device(id int) (*domain.Device, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, d := range s.devices {
+		if d.ID == id {
+			device := *d
+			return &device, nil
+		}
+	}
+	return nil, nil
+}
+
+// Devices retrieves the devices registered to a user
+func (s *DeviceService) Devices(userID int) ([]*domain.Device, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	devices := []*domain.Device{}
+	for _, d := range s.devices {
+		if d.UserID == userID {
+			device := *d
+			devices = append(devices, &device)
+		}
+	}
+	return devices, nil
+}
+
+// InsertDevice registers a device, generating its id and secret. Only the hash of the secret is stored
+func (s *DeviceService) InsertDevice(device *domain.Device) error {
+	secret, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	device.ID = len(s.devices) + 1
+	for _, d := range s.devices {
+		if d.ID >= device.ID {
+			device.ID = d.ID + 1
+		}
+	}
+	device.Secret = secret
+	device.Registered = time.Now()
+	device.LastSeen = device.Registered
+	stored := *device
+	stored.Secret = domain.HashDeviceSecret(secret)
+	s.devices = append(s.devices, &stored)
+	return nil
+}
+
+// DeleteDevice deletes a device and the commands which haven't been delivered to it
+func (s *DeviceService) DeleteDevice(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, d := range s.devices {
+		if d.ID != id {
+			continue
+		}
+		s.devices = append(s.devices[:i], s.devices[i+1:]...)
+
+		commands := []*domain.DeviceCommand{}
+		for _, c := range s.commands {
+			if c.DeviceID != id || c.Delivered != nil {
+				commands = append(commands, c)
+			}
+		}
+		s.commands = commands
+		return nil
+	}
+	return errors.New("device not found")
+}
+
+// TouchDevice updates when a device was last seen
+func (s *DeviceService) TouchDevice(id int, lastSeen time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, d := range s.devices {
+		if d.ID == id {
+			d.LastSeen = lastSeen
+			return nil
+		}
+	}
+	return errors.New("device not found")
+}
+
+// InsertCommand queues a command, generating its id
+func (s *DeviceService) InsertCommand(command *domain.DeviceCommand) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	command.ID = len(s.commands) + 1
+	s.commands = append(s.commands, command)
+	return nil
+}
+
+// PendingCommands retrieves the commands of a device which haven't been delivered, oldest first
+func (s *DeviceService) PendingCommands(deviceID int) ([]*domain.DeviceCommand, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	commands := []*domain.DeviceCommand{}
+	for _, c := range s.commands {
+		if c.DeviceID == deviceID && c.Delivered == nil {
+			commands = append(commands, c)
+		}
+	}
+	return commands, nil
+}
+
+// DeliverCommand records when a command was delivered
+func (s *DeviceService) DeliverCommand(id int, delivered time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.commands {
+		if c.ID == id {
+			c.Delivered = &delivered
+			return nil
+		}
+	}
+	return errors.New("command not found")
+}
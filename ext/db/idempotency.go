@@ -0,0 +1,63 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// IdempotencyService in memory implementation of domain.IdempotencyService, use a shared implementation when
+// running multiple replicas. Records are copied in and out so a record is only completed through Complete
+type IdempotencyService struct {
+	mu      sync.Mutex
+	records map[string]*domain.IdempotencyRecord
+}
+
+// Begin stores the record of a new request, returns the unexpired record when the user has already used the key
+func (s *IdempotencyService) Begin(record *domain.IdempotencyRecord) (*domain.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.records == nil {
+		s.records = map[string]*domain.IdempotencyRecord{}
+	}
+
+	now := time.Now()
+	for k, r := range s.records {
+		if !now.Before(r.Expires) {
+			delete(s.records, k)
+		}
+	}
+
+	k := idempotencyKey(record.UserID, record.Key)
+	if r, ok := s.records[k]; ok {
+		existing := *r
+		return &existing, nil
+	}
+	stored := *record
+	s.records[k] = &stored
+	return nil, nil
+}
+
+// Complete stores the response of a record
+func (s *IdempotencyService) Complete(record *domain.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *record
+	s.records[idempotencyKey(record.UserID, record.Key)] = &stored
+	return nil
+}
+
+// Release removes the record of a key so the request can be retried
+func (s *IdempotencyService) Release(userID int, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, idempotencyKey(userID, key))
+	return nil
+}
+
+func idempotencyKey(userID int, key string) string {
+	return fmt.Sprintf("%d:%s", userID, key)
+}
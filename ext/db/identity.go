@@ -0,0 +1,68 @@
+package db
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+var identities = []*domain.Identity{}
+
+// IdentityService implementation of domain.IdentityService
+type IdentityService struct {
+}
+
+// Identity retrieves the identity linked to a provider's subject, nil if it hasn't been linked
+func (s *IdentityService) Identity(provider string, subject string) (*domain.Identity, error) {
+	for _, i := range identities {
+		if i.Provider == provider && i.Subject == subject {
+			return i, nil
+		}
+	}
+	return nil, nil
+}
+
+// InsertIdentity links an identity to a user
+func (s *IdentityService) InsertIdentity(identity *domain.Identity) error {
+	for _, i := range identities {
+		if i.Provider == identity.Provider && i.Subject == identity.Subject {
+			return errors.New("identity already linked")
+		}
+	}
+	identities = append(identities, identity)
+	return nil
+}
+
+// AuthorizationStateService in memory implementation of domain.AuthorizationStateService
+type AuthorizationStateService struct {
+	mu     sync.Mutex
+	states map[string]*domain.AuthorizationState
+}
+
+// InsertState stores the state until the provider calls back
+func (s *AuthorizationStateService) InsertState(state *domain.AuthorizationState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.states == nil {
+		s.states = map[string]*domain.AuthorizationState{}
+	}
+	s.states[state.State] = state
+	return nil
+}
+
+// TakeState retrieves and removes a state, nil if it doesn't exist or has expired
+func (s *AuthorizationStateService) TakeState(state string) (*domain.AuthorizationState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.states[state]
+	if !ok {
+		return nil, nil
+	}
+	delete(s.states, state)
+	if time.Now().After(st.Expires) {
+		return nil, nil
+	}
+	return st, nil
+}
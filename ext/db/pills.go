@@ -2,31 +2,43 @@ package db
 
 import (
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/jacsmith21/lukabox/domain"
 )
 
-// PillService implementation of domain.PillService
+// PillService implementation of domain.PillService, pills are copied in and out of the store so the stored
+// pills are never changed in place
 type PillService struct {
 }
 
 var pills = []*domain.Pill{
-	{ID: 1, UserID: 1, Name: "DoxyPoxy", DaysOfWeek: []int{1, 2, 3, 4, 5, 6, 7}, TimesOfDay: []time.Time{time.Now()}, Archived: false},
+	{ID: 1, UserID: 1, Name: "DoxyPoxy", DaysOfWeek: []int{1, 2, 3, 4, 5, 6, 7}, TimesOfDay: []domain.TimeOfDay{{Hour: 8}}, Archived: false},
 }
 
+// pillsMu guards pills, the pills are archived from a background goroutine
+var pillsMu sync.Mutex
+
 //CreatePill creates a pill in the database
 func (s *PillService) CreatePill(pill *domain.Pill) error {
+	pillsMu.Lock()
+	defer pillsMu.Unlock()
 	pill.ID = pills[len(pills)-1].ID + 1
-	pills = append(pills, pill)
+	pill.Version = 1
+	stored := *pill
+	pills = append(pills, &stored)
 	return nil
 }
 
 //Pill retrieves a pill from the database
 func (s *PillService) Pill(id int) (*domain.Pill, error) {
+	pillsMu.Lock()
+	defer pillsMu.Unlock()
 	for _, p := range pills {
 		if p.ID == id {
-			return p, nil
+			pill := *p
+			return &pill, nil
 		}
 	}
 	return nil, errors.New("pill not found")
@@ -34,20 +46,100 @@ func (s *PillService) Pill(id int) (*domain.Pill, error) {
 
 //Pills retrieves a user's pills from the database
 func (s *PillService) Pills(id int) ([]*domain.Pill, error) {
+	pillsMu.Lock()
+	defer pillsMu.Unlock()
 	userPills := []*domain.Pill{}
 	for _, p := range pills {
 		if p.UserID == id {
-			userPills = append(userPills, p)
+			pill := *p
+			userPills = append(userPills, &pill)
 		}
 	}
 	return userPills, nil
 }
 
-//UpdatePill updates a pill in the datbase
+//UpdatePill updates a pill in the datbase, the pill must be at the stored version
 func (s *PillService) UpdatePill(id int, pill *domain.Pill) error {
+	pillsMu.Lock()
+	defer pillsMu.Unlock()
 	for i, p := range pills {
 		if p.ID == id {
-			pills[i] = pill
+			if pill.Version != p.Version {
+				return domain.ErrStale
+			}
+			pill.Version = p.Version + 1
+			stored := *pill
+			pills[i] = &stored
+			return nil
+		}
+	}
+	return errors.New("pill not found")
+}
+
+//ArchiveEndedPills archives the pills whose course has ended, returns how many were archived
+func (s *PillService) ArchiveEndedPills(now time.Time) (int, error) {
+	pillsMu.Lock()
+	defer pillsMu.Unlock()
+	archived := 0
+	for i, p := range pills {
+		if !p.Archived && p.Ended(now.In(userLocation(p.UserID))) {
+			stored := *p
+			stored.Archived = true
+			stored.Version++
+			pills[i] = &stored
+			archived++
+		}
+	}
+	return archived, nil
+}
+
+// userLocation the location of a user, pills are archived once their course has ended in the user's timezone
+func userLocation(id int) *time.Location {
+	usersMu.Lock()
+	defer usersMu.Unlock()
+
+	for _, u := range users {
+		if u.ID == id {
+			return u.Location()
+		}
+	}
+	return time.UTC
+}
+
+var adjustments = []*domain.StockAdjustment{}
+var adjustmentsMu sync.Mutex
+
+// StockService implementation of domain.StockService
+type StockService struct {
+}
+
+// Adjustments retrieves the stock adjustments of a pill
+func (s *StockService) Adjustments(pillID int) ([]*domain.StockAdjustment, error) {
+	adjustmentsMu.Lock()
+	defer adjustmentsMu.Unlock()
+	pillAdjustments := []*domain.StockAdjustment{}
+	for _, a := range adjustments {
+		if a.PillID == pillID {
+			pillAdjustments = append(pillAdjustments, a)
+		}
+	}
+	return pillAdjustments, nil
+}
+
+// InsertAdjustment inserts a stock adjustment and applies it to the pill's quantity
+func (s *StockService) InsertAdjustment(adjustment *domain.StockAdjustment) error {
+	adjustmentsMu.Lock()
+	defer adjustmentsMu.Unlock()
+	pillsMu.Lock()
+	defer pillsMu.Unlock()
+	for i, p := range pills {
+		if p.ID == adjustment.PillID {
+			stored := *p
+			stored.Quantity += adjustment.Change
+			stored.Version++
+			pills[i] = &stored
+			adjustment.ID = len(adjustments) + 1
+			adjustments = append(adjustments, adjustment)
 			return nil
 		}
 	}
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter in memory implementation of domain.RateLimiter, use a shared implementation when running multiple replicas
+type RateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// Allow takes a token from the key's bucket, returns how long to wait when the bucket is empty
+func (s *RateLimiter) Allow(key string, policy domain.RateLimitPolicy) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.buckets == nil {
+		s.buckets = map[string]*bucket{}
+	}
+
+	now := time.Now()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(policy.Capacity), last: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens += float64(now.Sub(b.last)) / float64(policy.Interval)
+	if b.tokens > float64(policy.Capacity) {
+		b.tokens = float64(policy.Capacity)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) * float64(policy.Interval))
+		return false, wait, nil
+	}
+
+	b.tokens--
+	return true, 0, nil
+}
+
+// Reset refills the key's bucket
+func (s *RateLimiter) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.buckets, key)
+	return nil
+}
+
+// LockoutService in memory implementation of domain.LockoutService
+type LockoutService struct {
+	Policy domain.LockoutPolicy
+
+	mu       sync.Mutex
+	lockouts map[string]*domain.Lockout
+}
+
+// Lockout retrieves the lockout for an email, nil if there have been no failures
+func (s *LockoutService) Lockout(email string) (*domain.Lockout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lockouts[email], nil
+}
+
+// Fail records a failed authentication attempt and locks the email once the threshold has been reached
+func (s *LockoutService) Fail(email string) (*domain.Lockout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lockouts == nil {
+		s.lockouts = map[string]*domain.Lockout{}
+	}
+
+	l, ok := s.lockouts[email]
+	if !ok {
+		l = &domain.Lockout{Email: email}
+		s.lockouts[email] = l
+	}
+
+	l.Failures++
+	if l.Failures < s.Policy.Threshold {
+		return l, nil
+	}
+
+	duration := s.Policy.Duration << uint(l.Failures-s.Policy.Threshold)
+	if duration > s.Policy.Max || duration <= 0 {
+		duration = s.Policy.Max
+	}
+	l.Until = time.Now().Add(duration)
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	l.Token = token
+
+	return l, nil
+}
+
+// Clear removes the lockout for an email
+func (s *LockoutService) Clear(email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.lockouts, email)
+	return nil
+}
+
+// Unlock removes the lockout with the given unlock token
+func (s *LockoutService) Unlock(token string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for email, l := range s.lockouts {
+		if l.Token != "" && l.Token == token {
+			delete(s.lockouts, email)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+var sessions = []*domain.Session{}
+var sessionsMu sync.Mutex
+
+// SessionService implementation of domain.SessionService
+type SessionService struct {
+}
+
+// Session retrieves a copy of a session, nil if it doesn't exist or has been revoked. Copies are returned since
+// TouchSession updates the stored session under the lock
+func (s *SessionService) Session(id string) (*domain.Session, error) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	for _, session := range sessions {
+		if session.ID == id {
+			found := *session
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+// Sessions retrieves copies of a user's sessions
+func (s *SessionService) Sessions(userID int) ([]*domain.Session, error) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	userSessions := []*domain.Session{}
+	for _, session := range sessions {
+		if session.UserID == userID {
+			found := *session
+			userSessions = append(userSessions, &found)
+		}
+	}
+	return userSessions, nil
+}
+
+// InsertSession creates a session with a random id
+func (s *SessionService) InsertSession(session *domain.Session) error {
+	id, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	session.ID = id
+	stored := *session
+	sessions = append(sessions, &stored)
+	return nil
+}
+
+// TouchSession updates when a session was last seen
+func (s *SessionService) TouchSession(id string, lastSeen time.Time) error {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	for _, session := range sessions {
+		if session.ID == id {
+			session.LastSeen = lastSeen
+			return nil
+		}
+	}
+	return nil
+}
+
+// DeleteSession revokes a session
+func (s *SessionService) DeleteSession(id string) error {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	for i, session := range sessions {
+		if session.ID == id {
+			sessions = append(sessions[:i], sessions[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// DeleteSessions revokes all of a user's sessions
+func (s *SessionService) DeleteSessions(userID int) error {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	remaining := []*domain.Session{}
+	for _, session := range sessions {
+		if session.UserID != userID {
+			remaining = append(remaining, session)
+		}
+	}
+	sessions = remaining
+	return nil
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// OneTimeTokenService in memory implementation of domain.OneTimeTokenService
+type OneTimeTokenService struct {
+	mu     sync.Mutex
+	tokens map[string]*domain.OneTimeToken
+}
+
+// Issue creates a token for the user, previous tokens issued to the user for the same purpose are used up
+func (s *OneTimeTokenService) Issue(userID int, purpose domain.TokenPurpose, ttl time.Duration) (*domain.OneTimeToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tokens == nil {
+		s.tokens = map[string]*domain.OneTimeToken{}
+	}
+
+	for _, t := range s.tokens {
+		if t.UserID == userID && t.Purpose == purpose {
+			t.Used = true
+		}
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	t := &domain.OneTimeToken{Token: token, UserID: userID, Purpose: purpose, Expires: time.Now().Add(ttl)}
+	s.tokens[token] = t
+	return t, nil
+}
+
+// Redeem uses up a token, returns nil if the token doesn't exist, has expired or has already been used
+func (s *OneTimeTokenService) Redeem(token string, purpose domain.TokenPurpose) (*domain.OneTimeToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tokens[token]
+	if !ok || t.Used || t.Purpose != purpose || time.Now().After(t.Expires) {
+		return nil, nil
+	}
+
+	t.Used = true
+	return t, nil
+}
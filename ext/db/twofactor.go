@@ -0,0 +1,106 @@
+package db
+
+import (
+	"sync"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+var twoFactors = []*domain.TwoFactor{}
+
+// twoFactorsMu guards twoFactors
+var twoFactorsMu sync.Mutex
+
+// TwoFactorService implementation of domain.TwoFactorService
+type TwoFactorService struct {
+}
+
+// copyTwoFactor copies a two factor authentication so callers can't change the stored one
+func copyTwoFactor(twoFactor *domain.TwoFactor) *domain.TwoFactor {
+	tf := *twoFactor
+	tf.RecoveryCodes = append([]string{}, twoFactor.RecoveryCodes...)
+	return &tf
+}
+
+// TwoFactor retrieves a user's two factor authentication, nil if the user hasn't started enrolling
+func (s *TwoFactorService) TwoFactor(userID int) (*domain.TwoFactor, error) {
+	twoFactorsMu.Lock()
+	defer twoFactorsMu.Unlock()
+
+	for _, tf := range twoFactors {
+		if tf.UserID == userID {
+			return copyTwoFactor(tf), nil
+		}
+	}
+	return nil, nil
+}
+
+// SaveTwoFactor creates or replaces a user's two factor authentication
+func (s *TwoFactorService) SaveTwoFactor(twoFactor *domain.TwoFactor) error {
+	twoFactorsMu.Lock()
+	defer twoFactorsMu.Unlock()
+
+	for i, tf := range twoFactors {
+		if tf.UserID == twoFactor.UserID {
+			twoFactors[i] = copyTwoFactor(twoFactor)
+			return nil
+		}
+	}
+	twoFactors = append(twoFactors, copyTwoFactor(twoFactor))
+	return nil
+}
+
+// DeleteTwoFactor removes a user's two factor authentication
+func (s *TwoFactorService) DeleteTwoFactor(userID int) error {
+	twoFactorsMu.Lock()
+	defer twoFactorsMu.Unlock()
+
+	for i, tf := range twoFactors {
+		if tf.UserID == userID {
+			twoFactors = append(twoFactors[:i], twoFactors[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// RedeemStep uses up a TOTP step, returns false if it isn't after the last step used
+func (s *TwoFactorService) RedeemStep(userID int, step int64) (bool, error) {
+	twoFactorsMu.Lock()
+	defer twoFactorsMu.Unlock()
+
+	for i, tf := range twoFactors {
+		if tf.UserID == userID {
+			if step <= tf.LastStep {
+				return false, nil
+			}
+			redeemed := copyTwoFactor(tf)
+			redeemed.LastStep = step
+			twoFactors[i] = redeemed
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RedeemRecoveryCode removes a hashed recovery code, returns false if the user doesn't have it
+func (s *TwoFactorService) RedeemRecoveryCode(userID int, hash string) (bool, error) {
+	twoFactorsMu.Lock()
+	defer twoFactorsMu.Unlock()
+
+	for i, tf := range twoFactors {
+		if tf.UserID != userID {
+			continue
+		}
+		for j, c := range tf.RecoveryCodes {
+			if c == hash {
+				redeemed := copyTwoFactor(tf)
+				redeemed.RecoveryCodes = append(redeemed.RecoveryCodes[:j], redeemed.RecoveryCodes[j+1:]...)
+				twoFactors[i] = redeemed
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, nil
+}
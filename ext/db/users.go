@@ -2,16 +2,20 @@ package db
 
 import (
 	"errors"
+	"sync"
 
 	"github.com/jacsmith21/lukabox/domain"
 )
 
 var users = []*domain.User{
-	{ID: 1, Email: "jacob.smith@unb.ca", Password: "password", FirstName: "Jacob", LastName: "Smith", Archived: false},
-	{ID: 2, Email: "j.a.smith@live.ca", Password: "password", FirstName: "Jacob", LastName: "Smith", Archived: false},
-	{ID: 3, Email: "jacobsmithunb@gmail.com", Password: "password", FirstName: "Jacob", LastName: "Smith", Archived: false},
+	{ID: 1, Email: "jacob.smith@unb.ca", Password: "password", FirstName: "Jacob", LastName: "Smith", Archived: false, Role: domain.PatientRole},
+	{ID: 2, Email: "j.a.smith@live.ca", Password: "password", FirstName: "Jacob", LastName: "Smith", Archived: false, Role: domain.PatientRole},
+	{ID: 3, Email: "jacobsmithunb@gmail.com", Password: "password", FirstName: "Jacob", LastName: "Smith", Archived: false, Role: domain.PatientRole},
 }
 
+// usersMu guards users, which are copied in and out so a user is only changed through UpdateUser
+var usersMu sync.Mutex
+
 //UserService represents an implementation UserService
 type UserService struct {
 }
@@ -21,21 +25,39 @@ func (s *UserService) InsertUser(user *domain.User) error {
 	if user.ID != 0 {
 		return errors.New("user id must equal 0")
 	}
+
+	usersMu.Lock()
+	defer usersMu.Unlock()
+
 	user.ID = users[len(users)-1].ID + 1
-	users = append(users, user)
+	user.Version = 1
+	stored := *user
+	users = append(users, &stored)
 	return nil
 }
 
 //Users retrieves a user from the database
 func (s *UserService) Users() ([]*domain.User, error) {
-	return users, nil
+	usersMu.Lock()
+	defer usersMu.Unlock()
+
+	copied := []*domain.User{}
+	for _, u := range users {
+		user := *u
+		copied = append(copied, &user)
+	}
+	return copied, nil
 }
 
 //UserByID retrieves a user from the database using their ID
 func (s *UserService) UserByID(id int) (*domain.User, error) {
+	usersMu.Lock()
+	defer usersMu.Unlock()
+
 	for _, u := range users {
 		if u.ID == id {
-			return u, nil
+			user := *u
+			return &user, nil
 		}
 	}
 	return nil, errors.New("user not found")
@@ -43,19 +65,32 @@ func (s *UserService) UserByID(id int) (*domain.User, error) {
 
 //UserByEmail retrieves a user from the database using their email
 func (s *UserService) UserByEmail(email string) (*domain.User, error) {
+	usersMu.Lock()
+	defer usersMu.Unlock()
+
 	for _, u := range users {
 		if u.Email == email {
-			return u, nil
+			user := *u
+			return &user, nil
 		}
 	}
 	return nil, errors.New("user not found")
 }
 
-//UpdateUser updates a user in the datbase
+//UpdateUser updates a user in the datbase, the user must be at the stored version. The version is checked
+//and bumped under the lock so only one of two concurrent updates of the same version is stored
 func (s *UserService) UpdateUser(id int, user *domain.User) error {
+	usersMu.Lock()
+	defer usersMu.Unlock()
+
 	for i, u := range users {
 		if u.ID == id {
-			users[i] = user
+			if user.Version != u.Version {
+				return domain.ErrStale
+			}
+			user.Version = u.Version + 1
+			stored := *user
+			users[i] = &stored
 			return nil
 		}
 	}
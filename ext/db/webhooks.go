@@ -0,0 +1,150 @@
+package db
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// WebhookService in memory implementation of domain.WebhookService, deliveries are copied in and out of the
+// store so deliveries being attempted aren't changed under the handlers listing them
+type WebhookService struct {
+	mu         sync.Mutex
+	webhooks   []*domain.Webhook
+	deliveries []*domain.WebhookDelivery
+}
+
+// Webhook retrieves a webhook, returns nil if the webhook doesn't exist
+func (s *WebhookService) Webhook(id int) (*domain.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, w := range s.webhooks {
+		if w.ID == id {
+			return w, nil
+		}
+	}
+	return nil, nil
+}
+
+// Webhooks retrieves the webhooks of a user
+func (s *WebhookService) Webhooks(userID int) ([]*domain.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhooks := []*domain.Webhook{}
+	for _, w := range s.webhooks {
+		if w.UserID == userID {
+			webhooks = append(webhooks, w)
+		}
+	}
+	return webhooks, nil
+}
+
+// InsertWebhook inserts a webhook, generating its id and signing secret
+func (s *WebhookService) InsertWebhook(webhook *domain.Webhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	webhook.ID = len(s.webhooks) + 1
+	for _, w := range s.webhooks {
+		if w.ID >= webhook.ID {
+			webhook.ID = w.ID + 1
+		}
+	}
+	webhook.Secret = secret
+	webhook.Created = time.Now()
+	s.webhooks = append(s.webhooks, webhook)
+	return nil
+}
+
+// DeleteWebhook deletes a webhook, its deliveries are kept in the log
+func (s *WebhookService) DeleteWebhook(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, w := range s.webhooks {
+		if w.ID == id {
+			s.webhooks = append(s.webhooks[:i], s.webhooks[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("webhook not found")
+}
+
+// Delivery retrieves a delivery, returns nil if the delivery doesn't exist
+func (s *WebhookService) Delivery(id int) (*domain.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, d := range s.deliveries {
+		if d.ID == id {
+			delivery := *d
+			return &delivery, nil
+		}
+	}
+	return nil, nil
+}
+
+// Deliveries retrieves the deliveries to a webhook, newest first
+func (s *WebhookService) Deliveries(webhookID int) ([]*domain.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deliveries := []*domain.WebhookDelivery{}
+	for i := len(s.deliveries) - 1; i >= 0; i-- {
+		if s.deliveries[i].WebhookID == webhookID {
+			delivery := *s.deliveries[i]
+			deliveries = append(deliveries, &delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+// DueDeliveries retrieves the pending deliveries whose next attempt is at or before now, oldest first
+func (s *WebhookService) DueDeliveries(now time.Time) ([]*domain.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deliveries := []*domain.WebhookDelivery{}
+	for _, d := range s.deliveries {
+		if d.Status == domain.PendingDelivery && !d.NextAttempt.After(now) {
+			delivery := *d
+			deliveries = append(deliveries, &delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+// InsertDelivery inserts a delivery, generating its id
+func (s *WebhookService) InsertDelivery(delivery *domain.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery.ID = len(s.deliveries) + 1
+	stored := *delivery
+	s.deliveries = append(s.deliveries, &stored)
+	return nil
+}
+
+// UpdateDelivery updates a delivery
+func (s *WebhookService) UpdateDelivery(delivery *domain.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, d := range s.deliveries {
+		if d.ID == delivery.ID {
+			stored := *delivery
+			s.deliveries[i] = &stored
+			return nil
+		}
+	}
+	return errors.New("delivery not found")
+}
@@ -0,0 +1,666 @@
+// The device protocol of lukabox boxes. The Go code in this package is generated from it
+// with go generate, regenerate it after changing this file.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: ext/devicepb/device.proto
+
+package devicepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type EventType int32
+
+const (
+	EventType_EVENT_TYPE_UNSPECIFIED EventType = 0
+	EventType_OPENED                 EventType = 1
+	EventType_CLOSED                 EventType = 2
+)
+
+// Enum value maps for EventType.
+var (
+	EventType_name = map[int32]string{
+		0: "EVENT_TYPE_UNSPECIFIED",
+		1: "OPENED",
+		2: "CLOSED",
+	}
+	EventType_value = map[string]int32{
+		"EVENT_TYPE_UNSPECIFIED": 0,
+		"OPENED":                 1,
+		"CLOSED":                 2,
+	}
+)
+
+func (x EventType) Enum() *EventType {
+	p := new(EventType)
+	*p = x
+	return p
+}
+
+func (x EventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_ext_devicepb_device_proto_enumTypes[0].Descriptor()
+}
+
+func (EventType) Type() protoreflect.EnumType {
+	return &file_ext_devicepb_device_proto_enumTypes[0]
+}
+
+func (x EventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EventType.Descriptor instead.
+func (EventType) EnumDescriptor() ([]byte, []int) {
+	return file_ext_devicepb_device_proto_rawDescGZIP(), []int{0}
+}
+
+type RegisterRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterRequest) Reset() {
+	*x = RegisterRequest{}
+	mi := &file_ext_devicepb_device_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterRequest) ProtoMessage() {}
+
+func (x *RegisterRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ext_devicepb_device_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterRequest.ProtoReflect.Descriptor instead.
+func (*RegisterRequest) Descriptor() ([]byte, []int) {
+	return file_ext_devicepb_device_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *RegisterRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type RegisterResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DeviceId      int64                  `protobuf:"varint,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	Secret        string                 `protobuf:"bytes,2,opt,name=secret,proto3" json:"secret,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterResponse) Reset() {
+	*x = RegisterResponse{}
+	mi := &file_ext_devicepb_device_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterResponse) ProtoMessage() {}
+
+func (x *RegisterResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ext_devicepb_device_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterResponse.ProtoReflect.Descriptor instead.
+func (*RegisterResponse) Descriptor() ([]byte, []int) {
+	return file_ext_devicepb_device_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RegisterResponse) GetDeviceId() int64 {
+	if x != nil {
+		return x.DeviceId
+	}
+	return 0
+}
+
+func (x *RegisterResponse) GetSecret() string {
+	if x != nil {
+		return x.Secret
+	}
+	return ""
+}
+
+// BoxEvent a compartment being opened or closed, time is in unix seconds
+type BoxEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          EventType              `protobuf:"varint,1,opt,name=type,proto3,enum=lukabox.device.v1.EventType" json:"type,omitempty"`
+	Compartment   int32                  `protobuf:"varint,2,opt,name=compartment,proto3" json:"compartment,omitempty"`
+	Time          int64                  `protobuf:"varint,3,opt,name=time,proto3" json:"time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BoxEvent) Reset() {
+	*x = BoxEvent{}
+	mi := &file_ext_devicepb_device_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BoxEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BoxEvent) ProtoMessage() {}
+
+func (x *BoxEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_ext_devicepb_device_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BoxEvent.ProtoReflect.Descriptor instead.
+func (*BoxEvent) Descriptor() ([]byte, []int) {
+	return file_ext_devicepb_device_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BoxEvent) GetType() EventType {
+	if x != nil {
+		return x.Type
+	}
+	return EventType_EVENT_TYPE_UNSPECIFIED
+}
+
+func (x *BoxEvent) GetCompartment() int32 {
+	if x != nil {
+		return x.Compartment
+	}
+	return 0
+}
+
+func (x *BoxEvent) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+type EventsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Accepted      int32                  `protobuf:"varint,1,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EventsResponse) Reset() {
+	*x = EventsResponse{}
+	mi := &file_ext_devicepb_device_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EventsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventsResponse) ProtoMessage() {}
+
+func (x *EventsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ext_devicepb_device_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EventsResponse.ProtoReflect.Descriptor instead.
+func (*EventsResponse) Descriptor() ([]byte, []int) {
+	return file_ext_devicepb_device_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *EventsResponse) GetAccepted() int32 {
+	if x != nil {
+		return x.Accepted
+	}
+	return 0
+}
+
+// ConfigurationRequest hours is how far ahead doses are included, 24 when unset
+type ConfigurationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hours         int32                  `protobuf:"varint,1,opt,name=hours,proto3" json:"hours,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfigurationRequest) Reset() {
+	*x = ConfigurationRequest{}
+	mi := &file_ext_devicepb_device_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigurationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigurationRequest) ProtoMessage() {}
+
+func (x *ConfigurationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ext_devicepb_device_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfigurationRequest.ProtoReflect.Descriptor instead.
+func (*ConfigurationRequest) Descriptor() ([]byte, []int) {
+	return file_ext_devicepb_device_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ConfigurationRequest) GetHours() int32 {
+	if x != nil {
+		return x.Hours
+	}
+	return 0
+}
+
+type Configuration struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Timezone          string                 `protobuf:"bytes,1,opt,name=timezone,proto3" json:"timezone,omitempty"`
+	DoseWindowMinutes int32                  `protobuf:"varint,2,opt,name=dose_window_minutes,json=doseWindowMinutes,proto3" json:"dose_window_minutes,omitempty"`
+	Doses             []*Dose                `protobuf:"bytes,3,rep,name=doses,proto3" json:"doses,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Configuration) Reset() {
+	*x = Configuration{}
+	mi := &file_ext_devicepb_device_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Configuration) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Configuration) ProtoMessage() {}
+
+func (x *Configuration) ProtoReflect() protoreflect.Message {
+	mi := &file_ext_devicepb_device_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Configuration.ProtoReflect.Descriptor instead.
+func (*Configuration) Descriptor() ([]byte, []int) {
+	return file_ext_devicepb_device_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Configuration) GetTimezone() string {
+	if x != nil {
+		return x.Timezone
+	}
+	return ""
+}
+
+func (x *Configuration) GetDoseWindowMinutes() int32 {
+	if x != nil {
+		return x.DoseWindowMinutes
+	}
+	return 0
+}
+
+func (x *Configuration) GetDoses() []*Dose {
+	if x != nil {
+		return x.Doses
+	}
+	return nil
+}
+
+type Dose struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PillId        int64                  `protobuf:"varint,1,opt,name=pill_id,json=pillId,proto3" json:"pill_id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Time          int64                  `protobuf:"varint,3,opt,name=time,proto3" json:"time,omitempty"`
+	Quantity      float64                `protobuf:"fixed64,4,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Dose) Reset() {
+	*x = Dose{}
+	mi := &file_ext_devicepb_device_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Dose) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Dose) ProtoMessage() {}
+
+func (x *Dose) ProtoReflect() protoreflect.Message {
+	mi := &file_ext_devicepb_device_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Dose.ProtoReflect.Descriptor instead.
+func (*Dose) Descriptor() ([]byte, []int) {
+	return file_ext_devicepb_device_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Dose) GetPillId() int64 {
+	if x != nil {
+		return x.PillId
+	}
+	return 0
+}
+
+func (x *Dose) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Dose) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *Dose) GetQuantity() float64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+type CommandsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandsRequest) Reset() {
+	*x = CommandsRequest{}
+	mi := &file_ext_devicepb_device_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandsRequest) ProtoMessage() {}
+
+func (x *CommandsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ext_devicepb_device_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandsRequest.ProtoReflect.Descriptor instead.
+func (*CommandsRequest) Descriptor() ([]byte, []int) {
+	return file_ext_devicepb_device_proto_rawDescGZIP(), []int{7}
+}
+
+type Command struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Compartment   int32                  `protobuf:"varint,3,opt,name=compartment,proto3" json:"compartment,omitempty"`
+	Created       int64                  `protobuf:"varint,4,opt,name=created,proto3" json:"created,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Command) Reset() {
+	*x = Command{}
+	mi := &file_ext_devicepb_device_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Command) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Command) ProtoMessage() {}
+
+func (x *Command) ProtoReflect() protoreflect.Message {
+	mi := &file_ext_devicepb_device_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Command.ProtoReflect.Descriptor instead.
+func (*Command) Descriptor() ([]byte, []int) {
+	return file_ext_devicepb_device_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Command) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Command) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Command) GetCompartment() int32 {
+	if x != nil {
+		return x.Compartment
+	}
+	return 0
+}
+
+func (x *Command) GetCreated() int64 {
+	if x != nil {
+		return x.Created
+	}
+	return 0
+}
+
+var File_ext_devicepb_device_proto protoreflect.FileDescriptor
+
+const file_ext_devicepb_device_proto_rawDesc = "" +
+	"\n" +
+	"\x19ext/devicepb/device.proto\x12\x11lukabox.device.v1\"%\n" +
+	"\x0fRegisterRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"G\n" +
+	"\x10RegisterResponse\x12\x1b\n" +
+	"\tdevice_id\x18\x01 \x01(\x03R\bdeviceId\x12\x16\n" +
+	"\x06secret\x18\x02 \x01(\tR\x06secret\"r\n" +
+	"\bBoxEvent\x120\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x1c.lukabox.device.v1.EventTypeR\x04type\x12 \n" +
+	"\vcompartment\x18\x02 \x01(\x05R\vcompartment\x12\x12\n" +
+	"\x04time\x18\x03 \x01(\x03R\x04time\",\n" +
+	"\x0eEventsResponse\x12\x1a\n" +
+	"\baccepted\x18\x01 \x01(\x05R\baccepted\",\n" +
+	"\x14ConfigurationRequest\x12\x14\n" +
+	"\x05hours\x18\x01 \x01(\x05R\x05hours\"\x8a\x01\n" +
+	"\rConfiguration\x12\x1a\n" +
+	"\btimezone\x18\x01 \x01(\tR\btimezone\x12.\n" +
+	"\x13dose_window_minutes\x18\x02 \x01(\x05R\x11doseWindowMinutes\x12-\n" +
+	"\x05doses\x18\x03 \x03(\v2\x17.lukabox.device.v1.DoseR\x05doses\"c\n" +
+	"\x04Dose\x12\x17\n" +
+	"\apill_id\x18\x01 \x01(\x03R\x06pillId\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x12\n" +
+	"\x04time\x18\x03 \x01(\x03R\x04time\x12\x1a\n" +
+	"\bquantity\x18\x04 \x01(\x01R\bquantity\"\x11\n" +
+	"\x0fCommandsRequest\"i\n" +
+	"\aCommand\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12 \n" +
+	"\vcompartment\x18\x03 \x01(\x05R\vcompartment\x12\x18\n" +
+	"\acreated\x18\x04 \x01(\x03R\acreated*?\n" +
+	"\tEventType\x12\x1a\n" +
+	"\x16EVENT_TYPE_UNSPECIFIED\x10\x00\x12\n" +
+	"\n" +
+	"\x06OPENED\x10\x01\x12\n" +
+	"\n" +
+	"\x06CLOSED\x10\x022\xe3\x02\n" +
+	"\x06Device\x12S\n" +
+	"\bRegister\x12\".lukabox.device.v1.RegisterRequest\x1a#.lukabox.device.v1.RegisterResponse\x12P\n" +
+	"\fStreamEvents\x12\x1b.lukabox.device.v1.BoxEvent\x1a!.lukabox.device.v1.EventsResponse(\x01\x12]\n" +
+	"\x10GetConfiguration\x12'.lukabox.device.v1.ConfigurationRequest\x1a .lukabox.device.v1.Configuration\x12S\n" +
+	"\x0fReceiveCommands\x12\".lukabox.device.v1.CommandsRequest\x1a\x1a.lukabox.device.v1.Command0\x01B,Z*github.com/jacsmith21/lukabox/ext/devicepbb\x06proto3"
+
+var (
+	file_ext_devicepb_device_proto_rawDescOnce sync.Once
+	file_ext_devicepb_device_proto_rawDescData []byte
+)
+
+func file_ext_devicepb_device_proto_rawDescGZIP() []byte {
+	file_ext_devicepb_device_proto_rawDescOnce.Do(func() {
+		file_ext_devicepb_device_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_ext_devicepb_device_proto_rawDesc), len(file_ext_devicepb_device_proto_rawDesc)))
+	})
+	return file_ext_devicepb_device_proto_rawDescData
+}
+
+var file_ext_devicepb_device_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_ext_devicepb_device_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_ext_devicepb_device_proto_goTypes = []any{
+	(EventType)(0),               // 0: lukabox.device.v1.EventType
+	(*RegisterRequest)(nil),      // 1: lukabox.device.v1.RegisterRequest
+	(*RegisterResponse)(nil),     // 2: lukabox.device.v1.RegisterResponse
+	(*BoxEvent)(nil),             // 3: lukabox.device.v1.BoxEvent
+	(*EventsResponse)(nil),       // 4: lukabox.device.v1.EventsResponse
+	(*ConfigurationRequest)(nil), // 5: lukabox.device.v1.ConfigurationRequest
+	(*Configuration)(nil),        // 6: lukabox.device.v1.Configuration
+	(*Dose)(nil),                 // 7: lukabox.device.v1.Dose
+	(*CommandsRequest)(nil),      // 8: lukabox.device.v1.CommandsRequest
+	(*Command)(nil),              // 9: lukabox.device.v1.Command
+}
+var file_ext_devicepb_device_proto_depIdxs = []int32{
+	0, // 0: lukabox.device.v1.BoxEvent.type:type_name -> lukabox.device.v1.EventType
+	7, // 1: lukabox.device.v1.Configuration.doses:type_name -> lukabox.device.v1.Dose
+	1, // 2: lukabox.device.v1.Device.Register:input_type -> lukabox.device.v1.RegisterRequest
+	3, // 3: lukabox.device.v1.Device.StreamEvents:input_type -> lukabox.device.v1.BoxEvent
+	5, // 4: lukabox.device.v1.Device.GetConfiguration:input_type -> lukabox.device.v1.ConfigurationRequest
+	8, // 5: lukabox.device.v1.Device.ReceiveCommands:input_type -> lukabox.device.v1.CommandsRequest
+	2, // 6: lukabox.device.v1.Device.Register:output_type -> lukabox.device.v1.RegisterResponse
+	4, // 7: lukabox.device.v1.Device.StreamEvents:output_type -> lukabox.device.v1.EventsResponse
+	6, // 8: lukabox.device.v1.Device.GetConfiguration:output_type -> lukabox.device.v1.Configuration
+	9, // 9: lukabox.device.v1.Device.ReceiveCommands:output_type -> lukabox.device.v1.Command
+	6, // [6:10] is the sub-list for method output_type
+	2, // [2:6] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_ext_devicepb_device_proto_init() }
+func file_ext_devicepb_device_proto_init() {
+	if File_ext_devicepb_device_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_ext_devicepb_device_proto_rawDesc), len(file_ext_devicepb_device_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_ext_devicepb_device_proto_goTypes,
+		DependencyIndexes: file_ext_devicepb_device_proto_depIdxs,
+		EnumInfos:         file_ext_devicepb_device_proto_enumTypes,
+		MessageInfos:      file_ext_devicepb_device_proto_msgTypes,
+	}.Build()
+	File_ext_devicepb_device_proto = out.File
+	file_ext_devicepb_device_proto_goTypes = nil
+	file_ext_devicepb_device_proto_depIdxs = nil
+}
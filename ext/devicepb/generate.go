@@ -0,0 +1,5 @@
+// Package devicepb the messages and grpc service of the device protocol, generated from device.proto
+// by protoc-gen-go and protoc-gen-go-grpc
+package devicepb
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative ext/devicepb/device.proto
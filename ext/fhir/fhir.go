@@ -0,0 +1,376 @@
+package fhir
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// ContentType the media type of FHIR JSON
+const ContentType = "application/fhir+json"
+
+const (
+	snomedSystem = "http://snomed.info/sct"
+	ucumSystem   = "http://unitsofmeasure.org"
+	dateLayout   = "2006-01-02"
+)
+
+// routes SNOMED CT codes of the routes of administration
+var routes = map[string]Coding{
+	"oral":          {System: snomedSystem, Code: "26643006", Display: "Oral route"},
+	"sublingual":    {System: snomedSystem, Code: "37839007", Display: "Sublingual route"},
+	"topical":       {System: snomedSystem, Code: "6064005", Display: "Topical route"},
+	"inhaled":       {System: snomedSystem, Code: "447694001", Display: "Respiratory tract route"},
+	"subcutaneous":  {System: snomedSystem, Code: "34206005", Display: "Subcutaneous route"},
+	"intramuscular": {System: snomedSystem, Code: "78421000", Display: "Intramuscular route"},
+	"intravenous":   {System: snomedSystem, Code: "47625008", Display: "Intravenous route"},
+	"rectal":        {System: snomedSystem, Code: "37161004", Display: "Rectal route"},
+}
+
+// units UCUM codes of the units of strength
+var units = map[string]string{
+	"mg": "mg", "mcg": "ug", "g": "g", "ml": "mL", "iu": "[iU]", "units": "[U]",
+}
+
+var weekdays = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+
+// Reference a reference to another resource
+type Reference struct {
+	Reference string `json:"reference,omitempty"`
+	Display   string `json:"display,omitempty"`
+}
+
+// Coding a code from a terminology
+type Coding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+// CodeableConcept a concept as codes and text
+type CodeableConcept struct {
+	Coding []Coding `json:"coding,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+// Quantity a measured amount
+type Quantity struct {
+	Value  float64 `json:"value"`
+	Unit   string  `json:"unit,omitempty"`
+	System string  `json:"system,omitempty"`
+	Code   string  `json:"code,omitempty"`
+}
+
+// Period a range of dates, either end may be open
+type Period struct {
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+// HumanName the name of a person
+type HumanName struct {
+	Family string   `json:"family,omitempty"`
+	Given  []string `json:"given,omitempty"`
+}
+
+// ContactPoint a way of contacting a person
+type ContactPoint struct {
+	System string `json:"system"`
+	Value  string `json:"value"`
+}
+
+// Timing when doses are taken
+type Timing struct {
+	Repeat *TimingRepeat `json:"repeat,omitempty"`
+}
+
+// TimingRepeat a repeating schedule
+type TimingRepeat struct {
+	BoundsPeriod *Period  `json:"boundsPeriod,omitempty"`
+	Frequency    int      `json:"frequency,omitempty"`
+	Period       int      `json:"period,omitempty"`
+	PeriodUnit   string   `json:"periodUnit,omitempty"`
+	DayOfWeek    []string `json:"dayOfWeek,omitempty"`
+	TimeOfDay    []string `json:"timeOfDay,omitempty"`
+}
+
+// DoseAndRate the amount of a dose
+type DoseAndRate struct {
+	DoseQuantity *Quantity `json:"doseQuantity,omitempty"`
+}
+
+// Dosage how a medication is taken
+type Dosage struct {
+	Text               string           `json:"text,omitempty"`
+	PatientInstruction string           `json:"patientInstruction,omitempty"`
+	Timing             *Timing          `json:"timing,omitempty"`
+	AsNeededBoolean    bool             `json:"asNeededBoolean,omitempty"`
+	Route              *CodeableConcept `json:"route,omitempty"`
+	DoseAndRate        []DoseAndRate    `json:"doseAndRate,omitempty"`
+}
+
+// AdministrationDosage the dose of a single administration
+type AdministrationDosage struct {
+	Route *CodeableConcept `json:"route,omitempty"`
+	Dose  *Quantity        `json:"dose,omitempty"`
+}
+
+// Patient a FHIR R4 Patient
+type Patient struct {
+	ResourceType string         `json:"resourceType"`
+	ID           string         `json:"id"`
+	Active       bool           `json:"active"`
+	Name         []HumanName    `json:"name,omitempty"`
+	Telecom      []ContactPoint `json:"telecom,omitempty"`
+}
+
+// MedicationStatement a FHIR R4 MedicationStatement
+type MedicationStatement struct {
+	ResourceType              string           `json:"resourceType"`
+	ID                        string           `json:"id"`
+	Status                    string           `json:"status"`
+	MedicationCodeableConcept *CodeableConcept `json:"medicationCodeableConcept"`
+	Subject                   *Reference       `json:"subject"`
+	EffectivePeriod           *Period          `json:"effectivePeriod,omitempty"`
+	DateAsserted              string           `json:"dateAsserted,omitempty"`
+	BasedOn                   []Reference      `json:"basedOn,omitempty"`
+	Dosage                    []Dosage         `json:"dosage,omitempty"`
+}
+
+// MedicationRequest a FHIR R4 MedicationRequest
+type MedicationRequest struct {
+	ResourceType              string           `json:"resourceType"`
+	ID                        string           `json:"id"`
+	Status                    string           `json:"status"`
+	Intent                    string           `json:"intent"`
+	MedicationCodeableConcept *CodeableConcept `json:"medicationCodeableConcept"`
+	Subject                   *Reference       `json:"subject"`
+	Requester                 *Reference       `json:"requester,omitempty"`
+	DosageInstruction         []Dosage         `json:"dosageInstruction,omitempty"`
+}
+
+// MedicationAdministration a FHIR R4 MedicationAdministration
+type MedicationAdministration struct {
+	ResourceType              string                `json:"resourceType"`
+	ID                        string                `json:"id"`
+	Status                    string                `json:"status"`
+	MedicationCodeableConcept *CodeableConcept      `json:"medicationCodeableConcept"`
+	Subject                   *Reference            `json:"subject"`
+	EffectiveDateTime         string                `json:"effectiveDateTime"`
+	Request                   *Reference            `json:"request,omitempty"`
+	Dosage                    *AdministrationDosage `json:"dosage,omitempty"`
+}
+
+// Bundle a FHIR R4 collection Bundle
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	Type         string        `json:"type"`
+	Timestamp    string        `json:"timestamp"`
+	Entry        []BundleEntry `json:"entry"`
+}
+
+// BundleEntry a resource in a bundle
+type BundleEntry struct {
+	FullURL  string      `json:"fullUrl"`
+	Resource interface{} `json:"resource"`
+}
+
+// NewPatient maps a user to a Patient
+func NewPatient(user *domain.User) *Patient {
+	patient := &Patient{ResourceType: "Patient", ID: strconv.Itoa(user.ID), Active: !user.Archived}
+	if user.FirstName != "" || user.LastName != "" {
+		name := HumanName{Family: user.LastName}
+		if user.FirstName != "" {
+			name.Given = []string{user.FirstName}
+		}
+		patient.Name = []HumanName{name}
+	}
+	if user.Email != "" {
+		patient.Telecom = []ContactPoint{{System: "email", Value: user.Email}}
+	}
+	return patient
+}
+
+// NewMedicationStatement maps a pill to a MedicationStatement, completed once the pill is archived or its course has ended
+func NewMedicationStatement(pill *domain.Pill, now time.Time) *MedicationStatement {
+	status := "active"
+	if pill.Archived || pill.Ended(now) {
+		status = "completed"
+	}
+	return &MedicationStatement{
+		ResourceType:              "MedicationStatement",
+		ID:                        strconv.Itoa(pill.ID),
+		Status:                    status,
+		MedicationCodeableConcept: medication(pill),
+		Subject:                   patient(pill.UserID),
+		EffectivePeriod:           course(pill),
+		DateAsserted:              now.Format(time.RFC3339),
+		BasedOn:                   []Reference{{Reference: fmt.Sprintf("MedicationRequest/%d", pill.ID)}},
+		Dosage:                    []Dosage{dosage(pill)},
+	}
+}
+
+// NewMedicationRequest maps a pill to the MedicationRequest it was prescribed by
+func NewMedicationRequest(pill *domain.Pill, now time.Time) *MedicationRequest {
+	status := "active"
+	if pill.Archived || pill.Ended(now) {
+		status = "completed"
+	}
+	request := &MedicationRequest{
+		ResourceType:              "MedicationRequest",
+		ID:                        strconv.Itoa(pill.ID),
+		Status:                    status,
+		Intent:                    "order",
+		MedicationCodeableConcept: medication(pill),
+		Subject:                   patient(pill.UserID),
+		DosageInstruction:         []Dosage{dosage(pill)},
+	}
+	if pill.Prescriber != "" {
+		request.Requester = &Reference{Display: pill.Prescriber}
+	}
+	return request
+}
+
+// NewMedicationAdministration maps a dose taken to a completed MedicationAdministration
+func NewMedicationAdministration(pill *domain.Pill, event *domain.PillEvent) *MedicationAdministration {
+	administration := administration(pill, event.Scheduled, event.Time)
+	administration.ID = strconv.Itoa(event.ID)
+	administration.Status = "completed"
+	return administration
+}
+
+// NewMissedAdministration maps a scheduled dose which wasn't taken to a not-done MedicationAdministration
+func NewMissedAdministration(pill *domain.Pill, dose time.Time) *MedicationAdministration {
+	administration := administration(pill, dose, dose)
+	administration.ID = fmt.Sprintf("%d-%d", pill.ID, dose.Unix())
+	administration.Status = "not-done"
+	return administration
+}
+
+// NewBundle creates a collection bundle of resources, entries are identified by their type and id
+func NewBundle(resources []interface{}, now time.Time) *Bundle {
+	bundle := &Bundle{ResourceType: "Bundle", Type: "collection", Timestamp: now.Format(time.RFC3339), Entry: []BundleEntry{}}
+	for _, r := range resources {
+		bundle.Entry = append(bundle.Entry, BundleEntry{FullURL: fullURL(r), Resource: r})
+	}
+	return bundle
+}
+
+func fullURL(resource interface{}) string {
+	switch r := resource.(type) {
+	case *Patient:
+		return "Patient/" + r.ID
+	case *MedicationStatement:
+		return "MedicationStatement/" + r.ID
+	case *MedicationRequest:
+		return "MedicationRequest/" + r.ID
+	case *MedicationAdministration:
+		return "MedicationAdministration/" + r.ID
+	}
+	return ""
+}
+
+func administration(pill *domain.Pill, scheduled time.Time, t time.Time) *MedicationAdministration {
+	administration := &MedicationAdministration{
+		ResourceType:              "MedicationAdministration",
+		MedicationCodeableConcept: medication(pill),
+		Subject:                   patient(pill.UserID),
+		EffectiveDateTime:         t.Format(time.RFC3339),
+		Request:                   &Reference{Reference: fmt.Sprintf("MedicationRequest/%d", pill.ID)},
+	}
+
+	dose := &AdministrationDosage{Route: route(pill)}
+	if _, quantity, ok := pill.Dose(scheduled); ok && quantity > 0 {
+		dose.Dose = &Quantity{Value: quantity, Unit: pill.Form}
+	}
+	if dose.Route != nil || dose.Dose != nil {
+		administration.Dosage = dose
+	}
+	return administration
+}
+
+func patient(userID int) *Reference {
+	return &Reference{Reference: fmt.Sprintf("Patient/%d", userID)}
+}
+
+func medication(pill *domain.Pill) *CodeableConcept {
+	return &CodeableConcept{Text: strings.TrimSpace(pill.Name + " " + pill.Dosage())}
+}
+
+func course(pill *domain.Pill) *Period {
+	if pill.StartDate == nil && pill.EndDate == nil {
+		return nil
+	}
+	period := &Period{}
+	if pill.StartDate != nil {
+		period.Start = pill.StartDate.Format(dateLayout)
+	}
+	if pill.EndDate != nil {
+		period.End = pill.EndDate.Format(dateLayout)
+	}
+	return period
+}
+
+func route(pill *domain.Pill) *CodeableConcept {
+	coding, ok := routes[pill.Route]
+	if !ok {
+		return nil
+	}
+	return &CodeableConcept{Coding: []Coding{coding}, Text: pill.Route}
+}
+
+// dosage how a pill is taken, schedules which FHIR timing can't express are described by their rule in the text
+func dosage(pill *domain.Pill) Dosage {
+	d := Dosage{PatientInstruction: pill.Instructions, Route: route(pill)}
+	switch pill.Food {
+	case "with-food":
+		d.Text = "Take with food"
+	case "empty-stomach":
+		d.Text = "Take on an empty stomach"
+	}
+
+	if pill.QuantityPerDose > 0 {
+		d.DoseAndRate = []DoseAndRate{{DoseQuantity: &Quantity{Value: pill.QuantityPerDose, Unit: pill.Form}}}
+	} else if pill.Strength > 0 {
+		quantity := &Quantity{Value: pill.Strength, Unit: pill.Unit}
+		if code, ok := units[pill.Unit]; ok {
+			quantity.System, quantity.Code = ucumSystem, code
+		}
+		d.DoseAndRate = []DoseAndRate{{DoseQuantity: quantity}}
+	}
+
+	days, times := pill.DaysOfWeek, pill.TimesOfDay
+	repeat := &TimingRepeat{BoundsPeriod: course(pill)}
+	if pill.Schedule != nil {
+		if pill.Schedule.AsNeeded {
+			d.AsNeededBoolean = true
+			if repeat.BoundsPeriod != nil {
+				d.Timing = &Timing{Repeat: repeat}
+			}
+			return d
+		}
+		days, times = pill.Schedule.Legacy()
+		if len(times) == 0 {
+			d.Text = strings.TrimSpace(d.Text + " " + "RRULE:" + strings.TrimPrefix(strings.ToUpper(pill.Schedule.RRule), "RRULE:"))
+			if rule, err := domain.ParseRule(pill.Schedule.RRule); err == nil {
+				repeat.Frequency = 1
+				repeat.Period = rule.Interval
+				repeat.PeriodUnit = map[string]string{"HOURLY": "h", "DAILY": "d", "WEEKLY": "wk", "MONTHLY": "mo"}[rule.Freq]
+			}
+		}
+	}
+
+	if len(days) < 7 {
+		for _, day := range days {
+			repeat.DayOfWeek = append(repeat.DayOfWeek, weekdays[day-1])
+		}
+	}
+	for _, t := range times {
+		repeat.TimeOfDay = append(repeat.TimeOfDay, t.String()+":00")
+	}
+	d.Timing = &Timing{Repeat: repeat}
+	return d
+}
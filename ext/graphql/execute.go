@@ -0,0 +1,557 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Request a GraphQL request as sent over HTTP
+type Request struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// Error an error, Path is the path of the field which failed
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// Response the result of a request, requests which can't be executed have errors but no data
+type Response struct {
+	Data   *Result  `json:"data,omitempty"`
+	Errors []*Error `json:"errors,omitempty"`
+}
+
+// Result the fields of an object in the order they were selected
+type Result struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (r *Result) set(key string, value interface{}) {
+	if r.values == nil {
+		r.values = map[string]interface{}{}
+	}
+	if _, ok := r.values[key]; !ok {
+		r.keys = append(r.keys, key)
+	}
+	r.values[key] = value
+}
+
+// Get the value of a field
+func (r *Result) Get(key string) interface{} {
+	return r.values[key]
+}
+
+// MarshalJSON marshals the fields in the order they were selected
+func (r *Result) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	for i, k := range r.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		value, err := json.Marshal(r.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Execute parses, validates and executes a query. Fields which fail are null and their errors are added to the
+// response, requests which can't be parsed, aren't valid, exceed the limits or are cancelled have no data
+func (s *Schema) Execute(ctx context.Context, request *Request) *Response {
+	doc, err := Parse(request.Query, s.maxSelections())
+	if err != nil {
+		return failed(err)
+	}
+
+	operation, err := doc.operation(request.OperationName)
+	if err != nil {
+		return failed(err)
+	}
+	if operation.Type != "query" {
+		return failed(fmt.Errorf("only queries are supported, not %ss", operation.Type))
+	}
+
+	e := &executor{schema: s, ctx: ctx, fragments: doc.Fragments}
+	if e.variables, err = variables(operation, request.Variables); err != nil {
+		return failed(err)
+	}
+
+	complexity, err := e.validate(s.Query, operation.Selections, 1, map[string]bool{})
+	if err != nil {
+		return failed(err)
+	}
+	if complexity > s.maxComplexity() {
+		return failed(fmt.Errorf("the query is too complex, its complexity is more than %d", s.maxComplexity()))
+	}
+
+	// the selections are collected again as they are executed
+	e.collected = 0
+	results := e.execute(s.Query, []interface{}{nil}, operation.Selections, [][]interface{}{{}})
+	if err := ctx.Err(); err != nil {
+		return failed(err)
+	}
+	return &Response{Data: results[0], Errors: e.errors}
+}
+
+func failed(err error) *Response {
+	return &Response{Errors: []*Error{{Message: err.Error()}}}
+}
+
+// operation the operation to execute, the name can be left out when there is only one
+func (d *Document) operation(name string) (*Operation, error) {
+	if name == "" {
+		if len(d.Operations) > 1 {
+			return nil, fmt.Errorf("an operation name is required when there are several operations")
+		}
+		return d.Operations[0], nil
+	}
+	for _, o := range d.Operations {
+		if o.Name == name {
+			return o, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %s", name)
+}
+
+// variables coerces the variables of a request to the types of the operation's variables
+func variables(operation *Operation, values map[string]interface{}) (map[string]interface{}, error) {
+	coerced := map[string]interface{}{}
+	for _, v := range operation.Variables {
+		value, ok := values[v.Name]
+		if !ok && v.Default != nil {
+			d, err := literal(v.Default, v.Type, nil)
+			if err != nil {
+				return nil, fmt.Errorf("variable $%s: %s", v.Name, err)
+			}
+			coerced[v.Name] = d
+			continue
+		}
+		c, err := coerce(value, v.Type)
+		if err != nil {
+			return nil, fmt.Errorf("variable $%s: %s", v.Name, err)
+		}
+		coerced[v.Name] = c
+	}
+	return coerced, nil
+}
+
+type executor struct {
+	schema    *Schema
+	ctx       context.Context
+	fragments map[string]*Fragment
+	variables map[string]interface{}
+	errors    []*Error
+	collected int
+}
+
+// collect the fields selected on an object, expanding fragments, applying @skip and @include and
+// merging the selections of fields with the same key. It also returns how many fields were selected
+// before they were merged. Every selection visited counts towards the schema's MaxSelections so
+// fragments which spread each other several times can't expand exponentially
+func (e *executor) collect(object *Object, selections []Selection, fields []*FieldSelection, visited map[string]bool) ([]*FieldSelection, int, error) {
+	selected := 0
+	for _, selection := range selections {
+		e.collected++
+		if e.collected > e.schema.maxSelections() {
+			return nil, 0, fmt.Errorf("the query has more than %d selections once its fragments are expanded", e.schema.maxSelections())
+		}
+
+		included, err := e.included(selection.directives())
+		if err != nil {
+			return nil, 0, err
+		}
+		if !included {
+			continue
+		}
+
+		switch s := selection.(type) {
+		case *FieldSelection:
+			selected++
+			merged := false
+			for i, f := range fields {
+				if f.Key() != s.Key() {
+					continue
+				}
+				if f.Name != s.Name {
+					return nil, 0, fmt.Errorf("fields %s and %s conflict because they both use the key %s", f.Name, s.Name, s.Key())
+				}
+				fields[i] = &FieldSelection{Alias: f.Alias, Name: f.Name, Arguments: f.Arguments, Selections: append(append([]Selection{}, f.Selections...), s.Selections...)}
+				merged = true
+			}
+			if !merged {
+				fields = append(fields, s)
+			}
+		case *InlineFragment:
+			if s.On != "" && s.On != object.Name {
+				continue
+			}
+			n := 0
+			if fields, n, err = e.collect(object, s.Selections, fields, visited); err != nil {
+				return nil, 0, err
+			}
+			selected += n
+		case *FragmentSpread:
+			fragment := e.fragments[s.Name]
+			if fragment == nil {
+				return nil, 0, fmt.Errorf("unknown fragment %s", s.Name)
+			}
+			if visited[s.Name] {
+				return nil, 0, fmt.Errorf("fragment %s spreads itself", s.Name)
+			}
+			if fragment.On != object.Name {
+				continue
+			}
+			visited[s.Name] = true
+			n := 0
+			fields, n, err = e.collect(object, fragment.Selections, fields, visited)
+			delete(visited, s.Name)
+			if err != nil {
+				return nil, 0, err
+			}
+			selected += n
+		}
+	}
+	return fields, selected, nil
+}
+
+// included applies the @skip and @include directives
+func (e *executor) included(directives []*Directive) (bool, error) {
+	for _, d := range directives {
+		if d.Name != "skip" && d.Name != "include" {
+			return false, fmt.Errorf("unknown directive @%s", d.Name)
+		}
+		args, err := e.arguments(d.Arguments, map[string]string{"if": "Boolean!"})
+		if err != nil {
+			return false, fmt.Errorf("directive @%s: %s", d.Name, err)
+		}
+		if args["if"].(bool) == (d.Name == "skip") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// validate checks the fields and arguments selected on an object and the depth of the selections,
+// returning their complexity
+func (e *executor) validate(object *Object, selections []Selection, depth int, visited map[string]bool) (int, error) {
+	if depth > e.schema.maxDepth() {
+		return 0, fmt.Errorf("the query is nested more than %d levels deep", e.schema.maxDepth())
+	}
+
+	// every field selected costs 1 even when it is merged with another field with the same key
+	fields, complexity, err := e.collect(object, selections, nil, visited)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, f := range fields {
+		if f.Name == "__typename" {
+			if len(f.Selections) > 0 {
+				return 0, fmt.Errorf("field __typename can't have a selection")
+			}
+			continue
+		}
+
+		def := object.Fields[f.Name]
+		if def == nil {
+			return 0, fmt.Errorf("cannot query field %s on type %s", f.Name, object.Name)
+		}
+		if _, err := e.arguments(f.Arguments, def.Args); err != nil {
+			return 0, fmt.Errorf("field %s: %s", f.Name, err)
+		}
+		if def.Type == nil && len(f.Selections) > 0 {
+			return 0, fmt.Errorf("field %s on type %s is a scalar and can't have a selection", f.Name, object.Name)
+		}
+		if def.Type != nil && len(f.Selections) == 0 {
+			return 0, fmt.Errorf("field %s on type %s must have a selection of subfields", f.Name, object.Name)
+		}
+
+		child := 0
+		if def.Type != nil {
+			if child, err = e.validate(def.Type, f.Selections, depth+1, visited); err != nil {
+				return 0, err
+			}
+		}
+		if def.List {
+			child *= e.schema.listComplexity()
+		}
+		complexity += child
+		if complexity > e.schema.maxComplexity() {
+			return complexity, nil
+		}
+	}
+	return complexity, nil
+}
+
+// execute resolves the selections of every source at once so batch resolvers are called once per field
+func (e *executor) execute(object *Object, sources []interface{}, selections []Selection, paths [][]interface{}) []*Result {
+	results := make([]*Result, len(sources))
+	for i := range results {
+		results[i] = &Result{}
+	}
+
+	// selections are validated before they are executed
+	fields, _, _ := e.collect(object, selections, nil, map[string]bool{})
+	for _, f := range fields {
+		// the fields which haven't been resolved are left out once the request is cancelled
+		if e.ctx.Err() != nil {
+			return results
+		}
+
+		key := f.Key()
+		if f.Name == "__typename" {
+			for _, r := range results {
+				r.set(key, object.Name)
+			}
+			continue
+		}
+
+		def := object.Fields[f.Name]
+		args, _ := e.arguments(f.Arguments, def.Args)
+		values := e.resolve(def, sources, args, paths, key)
+
+		if def.Type == nil {
+			for i, r := range results {
+				r.set(key, values[i])
+			}
+			continue
+		}
+
+		// gather the objects of every source to resolve the next level at once
+		children := []interface{}{}
+		childPaths := [][]interface{}{}
+		lists := make([][]int, len(sources))
+		for i, v := range values {
+			path := append(append([]interface{}{}, paths[i]...), key)
+			if isNil(v) {
+				continue
+			}
+			if !def.List {
+				lists[i] = []int{len(children)}
+				children = append(children, v)
+				childPaths = append(childPaths, path)
+				continue
+			}
+			list := reflect.ValueOf(v)
+			lists[i] = []int{}
+			for j := 0; j < list.Len(); j++ {
+				item := list.Index(j).Interface()
+				if isNil(item) {
+					lists[i] = append(lists[i], -1)
+					continue
+				}
+				lists[i] = append(lists[i], len(children))
+				children = append(children, item)
+				childPaths = append(childPaths, append(append([]interface{}{}, path...), j))
+			}
+		}
+
+		childResults := e.execute(def.Type, children, f.Selections, childPaths)
+		for i, r := range results {
+			switch {
+			case lists[i] == nil:
+				r.set(key, nil)
+			case !def.List:
+				r.set(key, childResults[lists[i][0]])
+			default:
+				items := make([]*Result, len(lists[i]))
+				for j, c := range lists[i] {
+					if c >= 0 {
+						items[j] = childResults[c]
+					}
+				}
+				r.set(key, items)
+			}
+		}
+	}
+	return results
+}
+
+// resolve the value of a field for every source, values which fail are nil and their errors are recorded
+func (e *executor) resolve(def *Field, sources []interface{}, args map[string]interface{}, paths [][]interface{}, key string) []interface{} {
+	values := make([]interface{}, len(sources))
+	if len(sources) == 0 {
+		return values
+	}
+
+	if def.Batch != nil {
+		batch, err := def.Batch(&BatchParams{Context: e.ctx, Sources: sources, Args: args})
+		if err == nil && len(batch) != len(sources) {
+			err = fmt.Errorf("resolved %d values for %d sources", len(batch), len(sources))
+		}
+		if err != nil {
+			for i := range sources {
+				e.errors = append(e.errors, &Error{Message: err.Error(), Path: append(append([]interface{}{}, paths[i]...), key)})
+			}
+			return values
+		}
+		return batch
+	}
+
+	for i, source := range sources {
+		value, err := def.Resolve(&Params{Context: e.ctx, Source: source, Args: args})
+		if err != nil {
+			e.errors = append(e.errors, &Error{Message: err.Error(), Path: append(append([]interface{}{}, paths[i]...), key)})
+			continue
+		}
+		values[i] = value
+	}
+	return values
+}
+
+// arguments coerces the arguments of a field or a directive to their types
+func (e *executor) arguments(arguments []*Argument, types map[string]string) (map[string]interface{}, error) {
+	args := map[string]interface{}{}
+	for _, a := range arguments {
+		t, ok := types[a.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown argument %s", a.Name)
+		}
+		value, err := literal(a.Value, t, e.variables)
+		if err != nil {
+			return nil, fmt.Errorf("argument %s: %s", a.Name, err)
+		}
+		args[a.Name] = value
+	}
+	for n, t := range types {
+		if _, ok := args[n]; !ok && strings.HasSuffix(t, "!") {
+			return nil, fmt.Errorf("argument %s of type %s is required", n, t)
+		}
+	}
+	return args, nil
+}
+
+// literal coerces a value of a query to a type, variables have already been coerced to the types they were declared as
+func literal(value *Value, t string, variables map[string]interface{}) (interface{}, error) {
+	if value.Kind == VariableValue {
+		v, ok := variables[value.Raw]
+		if !ok {
+			return nil, fmt.Errorf("variable $%s is not defined", value.Raw)
+		}
+		return coerce(v, t)
+	}
+
+	required := strings.HasSuffix(t, "!")
+	t = strings.TrimSuffix(t, "!")
+	if value.Kind == NullValue {
+		if required {
+			return nil, fmt.Errorf("expected a non null %s", t)
+		}
+		return nil, nil
+	}
+
+	if strings.HasPrefix(t, "[") {
+		inner := t[1 : len(t)-1]
+		items := []*Value{value}
+		if value.Kind == ListValue {
+			items = value.List
+		}
+		list := []interface{}{}
+		for _, item := range items {
+			v, err := literal(item, inner, variables)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	}
+
+	switch {
+	case t == "Int" && value.Kind == IntValue:
+		n, err := strconv.ParseInt(value.Raw, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a 32 bit integer", value.Raw)
+		}
+		return int(n), nil
+	case t == "Float" && (value.Kind == IntValue || value.Kind == FloatValue):
+		return strconv.ParseFloat(value.Raw, 64)
+	case (t == "String" || t == "ID") && value.Kind == StringValue, t == "ID" && value.Kind == IntValue:
+		return value.Raw, nil
+	case t == "Boolean" && value.Kind == BooleanValue:
+		return value.Raw == "true", nil
+	}
+	return nil, fmt.Errorf("expected a %s", t)
+}
+
+// coerce coerces a JSON value to a type
+func coerce(value interface{}, t string) (interface{}, error) {
+	required := strings.HasSuffix(t, "!")
+	t = strings.TrimSuffix(t, "!")
+	if value == nil {
+		if required {
+			return nil, fmt.Errorf("expected a non null %s", t)
+		}
+		return nil, nil
+	}
+
+	if strings.HasPrefix(t, "[") {
+		inner := t[1 : len(t)-1]
+		items, ok := value.([]interface{})
+		if !ok {
+			items = []interface{}{value}
+		}
+		list := []interface{}{}
+		for _, item := range items {
+			v, err := coerce(item, inner)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	}
+
+	switch v := value.(type) {
+	case int:
+		return coerce(float64(v), t)
+	case float64:
+		if t == "Float" {
+			return v, nil
+		}
+		if (t == "Int" || t == "ID") && v == math.Trunc(v) && math.Abs(v) <= math.MaxInt32 {
+			if t == "ID" {
+				return strconv.Itoa(int(v)), nil
+			}
+			return int(v), nil
+		}
+	case string:
+		if t == "String" || t == "ID" {
+			return v, nil
+		}
+	case bool:
+		if t == "Boolean" {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("expected a %s", t)
+}
+
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fragments a query whose fragments spread the next fragment twice, expanding to 2^n selections
+func fragments(n int) string {
+	query := "{ ...f0 }"
+	for i := 0; i < n; i++ {
+		query += fmt.Sprintf(" fragment f%d on Query { ...f%d ...f%d }", i, i+1, i+1)
+	}
+	return query + fmt.Sprintf(" fragment f%d on Query { name }", n)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{"{ name }", ""},
+		{"{ " + strings.Repeat("name ", 10) + "}", ""},
+		{"{ " + strings.Repeat("name ", 11) + "}", "the document has more than 10 selections"},
+		{"{ user { user { user { user { user { user { name } } } } } } }", ""},
+		{"{ user { user { user { user { user { user { user { user { user { user { name } } } } } } } } } } }", "the document has more than 10 selections"},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(test.query, 10)
+		if (err == nil && test.err != "") || (err != nil && err.Error() != test.err) {
+			t.Errorf("%s: expected error %q, got %v", test.query, test.err, err)
+		}
+	}
+}
+
+func TestExecute(t *testing.T) {
+	calls := 0
+	user := &Object{Name: "User", Fields: map[string]*Field{}}
+	user.Fields["name"] = &Field{Resolve: func(p *Params) (interface{}, error) {
+		calls++
+		return "Jacob", nil
+	}}
+	user.Fields["user"] = &Field{Type: user, Resolve: func(p *Params) (interface{}, error) { return struct{}{}, nil }}
+	query := &Object{Name: "Query", Fields: map[string]*Field{
+		"name": user.Fields["name"],
+		"user": user.Fields["user"],
+	}}
+	schema := &Schema{Query: query, MaxComplexity: 5, MaxSelections: 100}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		ctx      context.Context
+		query    string
+		response string
+		calls    int
+	}{
+		{context.Background(), "{ name user { name } }", `{"data":{"name":"Jacob","user":{"name":"Jacob"}}}`, 2},
+		{context.Background(), fragments(2), `{"data":{"name":"Jacob"}}`, 1},
+		{context.Background(), fragments(30), `{"errors":[{"message":"the query has more than 100 selections once its fragments are expanded"}]}`, 0},
+		{context.Background(), "{ " + strings.Repeat("name ", 6) + "}", `{"errors":[{"message":"the query is too complex, its complexity is more than 5"}]}`, 0},
+		{context.Background(), "{ user { name } user { name } user { name } }", `{"errors":[{"message":"the query is too complex, its complexity is more than 5"}]}`, 0},
+		{cancelled, "{ name }", `{"errors":[{"message":"context canceled"}]}`, 0},
+	}
+
+	for _, test := range tests {
+		calls = 0
+		response, err := json.Marshal(schema.Execute(test.ctx, &Request{Query: test.query}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(response) != test.response {
+			t.Errorf("%s: expected %s, got %s", test.query, test.response, response)
+		}
+		if calls != test.calls {
+			t.Errorf("%s: expected %d calls, got %d", test.query, test.calls, calls)
+		}
+	}
+}
@@ -0,0 +1,660 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Document a parsed request, the operations and the fragments they spread
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+// Operation a query or mutation, only queries are executed
+type Operation struct {
+	Type       string
+	Name       string
+	Variables  []*VariableDefinition
+	Selections []Selection
+}
+
+// VariableDefinition a variable of an operation, ie. $id: Int! = 1
+type VariableDefinition struct {
+	Name    string
+	Type    string
+	Default *Value
+}
+
+// Fragment a named fragment, ie. fragment pillFields on Pill { id name }
+type Fragment struct {
+	Name       string
+	On         string
+	Selections []Selection
+}
+
+// Selection a field, a fragment spread or an inline fragment
+type Selection interface {
+	directives() []*Directive
+}
+
+// FieldSelection a selected field, Alias is the key of the field in the result
+type FieldSelection struct {
+	Alias      string
+	Name       string
+	Arguments  []*Argument
+	Directives []*Directive
+	Selections []Selection
+}
+
+// FragmentSpread spreads a named fragment, ie. ...pillFields
+type FragmentSpread struct {
+	Name       string
+	Directives []*Directive
+}
+
+// InlineFragment a fragment without a name, ie. ... on Pill { id }
+type InlineFragment struct {
+	On         string
+	Directives []*Directive
+	Selections []Selection
+}
+
+// Directive a directive, only @include and @skip are supported
+type Directive struct {
+	Name      string
+	Arguments []*Argument
+}
+
+// Argument an argument of a field or a directive
+type Argument struct {
+	Name  string
+	Value *Value
+}
+
+// The kinds of values
+const (
+	IntValue      = "Int"
+	FloatValue    = "Float"
+	StringValue   = "String"
+	BooleanValue  = "Boolean"
+	NullValue     = "Null"
+	EnumValue     = "Enum"
+	ListValue     = "List"
+	ObjectValue   = "Object"
+	VariableValue = "Variable"
+)
+
+// Value a literal or a variable, Raw is the literal or the variable name
+type Value struct {
+	Kind   string
+	Raw    string
+	List   []*Value
+	Fields []*Argument
+}
+
+func (f *FieldSelection) directives() []*Directive { return f.Directives }
+func (f *FragmentSpread) directives() []*Directive { return f.Directives }
+func (f *InlineFragment) directives() []*Directive { return f.Directives }
+
+// Key the key of the field in the result, the alias when there is one
+func (f *FieldSelection) Key() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// Parse parses a GraphQL request document, documents with more than maxSelections selections are rejected
+func Parse(query string, maxSelections int) (*Document, error) {
+	p := &parser{lexer: &lexer{src: query}, max: maxSelections}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	doc := &Document{Fragments: map[string]*Fragment{}}
+	for p.token.kind != eof {
+		switch {
+		case p.token.is(punctuator, "{"):
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, &Operation{Type: "query", Selections: selections})
+		case p.token.is(name, "query"), p.token.is(name, "mutation"), p.token.is(name, "subscription"):
+			operation, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, operation)
+		case p.token.is(name, "fragment"):
+			fragment, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if doc.Fragments[fragment.Name] != nil {
+				return nil, fmt.Errorf("there can only be one fragment named %s", fragment.Name)
+			}
+			doc.Fragments[fragment.Name] = fragment
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.Operations) == 0 {
+		return nil, fmt.Errorf("the document has no operations")
+	}
+	return doc, nil
+}
+
+type parser struct {
+	lexer      *lexer
+	token      token
+	max        int
+	selections int
+}
+
+func (p *parser) next() error {
+	t, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.token = t
+	return nil
+}
+
+func (p *parser) unexpected() error {
+	if p.token.kind == eof {
+		return fmt.Errorf("syntax error: unexpected end of the document")
+	}
+	return fmt.Errorf("syntax error: unexpected %q at offset %d", p.token.value, p.token.offset)
+}
+
+// expect consumes a punctuator
+func (p *parser) expect(value string) error {
+	if !p.token.is(punctuator, value) {
+		return p.unexpected()
+	}
+	return p.next()
+}
+
+// skip consumes a punctuator if it is next, returning whether it was
+func (p *parser) skip(value string) (bool, error) {
+	if !p.token.is(punctuator, value) {
+		return false, nil
+	}
+	return true, p.next()
+}
+
+func (p *parser) name() (string, error) {
+	if p.token.kind != name {
+		return "", p.unexpected()
+	}
+	n := p.token.value
+	return n, p.next()
+}
+
+func (p *parser) operation() (*Operation, error) {
+	operation := &Operation{Type: p.token.value}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.token.kind == name {
+		operation.Name = p.token.value
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+
+	if ok, err := p.skip("("); err != nil {
+		return nil, err
+	} else if ok {
+		for !p.token.is(punctuator, ")") {
+			variable, err := p.variableDefinition()
+			if err != nil {
+				return nil, err
+			}
+			operation.Variables = append(operation.Variables, variable)
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	selections, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	operation.Selections = selections
+	return operation, nil
+}
+
+func (p *parser) variableDefinition() (*VariableDefinition, error) {
+	if err := p.expect("$"); err != nil {
+		return nil, err
+	}
+	n, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	t, err := p.typeReference()
+	if err != nil {
+		return nil, err
+	}
+
+	variable := &VariableDefinition{Name: n, Type: t}
+	if ok, err := p.skip("="); err != nil {
+		return nil, err
+	} else if ok {
+		if variable.Default, err = p.value(true); err != nil {
+			return nil, err
+		}
+	}
+	return variable, nil
+}
+
+// typeReference a type as written, ie. [Int!]!
+func (p *parser) typeReference() (string, error) {
+	var t string
+	if ok, err := p.skip("["); err != nil {
+		return "", err
+	} else if ok {
+		inner, err := p.typeReference()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expect("]"); err != nil {
+			return "", err
+		}
+		t = "[" + inner + "]"
+	} else {
+		n, err := p.name()
+		if err != nil {
+			return "", err
+		}
+		t = n
+	}
+	if ok, err := p.skip("!"); err != nil {
+		return "", err
+	} else if ok {
+		t += "!"
+	}
+	return t, nil
+}
+
+func (p *parser) fragment() (*Fragment, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	n, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if !p.token.is(name, "on") {
+		return nil, p.unexpected()
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	on, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	selections, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return &Fragment{Name: n, On: on, Selections: selections}, nil
+}
+
+func (p *parser) selectionSet() ([]Selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	selections := []Selection{}
+	for !p.token.is(punctuator, "}") {
+		selection, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, selection)
+	}
+	if len(selections) == 0 {
+		return nil, fmt.Errorf("syntax error: empty selection set at offset %d", p.token.offset)
+	}
+	return selections, p.next()
+}
+
+func (p *parser) selection() (Selection, error) {
+	p.selections++
+	if p.selections > p.max {
+		return nil, fmt.Errorf("the document has more than %d selections", p.max)
+	}
+
+	if ok, err := p.skip("..."); err != nil {
+		return nil, err
+	} else if ok {
+		return p.fragmentSelection()
+	}
+
+	field := &FieldSelection{}
+	n, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	field.Name = n
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		field.Alias = n
+		if field.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+
+	if field.Arguments, err = p.arguments(); err != nil {
+		return nil, err
+	}
+	if field.Directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.token.is(punctuator, "{") {
+		if field.Selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return field, nil
+}
+
+// fragmentSelection a fragment spread or an inline fragment, after the ...
+func (p *parser) fragmentSelection() (Selection, error) {
+	if p.token.kind == name && p.token.value != "on" {
+		n, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		directives, err := p.directives()
+		if err != nil {
+			return nil, err
+		}
+		return &FragmentSpread{Name: n, Directives: directives}, nil
+	}
+
+	fragment := &InlineFragment{}
+	if p.token.is(name, "on") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		on, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		fragment.On = on
+	}
+	var err error
+	if fragment.Directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if fragment.Selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return fragment, nil
+}
+
+func (p *parser) arguments() ([]*Argument, error) {
+	arguments := []*Argument{}
+	if ok, err := p.skip("("); err != nil || !ok {
+		return arguments, err
+	}
+	for !p.token.is(punctuator, ")") {
+		n, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.value(false)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, &Argument{Name: n, Value: value})
+	}
+	return arguments, p.next()
+}
+
+func (p *parser) directives() ([]*Directive, error) {
+	directives := []*Directive{}
+	for p.token.is(punctuator, "@") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		n, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		arguments, err := p.arguments()
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, &Directive{Name: n, Arguments: arguments})
+	}
+	return directives, nil
+}
+
+// value a value, constant values such as variable defaults can't contain variables
+func (p *parser) value(constant bool) (*Value, error) {
+	t := p.token
+	switch {
+	case t.is(punctuator, "$") && !constant:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		n, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		return &Value{Kind: VariableValue, Raw: n}, nil
+	case t.is(punctuator, "["):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		list := &Value{Kind: ListValue, List: []*Value{}}
+		for !p.token.is(punctuator, "]") {
+			item, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			list.List = append(list.List, item)
+		}
+		return list, p.next()
+	case t.is(punctuator, "{"):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		object := &Value{Kind: ObjectValue, Fields: []*Argument{}}
+		for !p.token.is(punctuator, "}") {
+			n, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			value, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			object.Fields = append(object.Fields, &Argument{Name: n, Value: value})
+		}
+		return object, p.next()
+	case t.kind == intToken:
+		return &Value{Kind: IntValue, Raw: t.value}, p.next()
+	case t.kind == floatToken:
+		return &Value{Kind: FloatValue, Raw: t.value}, p.next()
+	case t.kind == stringToken:
+		return &Value{Kind: StringValue, Raw: t.value}, p.next()
+	case t.is(name, "true"), t.is(name, "false"):
+		return &Value{Kind: BooleanValue, Raw: t.value}, p.next()
+	case t.is(name, "null"):
+		return &Value{Kind: NullValue}, p.next()
+	case t.kind == name:
+		return &Value{Kind: EnumValue, Raw: t.value}, p.next()
+	}
+	return nil, p.unexpected()
+}
+
+// The kinds of tokens
+const (
+	eof = iota
+	punctuator
+	name
+	intToken
+	floatToken
+	stringToken
+)
+
+type token struct {
+	kind   int
+	value  string
+	offset int
+}
+
+func (t token) is(kind int, value string) bool {
+	return t.kind == kind && t.value == value
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+func (l *lexer) next() (token, error) {
+	// whitespace, commas and comments are insignificant
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == '#' {
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' && c != ',' {
+			break
+		}
+		l.pos++
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: eof, offset: l.pos}, nil
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.pos += 3
+		return token{kind: punctuator, value: "...", offset: start}, nil
+	case strings.IndexByte("!$()[]{}:=@|&", c) >= 0:
+		l.pos++
+		return token{kind: punctuator, value: string(c), offset: start}, nil
+	case c == '_' || isLetter(c):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return token{kind: name, value: l.src[start:l.pos], offset: start}, nil
+	case c == '-' || isDigit(c):
+		return l.number()
+	case c == '"':
+		return l.string()
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return token{}, fmt.Errorf("syntax error: unexpected character %q at offset %d", r, start)
+}
+
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	kind := intToken
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	digits := func() int {
+		n := 0
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+			n++
+		}
+		return n
+	}
+	if digits() == 0 {
+		return token{}, fmt.Errorf("syntax error: invalid number at offset %d", start)
+	}
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = floatToken
+		l.pos++
+		if digits() == 0 {
+			return token{}, fmt.Errorf("syntax error: invalid number at offset %d", start)
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = floatToken
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		if digits() == 0 {
+			return token{}, fmt.Errorf("syntax error: invalid number at offset %d", start)
+		}
+	}
+	return token{kind: kind, value: l.src[start:l.pos], offset: start}, nil
+}
+
+func (l *lexer) string() (token, error) {
+	start := l.pos
+	if strings.HasPrefix(l.src[l.pos:], `"""`) {
+		end := strings.Index(l.src[l.pos+3:], `"""`)
+		if end < 0 {
+			return token{}, fmt.Errorf("syntax error: unterminated string at offset %d", start)
+		}
+		value := l.src[l.pos+3 : l.pos+3+end]
+		l.pos += end + 6
+		return token{kind: stringToken, value: strings.TrimSpace(value), offset: start}, nil
+	}
+
+	l.pos++
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case '\\':
+			l.pos += 2
+			continue
+		case '\n', '\r':
+			return token{}, fmt.Errorf("syntax error: unterminated string at offset %d", start)
+		case '"':
+			l.pos++
+			value, err := strconv.Unquote(l.src[start:l.pos])
+			if err != nil {
+				return token{}, fmt.Errorf("syntax error: invalid string at offset %d", start)
+			}
+			return token{kind: stringToken, value: value, offset: start}, nil
+		}
+		l.pos++
+	}
+	return token{}, fmt.Errorf("syntax error: unterminated string at offset %d", start)
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,128 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+)
+
+// The default limits of a schema
+const (
+	DefaultMaxDepth       = 10
+	DefaultMaxComplexity  = 1000
+	DefaultListComplexity = 10
+	DefaultMaxSelections  = 1000
+)
+
+// Schema the object types reachable from Query. Queries nested deeper than MaxDepth or more complex than
+// MaxComplexity are rejected before they are executed. Every field costs 1 and the selections of list
+// fields are assumed to be resolved ListComplexity times. MaxSelections caps the selections of a document
+// and the selections visited while expanding its fragments
+type Schema struct {
+	Query          *Object
+	MaxDepth       int
+	MaxComplexity  int
+	ListComplexity int
+	MaxSelections  int
+}
+
+// Object an object type
+type Object struct {
+	Name   string
+	Fields map[string]*Field
+}
+
+// Field a field of an object, Type is the object of its values and nil for scalars which are marshalled
+// as JSON. List fields resolve to slices. Args are the types of the arguments, ie. "Int!".
+// Fields are resolved for one source at a time by Resolve or for every source at once by Batch
+type Field struct {
+	Type    *Object
+	List    bool
+	Args    map[string]string
+	Resolve func(p *Params) (interface{}, error)
+	Batch   func(p *BatchParams) ([]interface{}, error)
+}
+
+// Params the parameters of a resolver
+type Params struct {
+	Context context.Context
+	Source  interface{}
+	Args    map[string]interface{}
+}
+
+// BatchParams the parameters of a batch resolver, it must return a value for each source
+type BatchParams struct {
+	Context context.Context
+	Sources []interface{}
+	Args    map[string]interface{}
+}
+
+// Loader batches and caches values by key for a request so the values of many sources are
+// loaded once, ie. the user of every pill in a list. Fetch is called with the keys which aren't cached
+type Loader struct {
+	Fetch func(keys []int) (map[int]interface{}, error)
+
+	mu    sync.Mutex
+	cache map[int]interface{}
+}
+
+// LoadMany loads the value of each key, fetching the keys which aren't cached at once
+func (l *Loader) LoadMany(keys []int) ([]interface{}, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.cache == nil {
+		l.cache = map[int]interface{}{}
+	}
+
+	missing := []int{}
+	seen := map[int]bool{}
+	for _, k := range keys {
+		if _, ok := l.cache[k]; !ok && !seen[k] {
+			seen[k] = true
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) > 0 {
+		fetched, err := l.Fetch(missing)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range missing {
+			l.cache[k] = fetched[k]
+		}
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, k := range keys {
+		values[i] = l.cache[k]
+	}
+	return values, nil
+}
+
+func (s *Schema) maxDepth() int {
+	if s.MaxDepth > 0 {
+		return s.MaxDepth
+	}
+	return DefaultMaxDepth
+}
+
+func (s *Schema) maxComplexity() int {
+	if s.MaxComplexity > 0 {
+		return s.MaxComplexity
+	}
+	return DefaultMaxComplexity
+}
+
+func (s *Schema) listComplexity() int {
+	if s.ListComplexity > 0 {
+		return s.ListComplexity
+	}
+	return DefaultListComplexity
+}
+
+func (s *Schema) maxSelections() int {
+	if s.MaxSelections > 0 {
+		return s.MaxSelections
+	}
+	return DefaultMaxSelections
+}
@@ -0,0 +1,181 @@
+package ical
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timezoneYears how many years ahead timezone transitions are included
+const timezoneYears = 5
+
+// Calendar an RFC 5545 calendar of events in a location
+type Calendar struct {
+	Name     string
+	Location *time.Location
+	Events   []*Event
+}
+
+// Event a VEVENT with an alarm when it starts, RRule is optional
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	Duration    time.Duration
+	RRule       string
+}
+
+// Bytes renders the calendar, now is used as the timestamp of every event
+func (c *Calendar) Bytes(now time.Time) []byte {
+	loc := c.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	w := &writer{}
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:-//Lukabox//Medication Schedule//EN")
+	w.line("CALSCALE:GREGORIAN")
+	w.line("METHOD:PUBLISH")
+	w.line("X-WR-CALNAME:" + escape(c.Name))
+	if loc != time.UTC {
+		w.line("X-WR-TIMEZONE:" + loc.String())
+		earliest := now
+		for _, e := range c.Events {
+			if e.Start.Before(earliest) {
+				earliest = e.Start
+			}
+		}
+		w.timezone(loc, earliest.AddDate(0, 0, -1), now.AddDate(timezoneYears, 0, 0))
+	}
+
+	for _, e := range c.Events {
+		w.line("BEGIN:VEVENT")
+		w.line("UID:" + e.UID)
+		w.line("DTSTAMP:" + now.UTC().Format("20060102T150405Z"))
+		w.line(dateTime("DTSTART", e.Start.In(loc)))
+		if e.Duration > 0 {
+			w.line(fmt.Sprintf("DURATION:PT%dM", int(e.Duration.Minutes())))
+		}
+		if e.RRule != "" {
+			w.line("RRULE:" + e.RRule)
+		}
+		w.line("SUMMARY:" + escape(e.Summary))
+		if e.Description != "" {
+			w.line("DESCRIPTION:" + escape(e.Description))
+		}
+		w.line("BEGIN:VALARM")
+		w.line("ACTION:DISPLAY")
+		w.line("TRIGGER:PT0M")
+		w.line("DESCRIPTION:" + escape(e.Summary))
+		w.line("END:VALARM")
+		w.line("END:VEVENT")
+	}
+
+	w.line("END:VCALENDAR")
+	return w.Bytes()
+}
+
+// Until the UNTIL value for the end of a recurrence, always in UTC
+func Until(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+func dateTime(name string, t time.Time) string {
+	if t.Location() == time.UTC {
+		return name + ":" + t.Format("20060102T150405Z")
+	}
+	return name + ";TZID=" + t.Location().String() + ":" + t.Format("20060102T150405")
+}
+
+type writer struct {
+	bytes.Buffer
+}
+
+// line writes a content line folded at 75 octets without splitting characters
+func (w *writer) line(s string) {
+	limit := 75
+	for len(s) > limit {
+		i := limit
+		for i > 0 && !isCharStart(s[i]) {
+			i--
+		}
+		w.WriteString(s[:i] + "\r\n ")
+		s = s[i:]
+		limit = 74
+	}
+	w.WriteString(s + "\r\n")
+}
+
+func isCharStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// timezone writes a VTIMEZONE with an observance for every transition of loc between from and to
+func (w *writer) timezone(loc *time.Location, from time.Time, to time.Time) {
+	w.line("BEGIN:VTIMEZONE")
+	w.line("TZID:" + loc.String())
+
+	transitions := 0
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		next := day.AddDate(0, 0, 1)
+		_, before := day.In(loc).Zone()
+		_, after := next.In(loc).Zone()
+		if before == after {
+			continue
+		}
+
+		// narrow the transition down to the minute
+		lo, hi := day, next
+		for hi.Sub(lo) > time.Minute {
+			mid := lo.Add(hi.Sub(lo) / 2)
+			if _, offset := mid.In(loc).Zone(); offset == before {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		w.observance(hi.In(loc), before, after)
+		transitions++
+	}
+
+	if transitions == 0 {
+		_, offset := from.In(loc).Zone()
+		w.observance(time.Date(1970, time.January, 1, 0, 0, 0, 0, loc), offset, offset)
+	}
+	w.line("END:VTIMEZONE")
+}
+
+func (w *writer) observance(t time.Time, before int, after int) {
+	kind := "DAYLIGHT"
+	if after <= before {
+		kind = "STANDARD"
+	}
+	name, _ := t.Zone()
+
+	w.line("BEGIN:" + kind)
+	// observances start at the local time before the transition
+	w.line("DTSTART:" + t.UTC().Add(time.Duration(before)*time.Second).Format("20060102T150405"))
+	w.line("TZOFFSETFROM:" + offset(before))
+	w.line("TZOFFSETTO:" + offset(after))
+	w.line("TZNAME:" + name)
+	w.line("END:" + kind)
+}
+
+func offset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds/60%60)
+}
+
+// escape escapes TEXT values
+func escape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return r.Replace(s)
+}
@@ -0,0 +1,104 @@
+package interactions
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// header the columns of an interaction dataset
+var header = []string{"drug", "other", "severity", "description"}
+
+// Dataset an in memory implementation of domain.InteractionService loaded from a local CSV file.
+// Drugs match pills whose names contain them as whole words, ie. warfarin matches Warfarin 5 mg
+type Dataset struct {
+	mu           sync.RWMutex
+	interactions []*domain.Interaction
+}
+
+// Load imports the dataset from a CSV file
+func (d *Dataset) Load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return d.Import(f)
+}
+
+// Import replaces the dataset with the interactions of a CSV file with drug, other, severity and description columns
+func (d *Dataset) Import(r io.Reader) error {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 || strings.Join(records[0], ",") != strings.Join(header, ",") {
+		return fmt.Errorf("the first line must be %s", strings.Join(header, ","))
+	}
+
+	interactions := []*domain.Interaction{}
+	for i, record := range records[1:] {
+		if len(record) != len(header) {
+			return fmt.Errorf("line %d must have %d columns", i+2, len(header))
+		}
+		interaction := &domain.Interaction{
+			Drug:        strings.ToLower(strings.TrimSpace(record[0])),
+			Other:       strings.ToLower(strings.TrimSpace(record[1])),
+			Severity:    strings.ToLower(strings.TrimSpace(record[2])),
+			Description: strings.TrimSpace(record[3]),
+		}
+		if interaction.Drug == "" || interaction.Other == "" {
+			return fmt.Errorf("line %d must name both drugs", i+2)
+		}
+		if !domain.ValidSeverity(interaction.Severity) {
+			return fmt.Errorf("line %d has an unknown severity %s", i+2, interaction.Severity)
+		}
+		interactions = append(interactions, interaction)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.interactions = interactions
+	return nil
+}
+
+// Interactions the interactions between two pills by name in either order
+func (d *Dataset) Interactions(pill string, other string) ([]*domain.Interaction, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	a, b := words(pill), words(other)
+	found := []*domain.Interaction{}
+	for _, i := range d.interactions {
+		drug, o := words(i.Drug), words(i.Other)
+		if (contains(a, drug) && contains(b, o)) || (contains(a, o) && contains(b, drug)) {
+			found = append(found, i)
+		}
+	}
+	return found, nil
+}
+
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// contains whether the words of name contain the words of drug in order
+func contains(name []string, drug []string) bool {
+	if len(drug) == 0 {
+		return false
+	}
+	for i := 0; i+len(drug) <= len(name); i++ {
+		if strings.Join(name[i:i+len(drug)], " ") == strings.Join(drug, " ") {
+			return true
+		}
+	}
+	return false
+}
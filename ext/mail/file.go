@@ -0,0 +1,67 @@
+package mail
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/log"
+)
+
+// unsafe the characters which aren't allowed in the names of the files
+var unsafe = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// Directory an implementation of domain.Mailer which writes every email to a file in Path, useful for local development
+type Directory struct {
+	Path string
+}
+
+// Send writes the email to a new file
+func (m *Directory) Send(email *domain.Email) error {
+	if err := os.MkdirAll(m.Path, 0755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d-%s.txt", time.Now().UnixNano(), sanitize(strings.Replace(email.To, "@", "_at_", -1)))
+	path, err := m.file(name)
+	if err != nil {
+		return err
+	}
+	log.WithField("path", path).Debug("writing email")
+
+	content := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", email.To, email.Subject, email.Body)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		return err
+	}
+
+	// attachments are written next to the email with the same prefix
+	for _, a := range email.Attachments {
+		attachment, err := m.file(strings.TrimSuffix(name, ".txt") + "-" + sanitize(a.Name))
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(attachment, a.Data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sanitize replaces the characters of a name which aren't safe in a file name, including separators
+func sanitize(name string) string {
+	return unsafe.ReplaceAllString(name, "_")
+}
+
+// file the path of a file in the directory, names which would resolve outside of it are refused
+func (m *Directory) file(name string) (string, error) {
+	path := filepath.Join(m.Path, name)
+	if filepath.Dir(path) != filepath.Clean(m.Path) {
+		return "", fmt.Errorf("%s is outside of the mail directory", name)
+	}
+	return path, nil
+}
@@ -0,0 +1,35 @@
+package mail
+
+import (
+	"sync"
+
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/log"
+)
+
+// Outbox an in memory implementation of domain.Mailer which keeps every email it sends
+type Outbox struct {
+	mu     sync.Mutex
+	Emails []*domain.Email
+}
+
+// Send adds the email to the outbox
+func (m *Outbox) Send(email *domain.Email) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	log.WithField("to", email.To).Debug("sending email to outbox")
+	m.Emails = append(m.Emails, email)
+	return nil
+}
+
+// Last the last email sent to an address
+func (m *Outbox) Last(to string) *domain.Email {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.Emails) - 1; i >= 0; i-- {
+		if m.Emails[i].To == to {
+			return m.Emails[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,363 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The content types of the patch formats
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+// ErrTestFailed returned when a test operation of a JSON patch doesn't match the document
+var ErrTestFailed = errors.New("test failed")
+
+// Merge applies an RFC 7396 merge patch to a document, the patch must be an object
+func Merge(doc []byte, patch []byte) ([]byte, error) {
+	p, err := decode(patch)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.(map[string]interface{}); !ok {
+		return nil, errors.New("a merge patch must be an object")
+	}
+	d, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(merge(d, p))
+}
+
+// MergeFields the top level members of a document a merge patch changes
+func MergeFields(patch []byte) ([]string, error) {
+	p := map[string]json.RawMessage{}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, errors.New("a merge patch must be an object")
+	}
+	fields := []string{}
+	for field := range p {
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// merge merges the patch into the target, members set to null are removed
+func merge(target interface{}, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = merge(t[k], v)
+	}
+	return t
+}
+
+// Operation an RFC 6902 JSON patch operation
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Fields the top level members of a document the operation changes, an empty field is the whole document
+func (o *Operation) Fields() []string {
+	fields := []string{field(o.Path)}
+	if o.Op == "move" {
+		fields = append(fields, field(o.From))
+	}
+	return fields
+}
+
+// field the top level member of a JSON pointer
+func field(pointer string) string {
+	tokens, err := parsePointer(pointer)
+	if err != nil || len(tokens) == 0 {
+		return ""
+	}
+	return tokens[0]
+}
+
+// Operations parses a JSON patch
+func Operations(patch []byte) ([]*Operation, error) {
+	ops := []*Operation{}
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, errors.New("a JSON patch must be an array of operations")
+	}
+	for _, o := range ops {
+		switch o.Op {
+		case "add", "replace", "test":
+			if o.Value == nil {
+				return nil, fmt.Errorf("%s %s needs a value", o.Op, o.Path)
+			}
+		case "move", "copy":
+			if _, err := parsePointer(o.From); err != nil {
+				return nil, err
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("unknown operation %q", o.Op)
+		}
+		if _, err := parsePointer(o.Path); err != nil {
+			return nil, err
+		}
+	}
+	return ops, nil
+}
+
+// Apply applies JSON patch operations to a document in order, none are applied when one fails
+func Apply(doc []byte, ops []*Operation) ([]byte, error) {
+	d, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range ops {
+		if d, err = apply(d, o); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(d)
+}
+
+// apply applies an operation to a decoded document and returns the patched document
+func apply(doc interface{}, o *Operation) (interface{}, error) {
+	path, _ := parsePointer(o.Path)
+	from, _ := parsePointer(o.From)
+
+	switch o.Op {
+	case "add":
+		value, err := decode(o.Value)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "remove":
+		return remove(doc, path)
+	case "replace":
+		value, err := decode(o.Value)
+		if err != nil {
+			return nil, err
+		}
+		if doc, err = remove(doc, path); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "move":
+		if len(from) < len(path) && reflect.DeepEqual(from, path[:len(from)]) {
+			return nil, fmt.Errorf("%s can't be moved into itself", o.From)
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if doc, err = remove(doc, from); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "copy":
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		// the value is decoded again so the copy doesn't share members with the original
+		b, _ := json.Marshal(value)
+		value, _ = decode(b)
+		return add(doc, path, value)
+	case "test":
+		value, err := decode(o.Value)
+		if err != nil {
+			return nil, err
+		}
+		actual, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(actual, value) {
+			return nil, fmt.Errorf("%s: %v", o.Path, ErrTestFailed)
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown operation %q", o.Op)
+}
+
+// add sets the member at the path, values are inserted into arrays and - appends to them
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	return update(doc, path, func(parent interface{}, key string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			p[key] = value
+			return p, nil
+		case []interface{}:
+			i := len(p)
+			if key != "-" {
+				var err error
+				if i, err = index(key, len(p)+1); err != nil {
+					return nil, err
+				}
+			}
+			p = append(p, nil)
+			copy(p[i+1:], p[i:])
+			p[i] = value
+			return p, nil
+		}
+		return nil, fmt.Errorf("%s can't be added to a value", key)
+	}, value)
+}
+
+// remove removes the member at the path, it must exist
+func remove(doc interface{}, path []string) (interface{}, error) {
+	return update(doc, path, func(parent interface{}, key string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := p[key]; !ok {
+				return nil, fmt.Errorf("%s doesn't exist", key)
+			}
+			delete(p, key)
+			return p, nil
+		case []interface{}:
+			i, err := index(key, len(p))
+			if err != nil {
+				return nil, err
+			}
+			return append(p[:i], p[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("%s doesn't exist", key)
+	}, nil)
+}
+
+// update calls f with the parent of the last token of the path and replaces the parent with its result,
+// an empty path replaces the whole document with the value
+func update(doc interface{}, path []string, f func(parent interface{}, key string) (interface{}, error), value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	if len(path) == 1 {
+		return f(doc, path[0])
+	}
+	child, err := get(doc, path[:1])
+	if err != nil {
+		return nil, err
+	}
+	if child, err = update(child, path[1:], f, value); err != nil {
+		return nil, err
+	}
+	switch p := doc.(type) {
+	case map[string]interface{}:
+		p[path[0]] = child
+	case []interface{}:
+		i, _ := index(path[0], len(p))
+		p[i] = child
+	}
+	return doc, nil
+}
+
+// get the value at the path
+func get(doc interface{}, path []string) (interface{}, error) {
+	for _, key := range path {
+		switch d := doc.(type) {
+		case map[string]interface{}:
+			v, ok := d[key]
+			if !ok {
+				return nil, fmt.Errorf("%s doesn't exist", key)
+			}
+			doc = v
+		case []interface{}:
+			i, err := index(key, len(d))
+			if err != nil {
+				return nil, err
+			}
+			doc = d[i]
+		default:
+			return nil, fmt.Errorf("%s doesn't exist", key)
+		}
+	}
+	return doc, nil
+}
+
+// index parses an array index lower than n
+func index(key string, n int) (int, error) {
+	i, err := strconv.Atoi(key)
+	if err != nil || i < 0 || i >= n || (len(key) > 1 && key[0] == '0') {
+		return 0, fmt.Errorf("%s is out of range", key)
+	}
+	return i, nil
+}
+
+// parsePointer splits an RFC 6901 JSON pointer into its reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%q isn't a JSON pointer", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(t, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+// equal whether two decoded values are the same JSON value, numbers are compared by value
+func equal(a interface{}, b interface{}) bool {
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fx, errx := x.Float64()
+		fy, erry := y.Float64()
+		return errx == nil && erry == nil && fx == fy
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			if w, ok := y[k]; !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// decode decodes a JSON value keeping numbers as they were written
+func decode(b []byte) (interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
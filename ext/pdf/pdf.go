@@ -0,0 +1,114 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// LinesPerPage how many lines of text fit on a letter sized page
+const LinesPerPage = 50
+
+// Document a plain text PDF document with a title, written without any dependencies.
+// Monospace documents are set in Courier so columns of text line up. Text is WinAnsi encoded, which covers
+// the accented letters of western languages, other characters are written as ?
+type Document struct {
+	Title     string
+	Lines     []string
+	Monospace bool
+}
+
+// Bytes renders the document as a PDF, the title is bold and lines wrap onto new pages
+func (d *Document) Bytes() []byte {
+	pages := [][]string{}
+	for i := 0; i < len(d.Lines) || i == 0; i += LinesPerPage {
+		end := i + LinesPerPage
+		if end > len(d.Lines) {
+			end = len(d.Lines)
+		}
+		pages = append(pages, d.Lines[i:end])
+	}
+
+	// objects 1 and 2 are the catalog and page tree, 3 and 4 the body and title fonts, then a page and its content per page
+	font := "Helvetica"
+	if d.Monospace {
+		font = "Courier"
+	}
+	objects := []string{"", "", "<< /Type /Font /Subtype /Type1 /BaseFont /" + font + " /Encoding /WinAnsiEncoding >>", "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>"}
+	kids := []string{}
+	for i, lines := range pages {
+		page := len(objects) + 1
+		content := d.content(i, lines)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", page+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		)
+		kids = append(kids, fmt.Sprintf("%d 0 R", page))
+	}
+	objects[0] = "<< /Type /Catalog /Pages 2 0 R >>"
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("%PDF-1.4\n")
+	offsets := []int{}
+	for i, o := range objects {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(buf, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func (d *Document) content(page int, lines []string) string {
+	buf := &bytes.Buffer{}
+	buf.WriteString("BT\n")
+	if page == 0 {
+		fmt.Fprintf(buf, "/F2 16 Tf 72 740 Td (%s) Tj 0 -28 Td\n", escape(d.Title))
+	} else {
+		buf.WriteString("72 740 Td\n")
+	}
+	buf.WriteString("/F1 11 Tf 14 TL\n")
+	for _, line := range lines {
+		fmt.Fprintf(buf, "(%s) Tj T*\n", escape(line))
+	}
+	buf.WriteString("ET")
+	return buf.String()
+}
+
+// winAnsi the characters WinAnsiEncoding puts in 0x80 to 0x9f, the rest of 0xa0 to 0xff are the same as Unicode
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a,
+	'‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// escape encodes a string as a PDF string in WinAnsiEncoding, escaping the characters which are special in PDF
+// strings. Characters outside of ASCII are written as octal escapes and ones WinAnsiEncoding doesn't have as ?
+func escape(s string) string {
+	buf := &bytes.Buffer{}
+	for _, c := range s {
+		switch {
+		case c == '\\' || c == '(' || c == ')':
+			buf.WriteByte('\\')
+			buf.WriteRune(c)
+		case c == '\r':
+		case c == '\n' || c == '\t':
+			buf.WriteByte(' ')
+		case c >= ' ' && c <= '~':
+			buf.WriteRune(c)
+		case c >= 0xa0 && c <= 0xff:
+			fmt.Fprintf(buf, "\\%03o", c)
+		case winAnsi[c] != 0:
+			fmt.Fprintf(buf, "\\%03o", winAnsi[c])
+		default:
+			buf.WriteByte('?')
+		}
+	}
+	return buf.String()
+}
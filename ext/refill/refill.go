@@ -0,0 +1,125 @@
+package refill
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/log"
+	"github.com/jacsmith21/lukabox/ext/pdf"
+)
+
+// PDF renders a refill request as a PDF
+func PDF(request *domain.RefillRequest) []byte {
+	p := request.Patient
+	m := request.Medication
+	doc := &pdf.Document{
+		Title: "Refill Request",
+		Lines: []string{
+			fmt.Sprintf("Date: %s", request.Created.Format("January 2, 2006")),
+			"",
+			fmt.Sprintf("Patient: %s %s", p.FirstName, p.LastName),
+			fmt.Sprintf("Email: %s", p.Email),
+			"",
+			fmt.Sprintf("Medication: %s", m.Name),
+			fmt.Sprintf("Strength: %s", m.Strength),
+			fmt.Sprintf("Quantity: %s", strconv.FormatFloat(request.Quantity, 'f', -1, 64)),
+		},
+	}
+	return doc.Bytes()
+}
+
+// Outbox an in memory implementation of domain.RefillChannel which keeps every request it sends, useful for testing
+type Outbox struct {
+	mu       sync.Mutex
+	Requests []*domain.RefillRequest
+}
+
+// SendRefill adds the request to the outbox
+func (o *Outbox) SendRefill(request *domain.RefillRequest) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	log.WithField("pillId", request.Medication.PillID).Debug("sending refill request to outbox")
+	o.Requests = append(o.Requests, request)
+	return nil
+}
+
+// Mail an implementation of domain.RefillChannel which emails the request to a pharmacy with the PDF and JSON attached
+type Mail struct {
+	Mailer domain.Mailer
+	To     string
+}
+
+// SendRefill emails the request
+func (m *Mail) SendRefill(request *domain.RefillRequest) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	p := request.Patient
+	email := &domain.Email{
+		To:      m.To,
+		Subject: fmt.Sprintf("Refill request for %s %s", p.FirstName, p.LastName),
+		Body:    fmt.Sprintf("Please refill %s %s for %s %s, the request is attached.", request.Medication.Name, request.Medication.Strength, p.FirstName, p.LastName),
+		Attachments: []*domain.Attachment{
+			{Name: "refill.pdf", ContentType: "application/pdf", Data: PDF(request)},
+			{Name: "refill.json", ContentType: "application/json", Data: data},
+		},
+	}
+	return m.Mailer.Send(email)
+}
+
+// Fax an implementation of domain.RefillChannel which posts the PDF to a fax gateway along with the pharmacy's number
+type Fax struct {
+	URL    string
+	Number string
+	Client *http.Client
+}
+
+// SendRefill faxes the request
+func (f *Fax) SendRefill(request *domain.RefillRequest) error {
+	u := f.URL + "?" + url.Values{"to": {f.Number}}.Encode()
+	return post(f.Client, u, "application/pdf", PDF(request))
+}
+
+// Webhook an implementation of domain.RefillChannel which posts the JSON request to a url
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+// SendRefill posts the request
+func (h *Webhook) SendRefill(request *domain.RefillRequest) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	return post(h.Client, h.URL, "application/json", data)
+}
+
+func post(client *http.Client, u string, contentType string, data []byte) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if u == "" {
+		return errors.New("no url configured")
+	}
+
+	resp, err := client.Post(u, contentType, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %d", u, resp.StatusCode)
+	}
+	return nil
+}
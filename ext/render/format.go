@@ -0,0 +1,122 @@
+package render
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+// ContentType a content type a response is negotiated into, the content types of go-chi/render and the ones
+// of reports
+type ContentType = render.ContentType
+
+// The content types responses can be negotiated into, csv and pdf follow on from the ones of go-chi/render
+const (
+	ContentTypeJSON ContentType = render.ContentTypeJSON
+	ContentTypeCSV  ContentType = 100
+	ContentTypePDF  ContentType = 101
+)
+
+// formats the names and media types of the content types, the names are used by the format parameter and
+// the url extension
+var formats = []struct {
+	name        string
+	mediaType   string
+	contentType ContentType
+}{
+	{"json", "application/json", ContentTypeJSON},
+	{"csv", "text/csv", ContentTypeCSV},
+	{"pdf", "application/pdf", ContentTypePDF},
+}
+
+// Negotiate negotiates the content type of the response from the offered ones. The format parameter or the url
+// extension, ie. json, csv or pdf, take precedence over the Accept header and the first offered content type is
+// the default. The content type is set like render.SetContentType sets it so AcceptedContentType returns it
+func Negotiate(offered ...ContentType) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contentType, ok := negotiate(r, offered)
+			if !ok {
+				names := []string{}
+				for _, c := range offered {
+					names = append(names, name(c))
+				}
+				if len(names) > 1 {
+					names = append(names[:len(names)-2], names[len(names)-2]+" or "+names[len(names)-1])
+				}
+				WithMessage("format must be one of "+strings.Join(names, ", ")).BadRequest(w, r)
+				return
+			}
+			render.SetContentType(contentType)(next).ServeHTTP(w, r)
+		})
+	}
+}
+
+// negotiate the offered content type requested, false when the format parameter or extension isn't offered
+func negotiate(r *http.Request, offered []ContentType) (ContentType, bool) {
+	requested := r.URL.Query().Get("format")
+	if requested == "" {
+		requested, _ = r.Context().Value(middleware.URLFormatCtxKey).(string)
+	}
+	if requested != "" {
+		for _, c := range offered {
+			if name(c) == requested {
+				return c, true
+			}
+		}
+		return 0, false
+	}
+
+	for _, field := range strings.Split(r.Header.Get("Accept"), ",") {
+		requested := contentType(field)
+		for _, c := range offered {
+			if c == requested {
+				return c, true
+			}
+		}
+	}
+	return offered[0], true
+}
+
+// contentType the content type of a media type, ie. a field of an Accept header
+func contentType(mediaType string) ContentType {
+	mediaType = strings.TrimSpace(strings.Split(mediaType, ";")[0])
+	for _, f := range formats {
+		if f.mediaType == mediaType {
+			return f.contentType
+		}
+	}
+	return render.GetContentType(mediaType)
+}
+
+// name the name of a content type in the format parameter and the url extension
+func name(contentType ContentType) string {
+	for _, f := range formats {
+		if f.contentType == contentType {
+			return f.name
+		}
+	}
+	return ""
+}
+
+// MediaType the media type of a content type
+func MediaType(contentType ContentType) string {
+	for _, f := range formats {
+		if f.contentType == contentType {
+			return f.mediaType
+		}
+	}
+	return ""
+}
+
+// SetContentType sets the content type of the responses, routes which offer others negotiate them with Negotiate
+func SetContentType(contentType ContentType) func(next http.Handler) http.Handler {
+	return render.SetContentType(contentType)
+}
+
+// AcceptedContentType the content type negotiated for the response
+func AcceptedContentType(r *http.Request) ContentType {
+	return render.GetAcceptedContentType(r)
+}
@@ -22,15 +22,26 @@ func List(w http.ResponseWriter, r *http.Request, l []render.Renderer) error {
 	return render.RenderList(w, r, l)
 }
 
+// Status sets the status code of the response rendered with Instance or List
+func Status(r *http.Request, status int) {
+	render.Status(r, status)
+}
+
 // Renderer interface
 type Renderer interface {
 	Render(w http.ResponseWriter, r *http.Request) error
 }
 
-// ErrRenderer ErrRenderer
+// ErrRenderer ErrRenderer, Fields has a message for every invalid field of a request
 type ErrRenderer struct {
-	HTTPStateCode int    `json:"-"`
-	Message       string `json:"message,omitempty"`
+	HTTPStateCode int               `json:"-"`
+	Message       string            `json:"message,omitempty"`
+	Fields        map[string]string `json:"fields,omitempty"`
+}
+
+// fieldErrors an error with a message for every invalid field, ie. validate.Errors
+type fieldErrors interface {
+	Fields() map[string]string
 }
 
 // Render Renderer implementation
@@ -44,9 +55,12 @@ func (ren *ErrRenderer) Render(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
-// WithError renders with error
+// WithError renders with error, including the messages of its fields
 func WithError(err error) *ErrRenderer {
 	renderer := &ErrRenderer{Message: err.Error()}
+	if fe, ok := err.(fieldErrors); ok {
+		renderer.Fields = fe.Fields()
+	}
 	return renderer
 }
 
@@ -87,3 +101,39 @@ func Unauthorized(w http.ResponseWriter, r *http.Request) {
 	ren.Message = "unauthorized"
 	render.Render(w, r, ren)
 }
+
+// TooManyRequests renders a too many requests
+func (ren *ErrRenderer) TooManyRequests(w http.ResponseWriter, r *http.Request) {
+	ren.HTTPStateCode = http.StatusTooManyRequests
+	render.Render(w, r, ren)
+}
+
+// PreconditionFailed renders a precondition failed
+func (ren *ErrRenderer) PreconditionFailed(w http.ResponseWriter, r *http.Request) {
+	ren.HTTPStateCode = http.StatusPreconditionFailed
+	render.Render(w, r, ren)
+}
+
+// Forbidden renders a forbidden
+func (ren *ErrRenderer) Forbidden(w http.ResponseWriter, r *http.Request) {
+	ren.HTTPStateCode = http.StatusForbidden
+	render.Render(w, r, ren)
+}
+
+// UnsupportedMediaType renders an unsupported media type
+func (ren *ErrRenderer) UnsupportedMediaType(w http.ResponseWriter, r *http.Request) {
+	ren.HTTPStateCode = http.StatusUnsupportedMediaType
+	render.Render(w, r, ren)
+}
+
+// RequestEntityTooLarge renders a request entity too large
+func (ren *ErrRenderer) RequestEntityTooLarge(w http.ResponseWriter, r *http.Request) {
+	ren.HTTPStateCode = http.StatusRequestEntityTooLarge
+	render.Render(w, r, ren)
+}
+
+// UnprocessableEntity renders an unprocessable entity
+func (ren *ErrRenderer) UnprocessableEntity(w http.ResponseWriter, r *http.Request) {
+	ren.HTTPStateCode = http.StatusUnprocessableEntity
+	render.Render(w, r, ren)
+}
@@ -0,0 +1,144 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/pdf"
+)
+
+// CSV renders an adherence report as CSV, the pill table, the daily table and the notes are separated by blank lines.
+// Cells which spreadsheets would run as formulas are quoted
+func CSV(report *domain.AdherenceReport) []byte {
+	buf := &bytes.Buffer{}
+	w := &formulaWriter{csv.NewWriter(buf)}
+
+	w.Write([]string{"pill", "dosage", "scheduled", "taken", "late", "missed", "as needed", "adherence"})
+	for _, p := range report.Pills {
+		w.Write([]string{p.Name, p.Dosage, strconv.Itoa(p.Scheduled), strconv.Itoa(p.Taken), strconv.Itoa(p.Late), strconv.Itoa(p.Missed), strconv.Itoa(p.AsNeeded), rate(&p.AdherenceCounts)})
+	}
+
+	w.Write([]string{})
+	w.Write([]string{"date", "scheduled", "taken", "late", "missed", "adherence"})
+	for _, d := range report.Days {
+		w.Write([]string{d.Day.Format("2006-01-02"), strconv.Itoa(d.Scheduled), strconv.Itoa(d.Taken), strconv.Itoa(d.Late), strconv.Itoa(d.Missed), rate(&d.AdherenceCounts)})
+	}
+
+	w.Write([]string{})
+	w.Write([]string{"note"})
+	for _, n := range report.Notes {
+		w.Write([]string{n})
+	}
+
+	w.Flush()
+	return buf.Bytes()
+}
+
+// formulaWriter a CSV writer which prefixes cells starting with =, +, - or @ with ' so spreadsheets show them as
+// text instead of running them as formulas, ie. a pill named =HYPERLINK(...)
+type formulaWriter struct {
+	*csv.Writer
+}
+
+// Write writes a record with its formulas quoted
+func (w *formulaWriter) Write(record []string) error {
+	quoted := []string{}
+	for _, cell := range record {
+		if cell != "" && strings.ContainsRune("=+-@", rune(cell[0])) {
+			cell = "'" + cell
+		}
+		quoted = append(quoted, cell)
+	}
+	return w.Writer.Write(quoted)
+}
+
+// PDF renders an adherence report for a user as a PDF with a table of pills, a heatmap of each day and the notes
+func PDF(user *domain.User, report *domain.AdherenceReport) []byte {
+	lines := []string{
+		fmt.Sprintf("Patient: %s %s", user.FirstName, user.LastName),
+		fmt.Sprintf("Period:  %s - %s", report.From.Format("January 2, 2006"), report.To.Add(-time.Nanosecond).Format("January 2, 2006")),
+		"",
+		fmt.Sprintf("%-24s %9s %6s %5s %6s %9s", "Medication", "Scheduled", "Taken", "Late", "Missed", "Adherence"),
+	}
+	for _, p := range report.Pills {
+		name := strings.TrimSpace(p.Name + " " + p.Dosage)
+		if r := []rune(name); len(r) > 24 {
+			name = string(r[:21]) + "..."
+		}
+		if p.Scheduled == 0 && p.AsNeeded > 0 {
+			lines = append(lines, fmt.Sprintf("%-24s %9s %6d", name, "as needed", p.AsNeeded))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%-24s %9d %6d %5d %6d %9s", name, p.Scheduled, p.Taken, p.Late, p.Missed, rate(&p.AdherenceCounts)))
+	}
+
+	lines = append(lines, "", "Daily adherence")
+	lines = append(lines, heatmap(report.Days)...)
+	lines = append(lines, "", "@@@ 90% or more  +++ 50% or more  ... under 50%  ??? pending", " -  nothing scheduled")
+
+	if len(report.Notes) > 0 {
+		lines = append(lines, "", "Notes")
+		for _, n := range report.Notes {
+			lines = append(lines, "- "+n)
+		}
+	}
+
+	doc := &pdf.Document{Title: "Adherence Report", Lines: lines, Monospace: true}
+	return doc.Bytes()
+}
+
+// heatmap a row per week with a cell per day shaded by the day's adherence
+func heatmap(days []*domain.DayAdherence) []string {
+	lines := []string{fmt.Sprintf("%-10s %s", "Week of", "Mon Tue Wed Thu Fri Sat Sun")}
+	if len(days) == 0 {
+		return lines
+	}
+
+	// weeks start on monday
+	first := days[0].Day
+	offset := (int(first.Weekday()) + 6) % 7
+	cells := make([]string, offset)
+	for i := range cells {
+		cells[i] = "   "
+	}
+	for _, d := range days {
+		cells = append(cells, cell(d))
+	}
+
+	monday := first.AddDate(0, 0, -offset)
+	for i := 0; i < len(cells); i += 7 {
+		end := i + 7
+		if end > len(cells) {
+			end = len(cells)
+		}
+		lines = append(lines, strings.TrimRight(fmt.Sprintf("%-10s %s", monday.AddDate(0, 0, i).Format("Jan 2"), strings.Join(cells[i:end], " ")), " "))
+	}
+	return lines
+}
+
+func cell(d *domain.DayAdherence) string {
+	switch {
+	case d.Scheduled == 0:
+		return " - "
+	case d.Taken+d.Late+d.Missed == 0:
+		return "???"
+	case d.Rate() >= 0.9:
+		return "@@@"
+	case d.Rate() >= 0.5:
+		return "+++"
+	}
+	return "..."
+}
+
+// rate the adherence rate as a percentage, empty when no doses have an outcome
+func rate(c *domain.AdherenceCounts) string {
+	if c.Taken+c.Late+c.Missed == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.0f%%", c.Rate()*100)
+}
@@ -0,0 +1,90 @@
+package stream
+
+import (
+	"sync"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// DefaultHistory how many of each user's recent events are kept to resume streams by default
+const DefaultHistory = 100
+
+// buffer how many events a subscriber can fall behind before it is dropped
+const buffer = 32
+
+// Broker an in memory implementation of domain.EventBroker. Each user's last History events are kept so
+// clients can resume, subscribers which fall behind are dropped and expected to resume from their last event
+type Broker struct {
+	History int
+
+	mu          sync.Mutex
+	lastID      int64
+	history     map[int][]*domain.StreamEvent
+	subscribers map[int]map[chan *domain.StreamEvent]bool
+}
+
+// Publish assigns the event the next id and sends it to the user's subscribers
+func (b *Broker) Publish(event *domain.StreamEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.history == nil {
+		b.history = map[int][]*domain.StreamEvent{}
+	}
+	history := b.History
+	if history <= 0 {
+		history = DefaultHistory
+	}
+
+	b.lastID++
+	event.ID = b.lastID
+	events := append(b.history[event.UserID], event)
+	if len(events) > history {
+		events = events[len(events)-history:]
+	}
+	b.history[event.UserID] = events
+
+	for ch := range b.subscribers[event.UserID] {
+		select {
+		case ch <- event:
+		default:
+			delete(b.subscribers[event.UserID], ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe subscribes to a user's events, the backlog has the kept events after lastEventID
+func (b *Broker) Subscribe(userID int, lastEventID int64) *domain.StreamSubscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subscribers == nil {
+		b.subscribers = map[int]map[chan *domain.StreamEvent]bool{}
+	}
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = map[chan *domain.StreamEvent]bool{}
+	}
+
+	backlog := []*domain.StreamEvent{}
+	if lastEventID > 0 {
+		for _, e := range b.history[userID] {
+			if e.ID > lastEventID {
+				backlog = append(backlog, e)
+			}
+		}
+	}
+
+	ch := make(chan *domain.StreamEvent, buffer)
+	b.subscribers[userID][ch] = true
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.subscribers[userID][ch] {
+			delete(b.subscribers[userID], ch)
+			close(ch)
+		}
+	}
+	return &domain.StreamSubscription{Backlog: backlog, Events: ch, Cancel: cancel}
+}
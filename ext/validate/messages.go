@@ -0,0 +1,67 @@
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator"
+)
+
+// messages the message of every rule in every supported language, %[1]s is the field and %[2]s the parameter
+// of the rule. Rules on the length of lists have an .items message
+var messages = map[string]map[string]string{
+	"en": {
+		"required":  "%[1]s is required",
+		"email":     "%[1]s must be a valid email address",
+		"url":       "%[1]s must be a valid url",
+		"oneof":     "%[1]s must be one of %[2]s",
+		"min":       "%[1]s must be at least %[2]s",
+		"min.items": "%[1]s must have at least %[2]s items",
+		"max":       "%[1]s must be at most %[2]s",
+		"max.items": "%[1]s must have at most %[2]s items",
+		"gte":       "%[1]s must be at least %[2]s",
+		"lte":       "%[1]s must be at most %[2]s",
+		"day":       "%[1]s must be a day of the week from 1 for Monday to 7 for Sunday",
+		"nooverlap": "%[1]s must not have the same time twice",
+		"password":  "%[1]s must be at least 8 characters with a letter and a number",
+		"notfuture": "%[1]s can't be in the future",
+		"invalid":   "%[1]s is invalid",
+	},
+	"fr": {
+		"required":  "%[1]s est obligatoire",
+		"email":     "%[1]s doit être une adresse courriel valide",
+		"url":       "%[1]s doit être une url valide",
+		"oneof":     "%[1]s doit être l'une des valeurs %[2]s",
+		"min":       "%[1]s doit être au moins %[2]s",
+		"min.items": "%[1]s doit avoir au moins %[2]s éléments",
+		"max":       "%[1]s doit être au plus %[2]s",
+		"max.items": "%[1]s doit avoir au plus %[2]s éléments",
+		"gte":       "%[1]s doit être au moins %[2]s",
+		"lte":       "%[1]s doit être au plus %[2]s",
+		"day":       "%[1]s doit être un jour de la semaine de 1 pour lundi à 7 pour dimanche",
+		"nooverlap": "%[1]s ne doit pas avoir la même heure deux fois",
+		"password":  "%[1]s doit avoir au moins 8 caractères dont une lettre et un chiffre",
+		"notfuture": "%[1]s ne peut pas être dans le futur",
+		"invalid":   "%[1]s n'est pas valide",
+	},
+}
+
+// message the message of a field which failed a rule in the language
+func message(lang string, field string, fe validator.FieldError) string {
+	catalogue := messages[lang]
+
+	key := fe.Tag()
+	switch fe.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if _, ok := catalogue[key+".items"]; ok {
+			key += ".items"
+		}
+	}
+
+	format, ok := catalogue[key]
+	if !ok {
+		format = catalogue["invalid"]
+	}
+	return fmt.Sprintf(format, field, strings.Replace(fe.Param(), " ", ", ", -1))
+}
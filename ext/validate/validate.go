@@ -0,0 +1,161 @@
+package validate
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-playground/validator"
+)
+
+// Skew how far in the future an event can be to allow for clocks which are a little fast
+const Skew = 5 * time.Minute
+
+// MinPasswordLength the minimum length of a password
+const MinPasswordLength = 8
+
+// Errors the invalid fields of a struct keyed by their JSON path, ie. daysOfWeek[0], with their messages
+type Errors map[string]string
+
+// Error the messages of the fields in order of their paths
+func (e Errors) Error() string {
+	fields := make([]string, 0, len(e))
+	for f := range e {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+
+	messages := make([]string, 0, len(e))
+	for _, f := range fields {
+		messages = append(messages, e[f])
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Fields the message of every invalid field
+func (e Errors) Fields() map[string]string {
+	return e
+}
+
+// validate the shared validator, it caches the rules of every struct it has seen and is safe for concurrent use
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+	v.RegisterValidation("day", day)
+	v.RegisterValidation("nooverlap", noOverlap)
+	v.RegisterValidation("password", password)
+	v.RegisterValidation("notfuture", notFuture)
+	return v
+}
+
+// Struct validates a struct, the messages of the Errors are in the language of the request. Requests
+// which didn't come over http like device rpcs can be nil and get english messages
+func Struct(r *http.Request, s interface{}) error {
+	return translate(validate.Struct(s), language(r), "")
+}
+
+// Var validates a single value against the tag, field names it in the messages
+func Var(r *http.Request, field string, value interface{}, tag string) error {
+	return translate(validate.Var(value, tag), language(r), field)
+}
+
+// translate converts the errors of the validator to Errors, field names values validated on their own
+func translate(err error, lang string, field string) error {
+	fieldErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+
+	errs := Errors{}
+	for _, fe := range fieldErrors {
+		path := field
+		if ns := fe.Namespace(); ns != "" {
+			// the namespace starts with the name of the struct type, ie. Pill.daysOfWeek[0]
+			path = ns[strings.Index(ns, ".")+1:]
+		}
+		errs[path] = message(lang, path, fe)
+	}
+	return errs
+}
+
+// day whether an int is a day of the week numbered 1 for Monday through 7 for Sunday
+func day(fl validator.FieldLevel) bool {
+	d := fl.Field().Int()
+	return d >= 1 && d <= 7
+}
+
+// noOverlap whether a list of times of day doesn't have the same time twice, ie. 08:00 and 8:00
+func noOverlap(fl validator.FieldLevel) bool {
+	list := fl.Field()
+	if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+		return false
+	}
+	seen := map[string]bool{}
+	for i := 0; i < list.Len(); i++ {
+		t := fmt.Sprint(list.Index(i).Interface())
+		if seen[t] {
+			return false
+		}
+		seen[t] = true
+	}
+	return true
+}
+
+// password whether a password is at least MinPasswordLength characters with a letter and a number
+func password(fl validator.FieldLevel) bool {
+	p := fl.Field().String()
+	letter, number := false, false
+	for _, c := range p {
+		letter = letter || unicode.IsLetter(c)
+		number = number || unicode.IsDigit(c)
+	}
+	return len([]rune(p)) >= MinPasswordLength && letter && number
+}
+
+// notFuture whether a time isn't in the future, allowing for Skew
+func notFuture(fl validator.FieldLevel) bool {
+	t, ok := fl.Field().Interface().(time.Time)
+	return ok && !t.After(time.Now().Add(Skew))
+}
+
+// language the supported language the request prefers from its Accept-Language, english by default
+func language(r *http.Request) string {
+	if r == nil {
+		return "en"
+	}
+
+	best, quality := "en", 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag := strings.TrimSpace(part)
+		q := 1.0
+		if i := strings.Index(tag, ";"); i >= 0 {
+			if v := strings.TrimSpace(tag[i+1:]); strings.HasPrefix(v, "q=") {
+				if parsed, err := strconv.ParseFloat(v[2:], 64); err == nil {
+					q = parsed
+				}
+			}
+			tag = tag[:i]
+		}
+		lang := strings.ToLower(strings.SplitN(tag, "-", 2)[0])
+		if _, ok := messages[lang]; ok && q > quality {
+			best, quality = lang, q
+		}
+	}
+	return best
+}
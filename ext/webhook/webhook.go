@@ -0,0 +1,163 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// The headers sent with every delivery
+const (
+	SignatureHeader = "X-Lukabox-Signature"
+	EventHeader     = "X-Lukabox-Event"
+	DeliveryHeader  = "X-Lukabox-Delivery"
+)
+
+// Sign signs a payload sent at t with a webhook's secret, the signature is
+// "t=<unix time>,v1=<hex hmac-sha256 of "<unix time>.<payload>">" so receivers can reject replays
+func Sign(secret string, t time.Time, payload []byte) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// private the networks webhooks can't be delivered to: this host, private networks, link local addresses like
+// the cloud metadata service and addresses which aren't routable
+var private = networks(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12",
+	"192.0.0.0/24", "192.168.0.0/16", "198.18.0.0/15", "224.0.0.0/4", "240.0.0.0/4",
+	"::/128", "::1/128", "fc00::/7", "fe80::/10", "ff00::/8",
+)
+
+func networks(cidrs ...string) []*net.IPNet {
+	nets := []*net.IPNet{}
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// Public whether an address is on the public internet
+func Public(ip net.IP) bool {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	for _, n := range private {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL checks the url of a webhook when it's subscribed, it must be https and can't name a private
+// address. Hostnames are checked again when they're resolved for every delivery
+func CheckURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" {
+		return errors.New("url must use https")
+	}
+	host := strings.ToLower(u.Hostname())
+	if ip := net.ParseIP(host); host == "localhost" || strings.HasSuffix(host, ".localhost") || (ip != nil && !Public(ip)) {
+		return errors.New("url must be a public address")
+	}
+	return nil
+}
+
+// NewClient a client which only connects to public addresses and doesn't follow redirects, the addresses are
+// checked once they're resolved so a hostname can't point a delivery at the internal network
+func NewClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network string, addr string) (net.Conn, error) {
+			host, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+			for _, ip := range ips {
+				if !Public(ip.IP) {
+					return nil, fmt.Errorf("%s is not a public address", host)
+				}
+			}
+			if len(ips) == 0 {
+				return nil, fmt.Errorf("%s has no addresses", host)
+			}
+			return dialer.DialContext(ctx, network, net.JoinHostPort(ips[0].IP.String(), port))
+		},
+		TLSHandshakeTimeout: 5 * time.Second,
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   10 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Sender an implementation of domain.WebhookSender which posts signed payloads over HTTPS, the Client defaults
+// to NewClient
+type Sender struct {
+	Client *http.Client
+	Now    func() time.Time
+}
+
+// Send posts a delivery's payload to the webhook, any response other than 2xx is an error including redirects
+func (s *Sender) Send(webhook *domain.Webhook, delivery *domain.WebhookDelivery) (int, error) {
+	client := s.Client
+	if client == nil {
+		client = NewClient()
+	}
+	if u, err := url.Parse(webhook.URL); err != nil || u.Scheme != "https" {
+		return 0, errors.New("url must use https")
+	}
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, now(), delivery.Payload))
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, strconv.Itoa(delivery.ID))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("%s returned %d", webhook.URL, resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
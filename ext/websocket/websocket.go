@@ -0,0 +1,267 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// guid the key suffix defined by RFC 6455 to compute Sec-WebSocket-Accept
+const guid = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxControlPayload the largest payload of a control frame, larger client frames are rejected
+const maxControlPayload = 125
+
+// The opcodes of the frames used
+const (
+	textFrame  = 0x1
+	closeFrame = 0x8
+	pingFrame  = 0x9
+	pongFrame  = 0xA
+)
+
+// The close codes sent by the server
+const (
+	NormalClosure = 1000
+	GoingAway     = 1001
+)
+
+// IsUpgrade whether a request asks to upgrade to a WebSocket
+func IsUpgrade(r *http.Request) bool {
+	return headerContains(r.Header, "Connection", "upgrade") && headerContains(r.Header, "Upgrade", "websocket")
+}
+
+// Accept the Sec-WebSocket-Accept value for a Sec-WebSocket-Key
+func Accept(key string) string {
+	h := sha1.Sum([]byte(key + guid))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// Conn a server side WebSocket connection which sends text messages. Messages from the client are discarded,
+// pings are answered and Done is closed once the client closes the connection or it fails
+type Conn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	mu   sync.Mutex
+	done chan struct{}
+	once sync.Once
+}
+
+// ErrOrigin the error of upgrades from a page on an origin which isn't allowed
+var ErrOrigin = errors.New("origin not allowed")
+
+// AllowedOrigin whether a request comes from the same origin as the server, from an allowed origin or from a
+// client which isn't a browser and doesn't send an Origin. Browsers send cookies with upgrades from any page,
+// so upgrades which are authorised by a cookie must be checked
+func AllowedOrigin(r *http.Request, origins []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if u.Host != "" && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, o := range origins {
+		if strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Upgrade completes the opening handshake of a WebSocket and takes over the connection from the server, pages
+// on other origins than the server's must be in origins
+func Upgrade(w http.ResponseWriter, r *http.Request, origins []string) (*Conn, error) {
+	if r.Method != http.MethodGet || !IsUpgrade(r) {
+		return nil, errors.New("not a websocket upgrade")
+	}
+	if !AllowedOrigin(r, origins) {
+		return nil, ErrOrigin
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, errors.New("unsupported websocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, errors.New("no websocket key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("the connection can't be hijacked")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + Accept(key) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	c := &Conn{conn: conn, rw: rw, done: make(chan struct{})}
+	go c.read()
+	return c, nil
+}
+
+// Done is closed once the connection is closed
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// WriteText sends a text message
+func (c *Conn) WriteText(message []byte) error {
+	return c.write(textFrame, message)
+}
+
+// Ping sends a ping, used as a heartbeat
+func (c *Conn) Ping() error {
+	return c.write(pingFrame, nil)
+}
+
+// Close sends a close frame with a code and closes the connection
+func (c *Conn) Close(code int, reason string) error {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	err := c.write(closeFrame, append(payload, reason...))
+	c.close()
+	return err
+}
+
+func (c *Conn) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// write sends an unfragmented, unmasked frame as servers must not mask frames
+func (c *Conn) write(opcode byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n <= 125:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126, byte(n>>8), byte(n))
+	default:
+		header = append(header, 127)
+		header = append(header, make([]byte, 8)...)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if _, err := c.rw.Write(header); err != nil {
+		c.close()
+		return err
+	}
+	if _, err := c.rw.Write(payload); err != nil {
+		c.close()
+		return err
+	}
+	if err := c.rw.Flush(); err != nil {
+		c.close()
+		return err
+	}
+	return nil
+}
+
+// read reads frames from the client until it closes the connection, answering pings
+func (c *Conn) read() {
+	defer c.close()
+	for {
+		opcode, payload, err := c.readFrame()
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case pingFrame:
+			if c.write(pongFrame, payload) != nil {
+				return
+			}
+		case closeFrame:
+			c.write(closeFrame, payload)
+			return
+		}
+	}
+}
+
+// readFrame reads a frame, client frames must be masked. Data frames are read in chunks and discarded
+func (c *Conn) readFrame() (byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(c.rw, header); err != nil {
+		return 0, nil, err
+	}
+	opcode := header[0] & 0x0F
+	if header[1]&0x80 == 0 {
+		return 0, nil, errors.New("client frames must be masked")
+	}
+
+	n := uint64(header[1] & 0x7F)
+	switch n {
+	case 126:
+		b := make([]byte, 2)
+		if _, err := io.ReadFull(c.rw, b); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(b))
+	case 127:
+		b := make([]byte, 8)
+		if _, err := io.ReadFull(c.rw, b); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(b)
+	}
+
+	mask := make([]byte, 4)
+	if _, err := io.ReadFull(c.rw, mask); err != nil {
+		return 0, nil, err
+	}
+
+	if opcode >= closeFrame {
+		if n > maxControlPayload {
+			return 0, nil, errors.New("control frame too large")
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(c.rw, payload); err != nil {
+			return 0, nil, err
+		}
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+		return opcode, payload, nil
+	}
+
+	if _, err := io.CopyN(io.Discard, c.rw, int64(n)); err != nil {
+		return 0, nil, err
+	}
+	return opcode, nil, nil
+}
+
+func headerContains(h http.Header, name string, token string) bool {
+	for _, v := range h[name] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
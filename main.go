@@ -1,15 +1,29 @@
 package main
 
 import (
+	"net"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/jwtauth"
-	"github.com/go-chi/render"
 	"github.com/jacsmith21/lukabox/api"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/catalogue"
 	"github.com/jacsmith21/lukabox/ext/db"
+	"github.com/jacsmith21/lukabox/ext/interactions"
+	"github.com/jacsmith21/lukabox/ext/log"
+	"github.com/jacsmith21/lukabox/ext/mail"
+	"github.com/jacsmith21/lukabox/ext/oidc"
+	"github.com/jacsmith21/lukabox/ext/refill"
+	"github.com/jacsmith21/lukabox/ext/render"
+	"github.com/jacsmith21/lukabox/ext/stream"
+	"github.com/jacsmith21/lukabox/ext/webhook"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 var tokenAuth *jwtauth.JwtAuth
@@ -24,24 +38,142 @@ func main() {
 	var userService = db.UserService{}
 	var pillService = db.PillService{}
 	var authenticationService = db.AuthenticationService{}
+	var rateLimiter = db.RateLimiter{}
+	var lockoutService = db.LockoutService{Policy: domain.LockoutPolicy{Threshold: 5, Duration: time.Minute, Max: time.Hour}}
+	var sessionPolicy = domain.SessionPolicy{Idle: 30 * 24 * time.Hour, Lifetime: 90 * 24 * time.Hour}
+	var mailer = mail.Directory{Path: "mail"}
+	var oneTimeTokenService = db.OneTimeTokenService{}
+	var identityService = db.IdentityService{}
+	var authorizationStateService = db.AuthorizationStateService{}
+	var twoFactorService = db.TwoFactorService{}
+	var sessionService = db.SessionService{}
+	var boxService = db.BoxService{}
+	var pillEventService = db.PillEventService{}
+	var stockService = db.StockService{}
+	var caregiverService = db.CaregiverService{}
+	var feedTokenService = db.FeedTokenService{}
+	var webhookService = db.WebhookService{}
+	var deviceService = db.DeviceService{}
+	var idempotencyService = db.IdempotencyService{}
+	var webhookSender = webhook.Sender{}
+	var eventBroker = stream.Broker{}
+	var interactionDataset = interactions.Dataset{}
+	var medicationCatalogue = catalogue.Catalogue{}
+	var refillChannel = refill.Mail{Mailer: &mailer, To: os.Getenv("PHARMACY_EMAIL")}
+
+	if err := interactionDataset.Load(interactionsFile()); err != nil {
+		log.WithError(err).Error("unable to load the interaction dataset, interactions won't be checked")
+	}
+
+	if err := medicationCatalogue.Load(medicationsFile()); err != nil {
+		log.WithError(err).Error("unable to load the medication catalogue")
+	}
 
 	// Creating apis
 	var userAPI api.UserAPI
 	var pillAPI api.PillAPI
 	var auth api.AuthenticationAPI
+	var rateLimitAPI api.RateLimitAPI
+	var sessionAPI api.SessionAPI
+	var boxAPI api.BoxAPI
+	var refillAPI api.RefillAPI
+	var caregiverAPI api.CaregiverAPI
+	var adherenceAPI api.AdherenceAPI
+	var calendarAPI api.CalendarAPI
+	var fhirAPI api.FHIRAPI
+	var reportAPI api.ReportAPI
+	var medicationAPI api.MedicationAPI
+	var webhookAPI api.WebhookAPI
+	var streamAPI api.StreamAPI
+	var graphQLAPI api.GraphQLAPI
+	var deviceAPI api.DeviceAPI
+	var idempotencyAPI api.IdempotencyAPI
 
 	// Adding services to apis
 	userAPI.UserService = &userService
+	userAPI.OneTimeTokenService = &oneTimeTokenService
+	userAPI.Mailer = &mailer
+	userAPI.SessionService = &sessionService
 	pillAPI.PillService = &pillService
+	pillAPI.StockService = &stockService
+	pillAPI.InteractionService = &interactionDataset
+	pillAPI.MedicationService = &medicationCatalogue
+	medicationAPI.MedicationService = &medicationCatalogue
+	boxAPI.BoxService = &boxService
+	adherenceAPI.UserService = &userService
+	adherenceAPI.PillService = &pillService
+	adherenceAPI.PillEventService = &pillEventService
+	adherenceAPI.StockService = &stockService
+	adherenceAPI.Refills = &refillAPI
+	boxAPI.Adherence = &adherenceAPI
+	refillAPI.PillService = &pillService
+	refillAPI.UserService = &userService
+	refillAPI.CaregiverService = &caregiverService
+	caregiverAPI.CaregiverService = &caregiverService
+	caregiverAPI.UserService = &userService
+	caregiverAPI.Mailer = &mailer
+	refillAPI.Mailer = &mailer
+	refillAPI.RefillChannel = &refillChannel
+	pillAPI.Refills = &refillAPI
+	calendarAPI.PillService = &pillService
+	calendarAPI.FeedTokenService = &feedTokenService
+	fhirAPI.PillService = &pillService
+	fhirAPI.PillEventService = &pillEventService
+	reportAPI.PillService = &pillService
+	reportAPI.PillEventService = &pillEventService
+	webhookAPI.WebhookService = &webhookService
+	webhookAPI.Sender = &webhookSender
+	boxAPI.Webhooks = &webhookAPI
+	pillAPI.Webhooks = &webhookAPI
+	refillAPI.Webhooks = &webhookAPI
+	adherenceAPI.Webhooks = &webhookAPI
+	streamAPI.Broker = &eventBroker
+	streamAPI.Origins = streamOrigins()
+	graphQLAPI.UserService = &userService
+	graphQLAPI.PillService = &pillService
+	graphQLAPI.PillEventService = &pillEventService
+	graphQLAPI.BoxService = &boxService
+	boxAPI.Stream = &streamAPI
+	refillAPI.Stream = &streamAPI
+	adherenceAPI.Stream = &streamAPI
+	deviceAPI.DeviceService = &deviceService
+	deviceAPI.UserService = &userService
+	deviceAPI.SessionService = &sessionService
+	deviceAPI.SessionPolicy = sessionPolicy
+	deviceAPI.PillService = &pillService
+	deviceAPI.Box = &boxAPI
+	deviceAPI.TokenAuth = tokenAuth
 	auth.AuthenticationService = &authenticationService
 	auth.UserService = &userService
+	auth.RateLimiter = &rateLimiter
+	auth.LockoutService = &lockoutService
+	auth.Mailer = &mailer
+	auth.OneTimeTokenService = &oneTimeTokenService
+	auth.TwoFactorService = &twoFactorService
+	auth.RequireTwoFactor = map[string]bool{domain.ClinicianRole: true, domain.AdminRole: true}
+	auth.SessionService = &sessionService
+	auth.SessionPolicy = sessionPolicy
+	sessionAPI.SessionService = &sessionService
+	auth.Providers = oidcProviders()
+	auth.IdentityService = &identityService
+	auth.AuthorizationStateService = &authorizationStateService
+	auth.AccountPolicy = domain.RateLimitPolicy{Capacity: 10, Interval: time.Minute}
+	rateLimitAPI.RateLimiter = &rateLimiter
+	idempotencyAPI.IdempotencyService = &idempotencyService
+	idempotencyAPI.UserService = &userService
+	idempotencyAPI.SessionService = &sessionService
+	idempotencyAPI.SessionPolicy = sessionPolicy
+	idempotencyAPI.TokenAuth = tokenAuth
+	idempotencyAPI.TTL = idempotencyTTL()
+
+	// Rate limiting policies for unauthenticated routes
+	var ipPolicy = domain.RateLimitPolicy{Capacity: 20, Interval: 3 * time.Second}
 
 	// The middleware
 	r.Use(middleware.RequestID)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.URLFormat)
-	r.Use(render.SetContentType(render.ContentTypeJSON))
 
 	//to stop processing after 60 seconds
 	r.Use(middleware.Timeout(60 * time.Second))
@@ -54,26 +186,337 @@ func main() {
 		panic("test")
 	})
 
-	r.Post("/login", auth.Login)
+	// The routes are mounted once for every version, the handlers render the representations of the version.
+	// Responses are JSON unless a route negotiates other content types
+	routes := func(r chi.Router) {
+		r.Use(render.SetContentType(render.ContentTypeJSON))
+		r.Use(idempotencyAPI.Idempotent)
 
-	r.Route("/users", func(r chi.Router) {
-		r.Get("/", userAPI.Users)
-		r.With(userAPI.UserRequestCtx).With(auth.SignUpValidator).Put("/", userAPI.CreateUser)
+		r.With(rateLimitAPI.ByIP(ipPolicy)).Post("/login", auth.Login)
+		r.With(rateLimitAPI.ByIP(ipPolicy)).Post("/login/2fa", auth.TwoFactorLogin)
+		r.With(rateLimitAPI.ByIP(ipPolicy)).Get("/login/{provider}", auth.ProviderLogin)
+		r.With(rateLimitAPI.ByIP(ipPolicy)).Get("/login/{provider}/callback", auth.ProviderCallback)
+		r.With(jwtauth.Verifier(tokenAuth)).With(auth.SessionValidator).Post("/logout", auth.Logout)
+		r.With(rateLimitAPI.ByIP(ipPolicy)).Post("/unlock", auth.Unlock)
+		r.With(rateLimitAPI.ByIP(ipPolicy)).Post("/verify", auth.Verify)
 
-		r.Route("/{userId}", func(r chi.Router) {
-			r.Use(userAPI.UserCtx)
-			r.Get("/", userAPI.UserByID)
-			r.Post("/", userAPI.UpdateUser)
+		r.Route("/password", func(r chi.Router) {
+			r.Use(rateLimitAPI.ByIP(ipPolicy))
+			r.Post("/forgot", auth.ForgotPassword)
+			r.Post("/reset", auth.ResetPassword)
+		})
 
-			r.Route("/pills", func(r chi.Router) {
-				r.Use(jwtauth.Verifier(tokenAuth))
-				r.Use(auth.RequestValidator)
-				r.Get("/", pillAPI.Pills)
-			})
+		r.Route("/medications", func(r chi.Router) {
+			r.Use(jwtauth.Verifier(tokenAuth))
+			r.Use(auth.SessionValidator)
+			r.Get("/", medicationAPI.Medications)
+			r.Get("/{medicationId}", medicationAPI.Medication)
+		})
 
-			r.Route("/box", func(r chi.Router) {})
+		r.Route("/graphql", func(r chi.Router) {
+			r.Use(jwtauth.Verifier(tokenAuth))
+			r.Use(auth.SessionValidator)
+			r.Get("/", graphQLAPI.GraphQL)
+			r.Post("/", graphQLAPI.GraphQL)
 		})
+
+		r.Route("/users", func(r chi.Router) {
+			r.Get("/", userAPI.Users)
+			r.With(rateLimitAPI.ByIP(ipPolicy)).With(userAPI.UserRequestCtx).With(auth.SignUpValidator).Put("/", userAPI.CreateUser)
+
+			r.Route("/{userId}", func(r chi.Router) {
+				r.Use(userAPI.UserCtx)
+				r.Get("/", userAPI.UserByID)
+				r.Get("/schedule", calendarAPI.Feed)
+
+				r.Group(func(r chi.Router) {
+					r.Use(jwtauth.Verifier(tokenAuth))
+					r.Use(auth.SessionValidator)
+					r.Use(auth.RequestValidator)
+					r.Post("/", userAPI.UpdateUser)
+					r.Patch("/", userAPI.PatchUser)
+				})
+
+				r.Route("/schedule/token", func(r chi.Router) {
+					r.Use(jwtauth.Verifier(tokenAuth))
+					r.Use(auth.SessionValidator)
+					r.Use(auth.RequestValidator)
+					r.Post("/", calendarAPI.IssueFeedToken)
+					r.Delete("/", calendarAPI.RevokeFeedToken)
+				})
+
+				r.Route("/2fa", func(r chi.Router) {
+					r.Use(jwtauth.Verifier(tokenAuth))
+					r.Use(auth.SessionValidator)
+					r.Use(auth.AllowEnrolment)
+					r.Use(auth.RequestValidator)
+					r.Post("/", auth.EnrolTwoFactor)
+					r.Post("/confirm", auth.ConfirmTwoFactor)
+					r.Delete("/", auth.DisableTwoFactor)
+				})
+
+				r.Route("/sessions", func(r chi.Router) {
+					r.Use(jwtauth.Verifier(tokenAuth))
+					r.Use(auth.SessionValidator)
+					r.Use(auth.RequestValidator)
+					r.Get("/", sessionAPI.Sessions)
+					r.With(sessionAPI.SessionCtx).Delete("/{sessionId}", sessionAPI.DeleteSession)
+				})
+
+				r.Route("/caregivers", func(r chi.Router) {
+					r.Use(jwtauth.Verifier(tokenAuth))
+					r.Use(auth.SessionValidator)
+					r.Use(auth.RequestValidator)
+					r.Use(auth.VerifiedValidator)
+					r.Get("/", caregiverAPI.Caregivers)
+					r.Put("/{caregiverId}", caregiverAPI.AddCaregiver)
+					r.Delete("/{caregiverId}", caregiverAPI.RemoveCaregiver)
+				})
+
+				r.Route("/patients", func(r chi.Router) {
+					r.Use(jwtauth.Verifier(tokenAuth))
+					r.Use(auth.SessionValidator)
+					r.Use(auth.RequestValidator)
+					r.Use(auth.VerifiedValidator)
+					r.Put("/{patientId}", caregiverAPI.AcceptPatient)
+					r.Delete("/{patientId}", caregiverAPI.RemovePatient)
+				})
+
+				r.Route("/pills", func(r chi.Router) {
+					r.Use(jwtauth.Verifier(tokenAuth))
+					r.Use(auth.SessionValidator)
+					r.Use(auth.RequestValidator)
+					r.Get("/", pillAPI.Pills)
+					r.Post("/", pillAPI.CreatePill)
+					r.Get("/interactions", pillAPI.Interactions)
+					r.Post("/preview", pillAPI.PreviewPill)
+
+					r.Route("/{pillId}", func(r chi.Router) {
+						r.Use(pillAPI.PillCtx)
+						r.Get("/", pillAPI.Pill)
+						r.Post("/", pillAPI.UpdatePill)
+						r.Patch("/", pillAPI.PatchPill)
+						r.Get("/stock", pillAPI.Stock)
+						r.Post("/stock", pillAPI.AdjustStock)
+						r.Get("/refill", refillAPI.RefillRequest)
+						r.Post("/refill", refillAPI.SendRefillRequest)
+						r.Get("/preview", pillAPI.Preview)
+						r.Post("/doses", adherenceAPI.TakeDose)
+					})
+				})
+
+				r.Route("/fhir", func(r chi.Router) {
+					r.Use(jwtauth.Verifier(tokenAuth))
+					r.Use(auth.SessionValidator)
+					r.Use(auth.RequestValidator)
+					r.Get("/", fhirAPI.Export)
+					r.Get("/Patient", fhirAPI.Patient)
+					r.With(pillAPI.PillCtx).Get("/MedicationStatement/{pillId}", fhirAPI.MedicationStatement)
+					r.With(pillAPI.PillCtx).Get("/MedicationRequest/{pillId}", fhirAPI.MedicationRequest)
+					r.Get("/MedicationAdministration/{eventId}", fhirAPI.MedicationAdministration)
+				})
+
+				r.Route("/reports", func(r chi.Router) {
+					r.Use(jwtauth.Verifier(tokenAuth))
+					r.Use(auth.SessionValidator)
+					r.Use(auth.RequestValidator)
+					r.Use(render.Negotiate(render.ContentTypeJSON, render.ContentTypeCSV, render.ContentTypePDF))
+					r.Get("/adherence", reportAPI.AdherenceReport)
+				})
+
+				r.Route("/stream", func(r chi.Router) {
+					r.Use(jwtauth.Verifier(tokenAuth))
+					r.Use(auth.SessionValidator)
+					r.Use(auth.RequestValidator)
+					r.Get("/", streamAPI.Stream)
+				})
+
+				r.Route("/webhooks", func(r chi.Router) {
+					r.Use(jwtauth.Verifier(tokenAuth))
+					r.Use(auth.SessionValidator)
+					r.Use(auth.RequestValidator)
+					r.Get("/", webhookAPI.Webhooks)
+					r.Post("/", webhookAPI.CreateWebhook)
+
+					r.Route("/{webhookId}", func(r chi.Router) {
+						r.Use(webhookAPI.WebhookCtx)
+						r.Get("/", webhookAPI.Webhook)
+						r.Delete("/", webhookAPI.DeleteWebhook)
+						r.Get("/deliveries", webhookAPI.Deliveries)
+						r.Post("/deliveries/{deliveryId}/redeliver", webhookAPI.Redeliver)
+						r.Post("/test", webhookAPI.SendTestEvent)
+					})
+				})
+
+				r.Route("/devices", func(r chi.Router) {
+					r.Use(jwtauth.Verifier(tokenAuth))
+					r.Use(auth.SessionValidator)
+					r.Use(auth.RequestValidator)
+					r.Get("/", deviceAPI.Devices)
+
+					r.Route("/{deviceId}", func(r chi.Router) {
+						r.Use(deviceAPI.DeviceCtx)
+						r.Get("/", deviceAPI.Device)
+						r.Delete("/", deviceAPI.DeleteDevice)
+						r.Post("/commands", deviceAPI.QueueCommand)
+					})
+				})
+
+				r.Route("/box", func(r chi.Router) {
+					r.Use(jwtauth.Verifier(tokenAuth))
+					r.Use(auth.SessionValidator)
+					r.Use(auth.RequestValidator)
+					r.Use(auth.VerifiedValidator)
+					r.With(boxAPI.OpenEventRequestCtx).Put("/open", boxAPI.Open)
+					r.With(boxAPI.CloseEventRequestCtx).Put("/close", boxAPI.Close)
+					r.Get("/sessions", boxAPI.Sessions)
+				})
+			})
+		})
+	}
+
+	// v1 is also mounted without a prefix for clients from before the api was versioned
+	deprecated := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC)
+	r.Group(func(r chi.Router) {
+		r.Use(api.Versioned(api.V1))
+		r.Use((&api.Deprecation{Successor: "/v2", Date: deprecated, Sunset: sunset}).Handler)
+		routes(r)
+	})
+	r.Route("/v1", func(r chi.Router) {
+		r.Use(api.Versioned(api.V1))
+		r.Use((&api.Deprecation{Prefix: "/v1", Successor: "/v2", Date: deprecated, Sunset: sunset}).Handler)
+		routes(r)
 	})
+	r.Route("/v2", func(r chi.Router) {
+		r.Use(api.Versioned(api.V2))
+		routes(r)
+	})
+
+	go archiveEndedPills(&pillService, time.Hour)
+	go publishMissedDoses(&adherenceAPI, time.Minute)
+	go deliverWebhooks(&webhookAPI, 10*time.Second)
+	go serveDevices(&deviceAPI, ":3002")
 
 	http.ListenAndServe(":3001", r)
 }
+
+// archiveEndedPills archives the pills whose course has ended, checking every interval
+func archiveEndedPills(pillService domain.PillService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := time.Now(); ; now = <-ticker.C {
+		archived, err := pillService.ArchiveEndedPills(now)
+		if err != nil {
+			log.WithError(err).Error("error archiving ended pills")
+			continue
+		}
+		if archived > 0 {
+			log.WithField("archived", archived).Info("archived ended pills")
+		}
+	}
+}
+
+// publishMissedDoses publishes the doses missed since the last check, checking every interval
+func publishMissedDoses(adherenceAPI *api.AdherenceAPI, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for from, to := time.Now(), time.Now(); ; from, to = to, <-ticker.C {
+		if err := adherenceAPI.PublishMissedDoses(from, to); err != nil {
+			log.WithError(err).Error("error publishing missed doses")
+		}
+	}
+}
+
+// deliverWebhooks attempts the webhook deliveries which are due, checking every interval
+func deliverWebhooks(webhookAPI *api.WebhookAPI, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := time.Now(); ; now = <-ticker.C {
+		if err := webhookAPI.DeliverDue(now); err != nil {
+			log.WithError(err).Error("error delivering webhooks")
+		}
+	}
+}
+
+// serveDevices serves the grpc device rpcs on addr alongside the rest api. They are served over tls with the
+// certificate and key in DEVICE_TLS_CERT and DEVICE_TLS_KEY, without them they must be behind a proxy which terminates tls
+func serveDevices(deviceAPI *api.DeviceAPI, addr string) {
+	opts := []grpc.ServerOption{}
+	if cert, key := os.Getenv("DEVICE_TLS_CERT"), os.Getenv("DEVICE_TLS_KEY"); cert != "" && key != "" {
+		creds, err := credentials.NewServerTLSFromFile(cert, key)
+		if err != nil {
+			log.WithError(err).Error("unable to load the device tls certificate")
+			return
+		}
+		opts = append(opts, grpc.Creds(creds))
+	} else {
+		log.Info("DEVICE_TLS_CERT and DEVICE_TLS_KEY aren't set, serving devices without tls")
+	}
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.WithError(err).Error("unable to listen for devices")
+		return
+	}
+	if err := deviceAPI.Server(opts...).Serve(lis); err != nil {
+		log.WithError(err).Error("error serving devices")
+	}
+}
+
+// oidcProviders creates the OpenID Connect providers listed in OIDC_PROVIDERS, ie. OIDC_PROVIDERS=google
+// is configured with OIDC_GOOGLE_ISSUER, OIDC_GOOGLE_CLIENT_ID, OIDC_GOOGLE_CLIENT_SECRET and OIDC_GOOGLE_REDIRECT_URL
+func oidcProviders() map[string]domain.IdentityProvider {
+	providers := map[string]domain.IdentityProvider{}
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers[name] = &oidc.Provider{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+		}
+	}
+	return providers
+}
+
+// interactionsFile the interaction dataset to load, INTERACTIONS_FILE defaults to the sample dataset in data
+func interactionsFile() string {
+	if path := os.Getenv("INTERACTIONS_FILE"); path != "" {
+		return path
+	}
+	return "data/interactions.csv"
+}
+
+// medicationsFile the drug list the catalogue is loaded from, MEDICATIONS_FILE defaults to the sample list in data
+func medicationsFile() string {
+	if path := os.Getenv("MEDICATIONS_FILE"); path != "" {
+		return path
+	}
+	return "data/medications.csv"
+}
+
+// idempotencyTTL how long responses to requests with an Idempotency-Key are replayed, IDEMPOTENCY_TTL defaults to a day
+func idempotencyTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_TTL")); err == nil && ttl > 0 {
+		return ttl
+	}
+	return 24 * time.Hour
+}
+
+// streamOrigins the origins of the pages which can open WebSocket streams besides the api's own, a comma
+// separated list in STREAM_ORIGINS
+func streamOrigins() []string {
+	origins := []string{}
+	for _, o := range strings.Split(os.Getenv("STREAM_ORIGINS"), ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			origins = append(origins, o)
+		}
+	}
+	return origins
+}
@@ -0,0 +1,29 @@
+package mock
+
+import (
+	"errors"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// PillEventService mock implementation of domain.PillEventService
+type PillEventService struct {
+	PillEventsFn      func(pillID int) ([]*domain.PillEvent, error)
+	InsertPillEventFn func(pillEvent *domain.PillEvent) error
+}
+
+// PillEvents mock implementation
+func (s *PillEventService) PillEvents(pillID int) ([]*domain.PillEvent, error) {
+	if s.PillEventsFn == nil {
+		return nil, errors.New("PillEventsFn not implemented")
+	}
+	return s.PillEventsFn(pillID)
+}
+
+// InsertPillEvent mock implementation
+func (s *PillEventService) InsertPillEvent(pillEvent *domain.PillEvent) error {
+	if s.InsertPillEventFn == nil {
+		return errors.New("InsertPillEventFn not implemented")
+	}
+	return s.InsertPillEventFn(pillEvent)
+}
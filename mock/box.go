@@ -10,6 +10,8 @@ import (
 type BoxService struct {
 	InsertOpenEventFn  func(openEvent *domain.OpenEvent) error
 	InsertCloseEventFn func(closeEvent *domain.CloseEvent) error
+	OpenEventsFn       func(userID int) ([]*domain.OpenEvent, error)
+	CloseEventsFn      func(userID int) ([]*domain.CloseEvent, error)
 }
 
 // InsertOpenEvent mock implementation
@@ -27,3 +29,19 @@ func (s *BoxService) InsertCloseEvent(closeEvent *domain.CloseEvent) error {
 	}
 	return s.InsertCloseEventFn(closeEvent)
 }
+
+// OpenEvents mock implementation
+func (s *BoxService) OpenEvents(userID int) ([]*domain.OpenEvent, error) {
+	if s.OpenEventsFn == nil {
+		return nil, errors.New("OpenEventsFn not implemented")
+	}
+	return s.OpenEventsFn(userID)
+}
+
+// CloseEvents mock implementation
+func (s *BoxService) CloseEvents(userID int) ([]*domain.CloseEvent, error) {
+	if s.CloseEventsFn == nil {
+		return nil, errors.New("CloseEventsFn not implemented")
+	}
+	return s.CloseEventsFn(userID)
+}
@@ -0,0 +1,38 @@
+package mock
+
+import (
+	"errors"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// FeedTokenService mock implementation
+type FeedTokenService struct {
+	FeedTokenFn       func(token string) (*domain.FeedToken, error)
+	IssueFeedTokenFn  func(userID int) (*domain.FeedToken, error)
+	RevokeFeedTokenFn func(userID int) error
+}
+
+// FeedToken mock implementation
+func (s *FeedTokenService) FeedToken(token string) (*domain.FeedToken, error) {
+	if s.FeedTokenFn == nil {
+		return nil, errors.New("FeedTokenFn not implemented")
+	}
+	return s.FeedTokenFn(token)
+}
+
+// IssueFeedToken mock implementation
+func (s *FeedTokenService) IssueFeedToken(userID int) (*domain.FeedToken, error) {
+	if s.IssueFeedTokenFn == nil {
+		return nil, errors.New("IssueFeedTokenFn not implemented")
+	}
+	return s.IssueFeedTokenFn(userID)
+}
+
+// RevokeFeedToken mock implementation
+func (s *FeedTokenService) RevokeFeedToken(userID int) error {
+	if s.RevokeFeedTokenFn == nil {
+		return errors.New("RevokeFeedTokenFn not implemented")
+	}
+	return s.RevokeFeedTokenFn(userID)
+}
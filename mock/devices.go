@@ -0,0 +1,84 @@
+package mock
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// DeviceService mock implementation
+type DeviceService struct {
+	DeviceFn          func(id int) (*domain.Device, error)
+	DevicesFn         func(userID int) ([]*domain.Device, error)
+	InsertDeviceFn    func(device *domain.Device) error
+	DeleteDeviceFn    func(id int) error
+	TouchDeviceFn     func(id int, lastSeen time.Time) error
+	InsertCommandFn   func(command *domain.DeviceCommand) error
+	PendingCommandsFn func(deviceID int) ([]*domain.DeviceCommand, error)
+	DeliverCommandFn  func(id int, delivered time.Time) error
+}
+
+// Device mock implementation
+func (s *DeviceService) Device(id int) (*domain.Device, error) {
+	if s.DeviceFn == nil {
+		return nil, errors.New("DeviceFn not implemented")
+	}
+	return s.DeviceFn(id)
+}
+
+// Devices mock implementation
+func (s *DeviceService) Devices(userID int) ([]*domain.Device, error) {
+	if s.DevicesFn == nil {
+		return nil, errors.New("DevicesFn not implemented")
+	}
+	return s.DevicesFn(userID)
+}
+
+// InsertDevice mock implementation
+func (s *DeviceService) InsertDevice(device *domain.Device) error {
+	if s.InsertDeviceFn == nil {
+		return errors.New("InsertDeviceFn not implemented")
+	}
+	return s.InsertDeviceFn(device)
+}
+
+// DeleteDevice mock implementation
+func (s *DeviceService) DeleteDevice(id int) error {
+	if s.DeleteDeviceFn == nil {
+		return errors.New("DeleteDeviceFn not implemented")
+	}
+	return s.DeleteDeviceFn(id)
+}
+
+// TouchDevice mock implementation
+func (s *DeviceService) TouchDevice(id int, lastSeen time.Time) error {
+	if s.TouchDeviceFn == nil {
+		return errors.New("TouchDeviceFn not implemented")
+	}
+	return s.TouchDeviceFn(id, lastSeen)
+}
+
+// InsertCommand mock implementation
+func (s *DeviceService) InsertCommand(command *domain.DeviceCommand) error {
+	if s.InsertCommandFn == nil {
+		return errors.New("InsertCommandFn not implemented")
+	}
+	return s.InsertCommandFn(command)
+}
+
+// PendingCommands mock implementation
+func (s *DeviceService) PendingCommands(deviceID int) ([]*domain.DeviceCommand, error) {
+	if s.PendingCommandsFn == nil {
+		return nil, errors.New("PendingCommandsFn not implemented")
+	}
+	return s.PendingCommandsFn(deviceID)
+}
+
+// DeliverCommand mock implementation
+func (s *DeviceService) DeliverCommand(id int, delivered time.Time) error {
+	if s.DeliverCommandFn == nil {
+		return errors.New("DeliverCommandFn not implemented")
+	}
+	return s.DeliverCommandFn(id, delivered)
+}
@@ -0,0 +1,38 @@
+package mock
+
+import (
+	"errors"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// IdempotencyService mock implementation
+type IdempotencyService struct {
+	BeginFn    func(record *domain.IdempotencyRecord) (*domain.IdempotencyRecord, error)
+	CompleteFn func(record *domain.IdempotencyRecord) error
+	ReleaseFn  func(userID int, key string) error
+}
+
+// Begin mock implementation
+func (s *IdempotencyService) Begin(record *domain.IdempotencyRecord) (*domain.IdempotencyRecord, error) {
+	if s.BeginFn == nil {
+		return nil, errors.New("BeginFn not implemented")
+	}
+	return s.BeginFn(record)
+}
+
+// Complete mock implementation
+func (s *IdempotencyService) Complete(record *domain.IdempotencyRecord) error {
+	if s.CompleteFn == nil {
+		return errors.New("CompleteFn not implemented")
+	}
+	return s.CompleteFn(record)
+}
+
+// Release mock implementation
+func (s *IdempotencyService) Release(userID int, key string) error {
+	if s.ReleaseFn == nil {
+		return errors.New("ReleaseFn not implemented")
+	}
+	return s.ReleaseFn(userID, key)
+}
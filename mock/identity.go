@@ -0,0 +1,73 @@
+package mock
+
+import (
+	"errors"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// IdentityProvider mock implementation
+type IdentityProvider struct {
+	AuthorizationURLFn func(state string, nonce string, challenge string) (string, error)
+	ExchangeFn         func(code string, verifier string, nonce string) (*domain.ExternalIdentity, error)
+}
+
+// AuthorizationURL mock implementation
+func (p *IdentityProvider) AuthorizationURL(state string, nonce string, challenge string) (string, error) {
+	if p.AuthorizationURLFn == nil {
+		return "", errors.New("AuthorizationURLFn not implemented")
+	}
+	return p.AuthorizationURLFn(state, nonce, challenge)
+}
+
+// Exchange mock implementation
+func (p *IdentityProvider) Exchange(code string, verifier string, nonce string) (*domain.ExternalIdentity, error) {
+	if p.ExchangeFn == nil {
+		return nil, errors.New("ExchangeFn not implemented")
+	}
+	return p.ExchangeFn(code, verifier, nonce)
+}
+
+// IdentityService mock implementation
+type IdentityService struct {
+	IdentityFn       func(provider string, subject string) (*domain.Identity, error)
+	InsertIdentityFn func(identity *domain.Identity) error
+}
+
+// Identity mock implementation
+func (s *IdentityService) Identity(provider string, subject string) (*domain.Identity, error) {
+	if s.IdentityFn == nil {
+		return nil, errors.New("IdentityFn not implemented")
+	}
+	return s.IdentityFn(provider, subject)
+}
+
+// InsertIdentity mock implementation
+func (s *IdentityService) InsertIdentity(identity *domain.Identity) error {
+	if s.InsertIdentityFn == nil {
+		return errors.New("InsertIdentityFn not implemented")
+	}
+	return s.InsertIdentityFn(identity)
+}
+
+// AuthorizationStateService mock implementation
+type AuthorizationStateService struct {
+	InsertStateFn func(state *domain.AuthorizationState) error
+	TakeStateFn   func(state string) (*domain.AuthorizationState, error)
+}
+
+// InsertState mock implementation
+func (s *AuthorizationStateService) InsertState(state *domain.AuthorizationState) error {
+	if s.InsertStateFn == nil {
+		return errors.New("InsertStateFn not implemented")
+	}
+	return s.InsertStateFn(state)
+}
+
+// TakeState mock implementation
+func (s *AuthorizationStateService) TakeState(state string) (*domain.AuthorizationState, error) {
+	if s.TakeStateFn == nil {
+		return nil, errors.New("TakeStateFn not implemented")
+	}
+	return s.TakeStateFn(state)
+}
@@ -0,0 +1,20 @@
+package mock
+
+import (
+	"errors"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// InteractionService mock implementation
+type InteractionService struct {
+	InteractionsFn func(pill string, other string) ([]*domain.Interaction, error)
+}
+
+// Interactions mock implementation
+func (s *InteractionService) Interactions(pill string, other string) ([]*domain.Interaction, error) {
+	if s.InteractionsFn == nil {
+		return nil, errors.New("InteractionsFn not implemented")
+	}
+	return s.InteractionsFn(pill, other)
+}
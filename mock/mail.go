@@ -0,0 +1,20 @@
+package mock
+
+import (
+	"errors"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// Mailer mock implementation
+type Mailer struct {
+	SendFn func(email *domain.Email) error
+}
+
+// Send mock implementation
+func (m *Mailer) Send(email *domain.Email) error {
+	if m.SendFn == nil {
+		return errors.New("SendFn not implemented")
+	}
+	return m.SendFn(email)
+}
@@ -0,0 +1,29 @@
+package mock
+
+import (
+	"errors"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// MedicationService mock implementation
+type MedicationService struct {
+	MedicationFn        func(id string) (*domain.Medication, error)
+	SearchMedicationsFn func(query string, limit int) ([]*domain.Medication, error)
+}
+
+// Medication mock implementation
+func (s *MedicationService) Medication(id string) (*domain.Medication, error) {
+	if s.MedicationFn == nil {
+		return nil, errors.New("MedicationFn not implemented")
+	}
+	return s.MedicationFn(id)
+}
+
+// SearchMedications mock implementation
+func (s *MedicationService) SearchMedications(query string, limit int) ([]*domain.Medication, error) {
+	if s.SearchMedicationsFn == nil {
+		return nil, errors.New("SearchMedicationsFn not implemented")
+	}
+	return s.SearchMedicationsFn(query, limit)
+}
@@ -2,16 +2,18 @@ package mock
 
 import (
 	"errors"
+	"time"
 
 	"github.com/jacsmith21/lukabox/domain"
 )
 
 // PillService represents a mock implementation of domain.PillService.
 type PillService struct {
-	PillFn       func(id int) (*domain.Pill, error)
-	PillsFn      func(id int) ([]*domain.Pill, error)
-	CreatePillFn func(pill *domain.Pill) error
-	UpdatePillFn func(id int, pill *domain.Pill) error
+	PillFn              func(id int) (*domain.Pill, error)
+	PillsFn             func(id int) ([]*domain.Pill, error)
+	CreatePillFn        func(pill *domain.Pill) error
+	UpdatePillFn        func(id int, pill *domain.Pill) error
+	ArchiveEndedPillsFn func(now time.Time) (int, error)
 }
 
 //Pill invokes the mock implementation and marks the function as invoked.
@@ -45,3 +47,33 @@ func (s *PillService) UpdatePill(id int, pill *domain.Pill) error {
 	}
 	return s.UpdatePillFn(id, pill)
 }
+
+//ArchiveEndedPills mock implementation
+func (s *PillService) ArchiveEndedPills(now time.Time) (int, error) {
+	if s.ArchiveEndedPillsFn == nil {
+		return 0, errors.New("ArchiveEndedPillsFn not implemented")
+	}
+	return s.ArchiveEndedPillsFn(now)
+}
+
+// StockService mock implementation of domain.StockService
+type StockService struct {
+	AdjustmentsFn      func(pillID int) ([]*domain.StockAdjustment, error)
+	InsertAdjustmentFn func(adjustment *domain.StockAdjustment) error
+}
+
+// Adjustments mock implementation
+func (s *StockService) Adjustments(pillID int) ([]*domain.StockAdjustment, error) {
+	if s.AdjustmentsFn == nil {
+		return nil, errors.New("AdjustmentsFn not implemented")
+	}
+	return s.AdjustmentsFn(pillID)
+}
+
+// InsertAdjustment mock implementation
+func (s *StockService) InsertAdjustment(adjustment *domain.StockAdjustment) error {
+	if s.InsertAdjustmentFn == nil {
+		return errors.New("InsertAdjustmentFn not implemented")
+	}
+	return s.InsertAdjustmentFn(adjustment)
+}
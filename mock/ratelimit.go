@@ -0,0 +1,70 @@
+package mock
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// RateLimiter mock implementation
+type RateLimiter struct {
+	AllowFn func(key string, policy domain.RateLimitPolicy) (bool, time.Duration, error)
+	ResetFn func(key string) error
+}
+
+// Allow mock implementation
+func (s *RateLimiter) Allow(key string, policy domain.RateLimitPolicy) (bool, time.Duration, error) {
+	if s.AllowFn == nil {
+		return false, 0, errors.New("AllowFn not implemented")
+	}
+	return s.AllowFn(key, policy)
+}
+
+// Reset mock implementation
+func (s *RateLimiter) Reset(key string) error {
+	if s.ResetFn == nil {
+		return errors.New("ResetFn not implemented")
+	}
+	return s.ResetFn(key)
+}
+
+// LockoutService mock implementation
+type LockoutService struct {
+	LockoutFn func(email string) (*domain.Lockout, error)
+	FailFn    func(email string) (*domain.Lockout, error)
+	ClearFn   func(email string) error
+	UnlockFn  func(token string) (bool, error)
+}
+
+// Lockout mock implementation
+func (s *LockoutService) Lockout(email string) (*domain.Lockout, error) {
+	if s.LockoutFn == nil {
+		return nil, errors.New("LockoutFn not implemented")
+	}
+	return s.LockoutFn(email)
+}
+
+// Fail mock implementation
+func (s *LockoutService) Fail(email string) (*domain.Lockout, error) {
+	if s.FailFn == nil {
+		return nil, errors.New("FailFn not implemented")
+	}
+	return s.FailFn(email)
+}
+
+// Clear mock implementation
+func (s *LockoutService) Clear(email string) error {
+	if s.ClearFn == nil {
+		return errors.New("ClearFn not implemented")
+	}
+	return s.ClearFn(email)
+}
+
+// Unlock mock implementation
+func (s *LockoutService) Unlock(token string) (bool, error) {
+	if s.UnlockFn == nil {
+		return false, errors.New("UnlockFn not implemented")
+	}
+	return s.UnlockFn(token)
+}
@@ -0,0 +1,69 @@
+package mock
+
+import (
+	"errors"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// RefillChannel mock implementation of domain.RefillChannel
+type RefillChannel struct {
+	SendRefillFn func(request *domain.RefillRequest) error
+}
+
+// SendRefill mock implementation
+func (c *RefillChannel) SendRefill(request *domain.RefillRequest) error {
+	if c.SendRefillFn == nil {
+		return errors.New("SendRefillFn not implemented")
+	}
+	return c.SendRefillFn(request)
+}
+
+// CaregiverService mock implementation of domain.CaregiverService
+type CaregiverService struct {
+	CaregiversFn      func(patientID int) ([]*domain.User, error)
+	CaregiverLinksFn  func(patientID int) ([]*domain.CaregiverLink, error)
+	InviteCaregiverFn func(patientID int, caregiverID int) error
+	AcceptCaregiverFn func(patientID int, caregiverID int) (bool, error)
+	RemoveCaregiverFn func(patientID int, caregiverID int) error
+}
+
+// Caregivers mock implementation
+func (s *CaregiverService) Caregivers(patientID int) ([]*domain.User, error) {
+	if s.CaregiversFn == nil {
+		return nil, errors.New("CaregiversFn not implemented")
+	}
+	return s.CaregiversFn(patientID)
+}
+
+// CaregiverLinks mock implementation
+func (s *CaregiverService) CaregiverLinks(patientID int) ([]*domain.CaregiverLink, error) {
+	if s.CaregiverLinksFn == nil {
+		return nil, errors.New("CaregiverLinksFn not implemented")
+	}
+	return s.CaregiverLinksFn(patientID)
+}
+
+// InviteCaregiver mock implementation
+func (s *CaregiverService) InviteCaregiver(patientID int, caregiverID int) error {
+	if s.InviteCaregiverFn == nil {
+		return errors.New("InviteCaregiverFn not implemented")
+	}
+	return s.InviteCaregiverFn(patientID, caregiverID)
+}
+
+// AcceptCaregiver mock implementation
+func (s *CaregiverService) AcceptCaregiver(patientID int, caregiverID int) (bool, error) {
+	if s.AcceptCaregiverFn == nil {
+		return false, errors.New("AcceptCaregiverFn not implemented")
+	}
+	return s.AcceptCaregiverFn(patientID, caregiverID)
+}
+
+// RemoveCaregiver mock implementation
+func (s *CaregiverService) RemoveCaregiver(patientID int, caregiverID int) error {
+	if s.RemoveCaregiverFn == nil {
+		return errors.New("RemoveCaregiverFn not implemented")
+	}
+	return s.RemoveCaregiverFn(patientID, caregiverID)
+}
@@ -0,0 +1,66 @@
+package mock
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// SessionService mock implementation
+type SessionService struct {
+	SessionFn        func(id string) (*domain.Session, error)
+	SessionsFn       func(userID int) ([]*domain.Session, error)
+	InsertSessionFn  func(session *domain.Session) error
+	TouchSessionFn   func(id string, lastSeen time.Time) error
+	DeleteSessionFn  func(id string) error
+	DeleteSessionsFn func(userID int) error
+}
+
+// Session mock implementation
+func (s *SessionService) Session(id string) (*domain.Session, error) {
+	if s.SessionFn == nil {
+		return nil, errors.New("SessionFn not implemented")
+	}
+	return s.SessionFn(id)
+}
+
+// Sessions mock implementation
+func (s *SessionService) Sessions(userID int) ([]*domain.Session, error) {
+	if s.SessionsFn == nil {
+		return nil, errors.New("SessionsFn not implemented")
+	}
+	return s.SessionsFn(userID)
+}
+
+// InsertSession mock implementation
+func (s *SessionService) InsertSession(session *domain.Session) error {
+	if s.InsertSessionFn == nil {
+		return errors.New("InsertSessionFn not implemented")
+	}
+	return s.InsertSessionFn(session)
+}
+
+// TouchSession mock implementation
+func (s *SessionService) TouchSession(id string, lastSeen time.Time) error {
+	if s.TouchSessionFn == nil {
+		return errors.New("TouchSessionFn not implemented")
+	}
+	return s.TouchSessionFn(id, lastSeen)
+}
+
+// DeleteSession mock implementation
+func (s *SessionService) DeleteSession(id string) error {
+	if s.DeleteSessionFn == nil {
+		return errors.New("DeleteSessionFn not implemented")
+	}
+	return s.DeleteSessionFn(id)
+}
+
+// DeleteSessions mock implementation
+func (s *SessionService) DeleteSessions(userID int) error {
+	if s.DeleteSessionsFn == nil {
+		return errors.New("DeleteSessionsFn not implemented")
+	}
+	return s.DeleteSessionsFn(userID)
+}
@@ -0,0 +1,30 @@
+package mock
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// OneTimeTokenService mock implementation
+type OneTimeTokenService struct {
+	IssueFn  func(userID int, purpose domain.TokenPurpose, ttl time.Duration) (*domain.OneTimeToken, error)
+	RedeemFn func(token string, purpose domain.TokenPurpose) (*domain.OneTimeToken, error)
+}
+
+// Issue mock implementation
+func (s *OneTimeTokenService) Issue(userID int, purpose domain.TokenPurpose, ttl time.Duration) (*domain.OneTimeToken, error) {
+	if s.IssueFn == nil {
+		return nil, errors.New("IssueFn not implemented")
+	}
+	return s.IssueFn(userID, purpose, ttl)
+}
+
+// Redeem mock implementation
+func (s *OneTimeTokenService) Redeem(token string, purpose domain.TokenPurpose) (*domain.OneTimeToken, error) {
+	if s.RedeemFn == nil {
+		return nil, errors.New("RedeemFn not implemented")
+	}
+	return s.RedeemFn(token, purpose)
+}
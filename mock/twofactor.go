@@ -0,0 +1,57 @@
+package mock
+
+import (
+	"errors"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// TwoFactorService mock implementation
+type TwoFactorService struct {
+	TwoFactorFn       func(userID int) (*domain.TwoFactor, error)
+	SaveTwoFactorFn   func(twoFactor *domain.TwoFactor) error
+	DeleteTwoFactorFn func(userID int) error
+
+	RedeemStepFn         func(userID int, step int64) (bool, error)
+	RedeemRecoveryCodeFn func(userID int, hash string) (bool, error)
+}
+
+// TwoFactor mock implementation
+func (s *TwoFactorService) TwoFactor(userID int) (*domain.TwoFactor, error) {
+	if s.TwoFactorFn == nil {
+		return nil, errors.New("TwoFactorFn not implemented")
+	}
+	return s.TwoFactorFn(userID)
+}
+
+// SaveTwoFactor mock implementation
+func (s *TwoFactorService) SaveTwoFactor(twoFactor *domain.TwoFactor) error {
+	if s.SaveTwoFactorFn == nil {
+		return errors.New("SaveTwoFactorFn not implemented")
+	}
+	return s.SaveTwoFactorFn(twoFactor)
+}
+
+// DeleteTwoFactor mock implementation
+func (s *TwoFactorService) DeleteTwoFactor(userID int) error {
+	if s.DeleteTwoFactorFn == nil {
+		return errors.New("DeleteTwoFactorFn not implemented")
+	}
+	return s.DeleteTwoFactorFn(userID)
+}
+
+// RedeemStep mock implementation
+func (s *TwoFactorService) RedeemStep(userID int, step int64) (bool, error) {
+	if s.RedeemStepFn == nil {
+		return false, errors.New("RedeemStepFn not implemented")
+	}
+	return s.RedeemStepFn(userID, step)
+}
+
+// RedeemRecoveryCode mock implementation
+func (s *TwoFactorService) RedeemRecoveryCode(userID int, hash string) (bool, error) {
+	if s.RedeemRecoveryCodeFn == nil {
+		return false, errors.New("RedeemRecoveryCodeFn not implemented")
+	}
+	return s.RedeemRecoveryCodeFn(userID, hash)
+}
@@ -0,0 +1,106 @@
+package mock
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// WebhookService mock implementation
+type WebhookService struct {
+	WebhookFn        func(id int) (*domain.Webhook, error)
+	WebhooksFn       func(userID int) ([]*domain.Webhook, error)
+	InsertWebhookFn  func(webhook *domain.Webhook) error
+	DeleteWebhookFn  func(id int) error
+	DeliveryFn       func(id int) (*domain.WebhookDelivery, error)
+	DeliveriesFn     func(webhookID int) ([]*domain.WebhookDelivery, error)
+	DueDeliveriesFn  func(now time.Time) ([]*domain.WebhookDelivery, error)
+	InsertDeliveryFn func(delivery *domain.WebhookDelivery) error
+	UpdateDeliveryFn func(delivery *domain.WebhookDelivery) error
+}
+
+// Webhook mock implementation
+func (s *WebhookService) Webhook(id int) (*domain.Webhook, error) {
+	if s.WebhookFn == nil {
+		return nil, errors.New("WebhookFn not implemented")
+	}
+	return s.WebhookFn(id)
+}
+
+// Webhooks mock implementation
+func (s *WebhookService) Webhooks(userID int) ([]*domain.Webhook, error) {
+	if s.WebhooksFn == nil {
+		return nil, errors.New("WebhooksFn not implemented")
+	}
+	return s.WebhooksFn(userID)
+}
+
+// InsertWebhook mock implementation
+func (s *WebhookService) InsertWebhook(webhook *domain.Webhook) error {
+	if s.InsertWebhookFn == nil {
+		return errors.New("InsertWebhookFn not implemented")
+	}
+	return s.InsertWebhookFn(webhook)
+}
+
+// DeleteWebhook mock implementation
+func (s *WebhookService) DeleteWebhook(id int) error {
+	if s.DeleteWebhookFn == nil {
+		return errors.New("DeleteWebhookFn not implemented")
+	}
+	return s.DeleteWebhookFn(id)
+}
+
+// Delivery mock implementation
+func (s *WebhookService) Delivery(id int) (*domain.WebhookDelivery, error) {
+	if s.DeliveryFn == nil {
+		return nil, errors.New("DeliveryFn not implemented")
+	}
+	return s.DeliveryFn(id)
+}
+
+// Deliveries mock implementation
+func (s *WebhookService) Deliveries(webhookID int) ([]*domain.WebhookDelivery, error) {
+	if s.DeliveriesFn == nil {
+		return nil, errors.New("DeliveriesFn not implemented")
+	}
+	return s.DeliveriesFn(webhookID)
+}
+
+// DueDeliveries mock implementation
+func (s *WebhookService) DueDeliveries(now time.Time) ([]*domain.WebhookDelivery, error) {
+	if s.DueDeliveriesFn == nil {
+		return nil, errors.New("DueDeliveriesFn not implemented")
+	}
+	return s.DueDeliveriesFn(now)
+}
+
+// InsertDelivery mock implementation
+func (s *WebhookService) InsertDelivery(delivery *domain.WebhookDelivery) error {
+	if s.InsertDeliveryFn == nil {
+		return errors.New("InsertDeliveryFn not implemented")
+	}
+	return s.InsertDeliveryFn(delivery)
+}
+
+// UpdateDelivery mock implementation
+func (s *WebhookService) UpdateDelivery(delivery *domain.WebhookDelivery) error {
+	if s.UpdateDeliveryFn == nil {
+		return errors.New("UpdateDeliveryFn not implemented")
+	}
+	return s.UpdateDeliveryFn(delivery)
+}
+
+// WebhookSender mock implementation of domain.WebhookSender
+type WebhookSender struct {
+	SendFn func(webhook *domain.Webhook, delivery *domain.WebhookDelivery) (int, error)
+}
+
+// Send mock implementation
+func (s *WebhookSender) Send(webhook *domain.Webhook, delivery *domain.WebhookDelivery) (int, error) {
+	if s.SendFn == nil {
+		return 0, errors.New("SendFn not implemented")
+	}
+	return s.SendFn(webhook, delivery)
+}
@@ -1,9 +1,11 @@
 package stc
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/validate"
 )
 
 //CredentialsRequest a request with credentials
@@ -13,7 +15,10 @@ type CredentialsRequest struct {
 
 //Bind post-processing after decode
 func (c *CredentialsRequest) Bind(r *http.Request) error {
-	return nil
+	if c.Credentials == nil {
+		return nil
+	}
+	return validate.Struct(r, c.Credentials)
 }
 
 //TokenResponse a token response
@@ -31,3 +36,59 @@ func NewTokenResponse(token *domain.Token) *TokenResponse {
 	resp := &TokenResponse{Token: token}
 	return resp
 }
+
+// UnlockRequest a request to unlock an account
+type UnlockRequest struct {
+	Token string `json:"token"`
+}
+
+// Bind post-processing after decode
+func (u *UnlockRequest) Bind(r *http.Request) error {
+	if u.Token == "" {
+		return errors.New("token must be supplied")
+	}
+	return nil
+}
+
+// VerifyRequest a request to verify an email
+type VerifyRequest struct {
+	Token string `json:"token"`
+}
+
+// Bind post-processing after decode
+func (v *VerifyRequest) Bind(r *http.Request) error {
+	if v.Token == "" {
+		return errors.New("token must be supplied")
+	}
+	return nil
+}
+
+// ForgotPasswordRequest a request for a password reset email
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// Bind post-processing after decode
+func (f *ForgotPasswordRequest) Bind(r *http.Request) error {
+	if f.Email == "" {
+		return errors.New("email must be supplied")
+	}
+	return nil
+}
+
+// ResetPasswordRequest a request to reset a password
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// Bind post-processing after decode
+func (p *ResetPasswordRequest) Bind(r *http.Request) error {
+	if p.Token == "" {
+		return errors.New("token must be supplied")
+	}
+	if p.Password == "" {
+		return errors.New("password must be supplied")
+	}
+	return validate.Var(r, "password", p.Password, "password")
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/jacsmith21/lukabox/domain"
 	"github.com/jacsmith21/lukabox/ext/render"
+	"github.com/jacsmith21/lukabox/ext/validate"
 )
 
 // OpenEventRequest request structure
@@ -15,13 +16,16 @@ type OpenEventRequest struct {
 
 // Bind post-processing
 func (e *OpenEventRequest) Bind(r *http.Request) error {
+	if e.OpenEvent == nil {
+		return errors.New("an open event must be supplied")
+	}
 	tmp := r.Context().Value("user")
 	if tmp == nil {
 		return errors.New("no user in open event request context")
 	}
 	user := tmp.(*domain.User)
 	e.UserID = user.ID
-	return nil
+	return validate.Struct(r, e.OpenEvent)
 }
 
 // CloseEventRequest CloseEventRequest
@@ -31,13 +35,16 @@ type CloseEventRequest struct {
 
 // Bind post-processing
 func (e *CloseEventRequest) Bind(r *http.Request) error {
+	if e.CloseEvent == nil {
+		return errors.New("a close event must be supplied")
+	}
 	tmp := r.Context().Value("user")
 	if tmp == nil {
 		return errors.New("no user in close event request context")
 	}
 	user := tmp.(*domain.User)
 	e.UserID = user.ID
-	return nil
+	return validate.Struct(r, e.CloseEvent)
 }
 
 // OpenEventResponse reponse structure
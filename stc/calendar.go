@@ -0,0 +1,25 @@
+package stc
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// FeedTokenResponse a feed token response with the url of the feed
+type FeedTokenResponse struct {
+	*domain.FeedToken
+	URL string `json:"url"`
+}
+
+// Render pre-processing before marshelling
+func (t *FeedTokenResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// NewFeedTokenResponse creates a new feed token response
+func NewFeedTokenResponse(token *domain.FeedToken) render.Renderer {
+	return &FeedTokenResponse{FeedToken: token, URL: fmt.Sprintf("/users/%d/schedule.ics?token=%s", token.UserID, token.Token)}
+}
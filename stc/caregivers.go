@@ -0,0 +1,39 @@
+package stc
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// CaregiverResponse a caregiver of a patient, only their name and email are shared with the patient. Accepted
+// is false while the caregiver hasn't accepted the invitation
+type CaregiverResponse struct {
+	ID        int    `json:"id"`
+	Email     string `json:"email"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Accepted  bool   `json:"accepted"`
+}
+
+// Render pre-processing before marshelling
+func (c *CaregiverResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// NewCaregiverResponse creates a new caregiver response
+func NewCaregiverResponse(link *domain.CaregiverLink, caregiver *domain.User) render.Renderer {
+	return &CaregiverResponse{ID: caregiver.ID, Email: caregiver.Email, FirstName: caregiver.FirstName, LastName: caregiver.LastName, Accepted: link.Accepted}
+}
+
+// NewCaregiverListResponse creates a new caregiver list response, links without a caregiver are left out
+func NewCaregiverListResponse(links []*domain.CaregiverLink, caregivers map[int]*domain.User) []render.Renderer {
+	list := []render.Renderer{}
+	for _, link := range links {
+		if caregiver, ok := caregivers[link.CaregiverID]; ok {
+			list = append(list, NewCaregiverResponse(link, caregiver))
+		}
+	}
+	return list
+}
@@ -0,0 +1,27 @@
+package stc
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// CompartmentSessionResponse response structure
+type CompartmentSessionResponse struct {
+	*domain.CompartmentSession
+}
+
+// Render pre-processing
+func (s *CompartmentSessionResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// NewCompartmentSessionListResponse compartment session list response
+func NewCompartmentSessionListResponse(sessions []*domain.CompartmentSession) []render.Renderer {
+	list := []render.Renderer{}
+	for _, session := range sessions {
+		list = append(list, &CompartmentSessionResponse{CompartmentSession: session})
+	}
+	return list
+}
@@ -0,0 +1,68 @@
+package stc
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/validate"
+)
+
+// DeviceResponse a device response, the secret is never included
+type DeviceResponse struct {
+	*domain.Device
+}
+
+// Render pre-processing before marshelling
+func (d *DeviceResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// NewDeviceResponse creates a new device response
+func NewDeviceResponse(device *domain.Device) render.Renderer {
+	return &DeviceResponse{Device: device}
+}
+
+// NewDeviceListResponse creates a new device list response
+func NewDeviceListResponse(devices []*domain.Device) []render.Renderer {
+	list := []render.Renderer{}
+	for _, device := range devices {
+		list = append(list, NewDeviceResponse(device))
+	}
+	return list
+}
+
+// DeviceCommandRequest a request to send a command to a device
+type DeviceCommandRequest struct {
+	*domain.DeviceCommand
+}
+
+// Bind post-processing DeviceCommandRequest, unlock commands need a compartment
+func (c *DeviceCommandRequest) Bind(r *http.Request) error {
+	if c.DeviceCommand == nil {
+		return errors.New("a command must be supplied")
+	}
+	if err := validate.Struct(r, c.DeviceCommand); err != nil {
+		return err
+	}
+	if c.Type == domain.UnlockCommand && c.CompID == 0 {
+		return errors.New("an unlock command needs a compartment")
+	}
+	return nil
+}
+
+// DeviceCommandResponse a device command response
+type DeviceCommandResponse struct {
+	*domain.DeviceCommand
+}
+
+// Render pre-processing before marshelling
+func (c *DeviceCommandResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// NewDeviceCommandResponse creates a new device command response
+func NewDeviceCommandResponse(command *domain.DeviceCommand) render.Renderer {
+	return &DeviceCommandResponse{DeviceCommand: command}
+}
@@ -0,0 +1,44 @@
+package stc
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// InteractionWarningResponse an interaction warning response
+type InteractionWarningResponse struct {
+	*domain.InteractionWarning
+}
+
+// Render pre-processing before marshelling
+func (i *InteractionWarningResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// NewInteractionWarningListResponse creates a new interaction warning list response
+func NewInteractionWarningListResponse(warnings []*domain.InteractionWarning) []render.Renderer {
+	list := []render.Renderer{}
+	for _, warning := range warnings {
+		list = append(list, &InteractionWarningResponse{InteractionWarning: warning})
+	}
+	return list
+}
+
+// InteractionConflictResponse rejects a pill which interacts with the regimen until the interactions are acknowledged
+type InteractionConflictResponse struct {
+	Message      string                       `json:"message"`
+	Interactions []*domain.InteractionWarning `json:"interactions"`
+}
+
+// Render pre-processing before marshelling
+func (i *InteractionConflictResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, http.StatusConflict)
+	return nil
+}
+
+// NewInteractionConflictResponse creates a new interaction conflict response
+func NewInteractionConflictResponse(warnings []*domain.InteractionWarning) render.Renderer {
+	return &InteractionConflictResponse{Message: "the pill interacts with the regimen, acknowledge the interactions to proceed", Interactions: warnings}
+}
@@ -0,0 +1,32 @@
+package stc
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// MedicationResponse a medication response
+type MedicationResponse struct {
+	*domain.Medication
+}
+
+// Render pre-processing before marshelling
+func (m *MedicationResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// NewMedicationResponse creates a new medication response
+func NewMedicationResponse(medication *domain.Medication) render.Renderer {
+	return &MedicationResponse{Medication: medication}
+}
+
+// NewMedicationListResponse creates a new medication list response
+func NewMedicationListResponse(medications []*domain.Medication) []render.Renderer {
+	list := []render.Renderer{}
+	for _, medication := range medications {
+		list = append(list, NewMedicationResponse(medication))
+	}
+	return list
+}
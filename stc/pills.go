@@ -1,15 +1,19 @@
 package stc
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/render"
 	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/validate"
 )
 
-// PillResponse respose stc
+// PillResponse respose stc, Interactions warns about the pill's interactions when it is created or updated
 type PillResponse struct {
 	*domain.Pill
+	Interactions []*domain.InteractionWarning `json:"interactions,omitempty"`
 }
 
 // Render implementation
@@ -17,13 +21,35 @@ func (pr *PillResponse) Render(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
-// PillRequest a pill request
+// PillRequest a pill request, AcknowledgeInteractions saves the pill even if it interacts with the regimen
 type PillRequest struct {
 	*domain.Pill
+	AcknowledgeInteractions bool `json:"acknowledgeInteractions"`
 }
 
 // Bind post-processing PillRequest
 func (pr *PillRequest) Bind(r *http.Request) error {
+	if pr.Pill == nil {
+		return errors.New("a pill must be supplied")
+	}
+	if err := validate.Struct(r, pr.Pill); err != nil {
+		return err
+	}
+	// the stock is read-only, it's only changed through stock adjustments
+	pr.Quantity = 0
+	if pr.Strength > 0 && pr.Unit == "" {
+		return errors.New("a unit must be supplied with the strength")
+	}
+	if pr.StartDate != nil && pr.EndDate != nil && pr.EndDate.Before(*pr.StartDate) {
+		return errors.New("the end date must not be before the start date")
+	}
+	if pr.Schedule != nil {
+		if err := pr.Schedule.Validate(); err != nil {
+			return err
+		}
+		// the schedule supersedes the days and times which are kept for older clients
+		pr.DaysOfWeek, pr.TimesOfDay = pr.Schedule.Legacy()
+	}
 	return nil
 }
 
@@ -41,3 +67,117 @@ func NewPillResponse(pill *domain.Pill) render.Renderer {
 	resp := &PillResponse{Pill: pill}
 	return resp
 }
+
+// NewPillInteractionsResponse creates a new response with the interactions of the pill
+func NewPillInteractionsResponse(pill *domain.Pill, warnings []*domain.InteractionWarning) render.Renderer {
+	return &PillResponse{Pill: pill, Interactions: warnings}
+}
+
+// PillV2Request a v2 pill request, the pill's id is id and the user's id is userId instead of pillId and id
+type PillV2Request struct {
+	ID     int `json:"id"`
+	UserID int `json:"userId"`
+	*PillRequest
+}
+
+// Bind post-processing PillV2Request, the embedded PillRequest is bound first
+func (pr *PillV2Request) Bind(r *http.Request) error {
+	if pr.PillRequest == nil || pr.Pill == nil {
+		return errors.New("a pill must be supplied")
+	}
+	pr.Pill.ID = pr.ID
+	pr.Pill.UserID = pr.UserID
+	return nil
+}
+
+// PillV2Response a v2 pill response, the pill's id is id and the user's id is userId instead of pillId and id
+type PillV2Response struct {
+	ID     int       `json:"id"`
+	UserID int       `json:"userId"`
+	PillID *struct{} `json:"pillId,omitempty"`
+	*PillResponse
+}
+
+// NewPillV2ListResponse create new v2 pill list response
+func NewPillV2ListResponse(pills []*domain.Pill) []render.Renderer {
+	list := []render.Renderer{}
+	for _, pill := range pills {
+		list = append(list, NewPillV2Response(pill, nil))
+	}
+	return list
+}
+
+// NewPillV2Response create new v2 pill response with the interactions of the pill
+func NewPillV2Response(pill *domain.Pill, warnings []*domain.InteractionWarning) render.Renderer {
+	return &PillV2Response{ID: pill.ID, UserID: pill.UserID, PillResponse: &PillResponse{Pill: pill, Interactions: warnings}}
+}
+
+// StockAdjustmentRequest a request to adjust the quantity of a pill on hand
+type StockAdjustmentRequest struct {
+	*domain.StockAdjustment
+}
+
+// Bind post-processing StockAdjustmentRequest
+func (s *StockAdjustmentRequest) Bind(r *http.Request) error {
+	if s.StockAdjustment == nil {
+		return errors.New("a stock adjustment must be supplied")
+	}
+	if err := validate.Struct(r, s.StockAdjustment); err != nil {
+		return err
+	}
+	if s.Reason == domain.DoseReason {
+		return errors.New("dose adjustments are made automatically")
+	}
+	return nil
+}
+
+// StockResponse the stock of a pill and its projected run out date
+type StockResponse struct {
+	Quantity        float64                   `json:"quantity"`
+	QuantityPerDose float64                   `json:"quantityPerDose"`
+	RunOut          *time.Time                `json:"runOut"`
+	Adjustments     []*domain.StockAdjustment `json:"adjustments"`
+}
+
+// Render implementation
+func (s *StockResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// NewStockResponse create new stock response, projecting the run out date from now
+func NewStockResponse(pill *domain.Pill, adjustments []*domain.StockAdjustment, now time.Time) render.Renderer {
+	resp := &StockResponse{Quantity: pill.Quantity, QuantityPerDose: pill.QuantityPerDose, Adjustments: adjustments}
+	if runOut, ok := pill.RunOut(now); ok {
+		resp.RunOut = &runOut
+	}
+	return resp
+}
+
+// OccurrenceResponse an occurrence response
+type OccurrenceResponse struct {
+	*domain.Occurrence
+}
+
+// Render implementation
+func (o *OccurrenceResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// NewOccurrenceListResponse create new occurrence list response
+func NewOccurrenceListResponse(occurrences []domain.Occurrence) []render.Renderer {
+	list := []render.Renderer{}
+	for i := range occurrences {
+		list = append(list, &OccurrenceResponse{Occurrence: &occurrences[i]})
+	}
+	return list
+}
+
+// DoseRequest a dose taken outside of the box, the time defaults to now
+type DoseRequest struct {
+	Time time.Time `json:"time" validate:"omitempty,notfuture"`
+}
+
+// Bind post-processing DoseRequest
+func (d *DoseRequest) Bind(r *http.Request) error {
+	return validate.Struct(r, d)
+}
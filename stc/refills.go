@@ -0,0 +1,22 @@
+package stc
+
+import (
+	"net/http"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// RefillRequestResponse a refill request response
+type RefillRequestResponse struct {
+	*domain.RefillRequest
+}
+
+// Render implementation
+func (rr *RefillRequestResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// NewRefillRequestResponse create new refill request response
+func NewRefillRequestResponse(request *domain.RefillRequest) *RefillRequestResponse {
+	return &RefillRequestResponse{RefillRequest: request}
+}
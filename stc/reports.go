@@ -0,0 +1,23 @@
+package stc
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// AdherenceReportResponse an adherence report response
+type AdherenceReportResponse struct {
+	*domain.AdherenceReport
+}
+
+// Render pre-processing before marshelling
+func (a *AdherenceReportResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// NewAdherenceReportResponse creates a new adherence report response
+func NewAdherenceReportResponse(report *domain.AdherenceReport) render.Renderer {
+	return &AdherenceReportResponse{AdherenceReport: report}
+}
@@ -0,0 +1,36 @@
+package stc
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// SessionResponse a session response, Current is set for the session making the request
+type SessionResponse struct {
+	*domain.Session
+	Current bool `json:"current"`
+}
+
+// Render pre-processing before marshelling
+func (s *SessionResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if current, ok := r.Context().Value("session").(*domain.Session); ok {
+		s.Current = current.ID == s.ID
+	}
+	return nil
+}
+
+// NewSessionResponse creates a new session response
+func NewSessionResponse(session *domain.Session) render.Renderer {
+	return &SessionResponse{Session: session}
+}
+
+// NewSessionListResponse creates a new session list response
+func NewSessionListResponse(sessions []*domain.Session) []render.Renderer {
+	list := []render.Renderer{}
+	for _, session := range sessions {
+		list = append(list, NewSessionResponse(session))
+	}
+	return list
+}
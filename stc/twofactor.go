@@ -0,0 +1,89 @@
+package stc
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/jacsmith21/lukabox/domain"
+)
+
+// TwoFactorChallengeResponse a two factor challenge response
+type TwoFactorChallengeResponse struct {
+	*domain.TwoFactorChallenge
+}
+
+// Render pre-processing before marshelling
+func (c *TwoFactorChallengeResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// NewTwoFactorChallengeResponse creates a new two factor challenge response
+func NewTwoFactorChallengeResponse(challenge *domain.TwoFactorChallenge) *TwoFactorChallengeResponse {
+	return &TwoFactorChallengeResponse{TwoFactorChallenge: challenge}
+}
+
+// TwoFactorEnrolmentResponse the secret and provisioning uri for an authenticator app
+type TwoFactorEnrolmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// Render pre-processing before marshelling
+func (e *TwoFactorEnrolmentResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// RecoveryCodesResponse recovery codes, only ever shown once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// Render pre-processing before marshelling
+func (c *RecoveryCodesResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// TwoFactorCodeRequest a request with a code from an authenticator app
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// Bind post-processing after decode
+func (c *TwoFactorCodeRequest) Bind(r *http.Request) error {
+	if c.Code == "" {
+		return errors.New("code must be supplied")
+	}
+	return nil
+}
+
+// TwoFactorLoginRequest the second step of a login, either a code or a recovery code must be supplied
+type TwoFactorLoginRequest struct {
+	Challenge    string `json:"challenge"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+// Bind post-processing after decode
+func (l *TwoFactorLoginRequest) Bind(r *http.Request) error {
+	if l.Challenge == "" {
+		return errors.New("challenge must be supplied")
+	}
+	if l.Code == "" && l.RecoveryCode == "" {
+		return errors.New("code or recovery code must be supplied")
+	}
+	return nil
+}
+
+// TwoFactorDisableRequest a request to disable two factor authentication, proven with a code or a recovery code
+type TwoFactorDisableRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+// Bind post-processing after decode
+func (d *TwoFactorDisableRequest) Bind(r *http.Request) error {
+	if d.Code == "" && d.RecoveryCode == "" {
+		return errors.New("code or recovery code must be supplied")
+	}
+	return nil
+}
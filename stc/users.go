@@ -1,10 +1,13 @@
 package stc
 
 import (
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/render"
 	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/validate"
 )
 
 // UserResponse for json
@@ -17,13 +20,31 @@ func (rd *UserResponse) Render(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
-//UserRequest a user request
+//UserRequest a user request, CurrentPassword is the user's password before the request and empty for new users.
+//The password is only checked against the password rules when it's changed so existing passwords are kept
 type UserRequest struct {
 	*domain.User
+	CurrentPassword string `json:"-"`
 }
 
 // Bind post-processing after decode
 func (u *UserRequest) Bind(r *http.Request) error {
+	if u.User == nil {
+		return nil
+	}
+	if err := validate.Struct(r, u.User); err != nil {
+		return err
+	}
+	if u.Password != u.CurrentPassword {
+		if err := validate.Var(r, "password", u.Password, "password"); err != nil {
+			return err
+		}
+	}
+	for _, name := range []string{u.Timezone, u.TravelTimezone} {
+		if _, err := time.LoadLocation(name); err != nil {
+			return fmt.Errorf("unknown timezone %s", name)
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,81 @@
+package stc
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/jacsmith21/lukabox/domain"
+	"github.com/jacsmith21/lukabox/ext/validate"
+	"github.com/jacsmith21/lukabox/ext/webhook"
+)
+
+// WebhookRequest a request to subscribe a url to events
+type WebhookRequest struct {
+	*domain.Webhook
+}
+
+// Bind post-processing WebhookRequest
+func (wr *WebhookRequest) Bind(r *http.Request) error {
+	if wr.Webhook == nil {
+		return errors.New("a webhook must be supplied")
+	}
+	if err := validate.Struct(r, wr.Webhook); err != nil {
+		return err
+	}
+	return webhook.CheckURL(wr.URL)
+}
+
+// WebhookResponse a webhook response, the secret is only included when the webhook is created
+type WebhookResponse struct {
+	*domain.Webhook
+	Secret string `json:"secret,omitempty"`
+}
+
+// Render pre-processing before marshelling
+func (wr *WebhookResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// NewWebhookResponse creates a new webhook response
+func NewWebhookResponse(webhook *domain.Webhook) render.Renderer {
+	return &WebhookResponse{Webhook: webhook}
+}
+
+// NewWebhookSecretResponse creates a new webhook response with the secret used to sign its payloads
+func NewWebhookSecretResponse(webhook *domain.Webhook) render.Renderer {
+	return &WebhookResponse{Webhook: webhook, Secret: webhook.Secret}
+}
+
+// NewWebhookListResponse creates a new webhook list response
+func NewWebhookListResponse(webhooks []*domain.Webhook) []render.Renderer {
+	list := []render.Renderer{}
+	for _, webhook := range webhooks {
+		list = append(list, NewWebhookResponse(webhook))
+	}
+	return list
+}
+
+// WebhookDeliveryResponse a webhook delivery response
+type WebhookDeliveryResponse struct {
+	*domain.WebhookDelivery
+}
+
+// Render pre-processing before marshelling
+func (d *WebhookDeliveryResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// NewWebhookDeliveryResponse creates a new webhook delivery response
+func NewWebhookDeliveryResponse(delivery *domain.WebhookDelivery) render.Renderer {
+	return &WebhookDeliveryResponse{WebhookDelivery: delivery}
+}
+
+// NewWebhookDeliveryListResponse creates a new webhook delivery list response
+func NewWebhookDeliveryListResponse(deliveries []*domain.WebhookDelivery) []render.Renderer {
+	list := []render.Renderer{}
+	for _, delivery := range deliveries {
+		list = append(list, NewWebhookDeliveryResponse(delivery))
+	}
+	return list
+}